@@ -20,10 +20,16 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Storage_Update_FullMethodName     = "/server.Storage/Update"
-	Storage_UpdateMany_FullMethodName = "/server.Storage/UpdateMany"
-	Storage_Metric_FullMethodName     = "/server.Storage/Metric"
-	Storage_AllMetrics_FullMethodName = "/server.Storage/AllMetrics"
+	Storage_Update_FullMethodName          = "/server.Storage/Update"
+	Storage_UpdateMany_FullMethodName      = "/server.Storage/UpdateMany"
+	Storage_Metric_FullMethodName          = "/server.Storage/Metric"
+	Storage_AllMetrics_FullMethodName      = "/server.Storage/AllMetrics"
+	Storage_MetricsByLabels_FullMethodName = "/server.Storage/MetricsByLabels"
+	Storage_QueryRange_FullMethodName      = "/server.Storage/QueryRange"
+	Storage_StreamUpdates_FullMethodName   = "/server.Storage/StreamUpdates"
+	Storage_Delete_FullMethodName          = "/server.Storage/Delete"
+	Storage_DeleteByPattern_FullMethodName = "/server.Storage/DeleteByPattern"
+	Storage_Reset_FullMethodName           = "/server.Storage/Reset"
 )
 
 // StorageClient is the client API for Storage service.
@@ -38,6 +44,18 @@ type StorageClient interface {
 	Metric(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*MetricResponse, error)
 	// AllMetrics описание всех метрик из хранилища.
 	AllMetrics(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AllMetricsResponse, error)
+	// MetricsByLabels возвращает описание метрик, набор меток которых содержит все переданные метки.
+	MetricsByLabels(ctx context.Context, in *LabelsRequest, opts ...grpc.CallOption) (*AllMetricsResponse, error)
+	// QueryRange возвращает значения метрики за интервал времени, агрегированные по шагам.
+	QueryRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error)
+	// StreamUpdates принимает поток наборов метрик для обновления и подтверждает обработку каждого набора.
+	StreamUpdates(ctx context.Context, opts ...grpc.CallOption) (Storage_StreamUpdatesClient, error)
+	// Delete удаляет метрику вместе с её историей. Требует токен администратора.
+	Delete(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону. Требует токен администратора.
+	DeleteByPattern(ctx context.Context, in *PatternRequest, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// Reset обнуляет значение метрики типа counter. Требует токен администратора.
+	Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type storageClient struct {
@@ -84,6 +102,82 @@ func (c *storageClient) AllMetrics(ctx context.Context, in *emptypb.Empty, opts
 	return out, nil
 }
 
+func (c *storageClient) MetricsByLabels(ctx context.Context, in *LabelsRequest, opts ...grpc.CallOption) (*AllMetricsResponse, error) {
+	out := new(AllMetricsResponse)
+	err := c.cc.Invoke(ctx, Storage_MetricsByLabels_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) QueryRange(ctx context.Context, in *RangeRequest, opts ...grpc.CallOption) (*RangeResponse, error) {
+	out := new(RangeResponse)
+	err := c.cc.Invoke(ctx, Storage_QueryRange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) StreamUpdates(ctx context.Context, opts ...grpc.CallOption) (Storage_StreamUpdatesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Storage_ServiceDesc.Streams[0], Storage_StreamUpdates_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &storageStreamUpdatesClient{stream}
+	return x, nil
+}
+
+type Storage_StreamUpdatesClient interface {
+	Send(*StreamUpdateRequest) error
+	Recv() (*StreamUpdateResponse, error)
+	grpc.ClientStream
+}
+
+type storageStreamUpdatesClient struct {
+	grpc.ClientStream
+}
+
+func (x *storageStreamUpdatesClient) Send(m *StreamUpdateRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *storageStreamUpdatesClient) Recv() (*StreamUpdateResponse, error) {
+	m := new(StreamUpdateResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *storageClient) Delete(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storage_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) DeleteByPattern(ctx context.Context, in *PatternRequest, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, Storage_DeleteByPattern_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageClient) Reset(ctx context.Context, in *ResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Storage_Reset_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // StorageServer is the server API for Storage service.
 // All implementations must embed UnimplementedStorageServer
 // for forward compatibility
@@ -96,6 +190,18 @@ type StorageServer interface {
 	Metric(context.Context, *MetricRequest) (*MetricResponse, error)
 	// AllMetrics описание всех метрик из хранилища.
 	AllMetrics(context.Context, *emptypb.Empty) (*AllMetricsResponse, error)
+	// MetricsByLabels возвращает описание метрик, набор меток которых содержит все переданные метки.
+	MetricsByLabels(context.Context, *LabelsRequest) (*AllMetricsResponse, error)
+	// QueryRange возвращает значения метрики за интервал времени, агрегированные по шагам.
+	QueryRange(context.Context, *RangeRequest) (*RangeResponse, error)
+	// StreamUpdates принимает поток наборов метрик для обновления и подтверждает обработку каждого набора.
+	StreamUpdates(Storage_StreamUpdatesServer) error
+	// Delete удаляет метрику вместе с её историей. Требует токен администратора.
+	Delete(context.Context, *MetricRequest) (*emptypb.Empty, error)
+	// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону. Требует токен администратора.
+	DeleteByPattern(context.Context, *PatternRequest) (*DeleteResponse, error)
+	// Reset обнуляет значение метрики типа counter. Требует токен администратора.
+	Reset(context.Context, *ResetRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedStorageServer()
 }
 
@@ -115,6 +221,24 @@ func (UnimplementedStorageServer) Metric(context.Context, *MetricRequest) (*Metr
 func (UnimplementedStorageServer) AllMetrics(context.Context, *emptypb.Empty) (*AllMetricsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AllMetrics not implemented")
 }
+func (UnimplementedStorageServer) MetricsByLabels(context.Context, *LabelsRequest) (*AllMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MetricsByLabels not implemented")
+}
+func (UnimplementedStorageServer) QueryRange(context.Context, *RangeRequest) (*RangeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryRange not implemented")
+}
+func (UnimplementedStorageServer) StreamUpdates(Storage_StreamUpdatesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamUpdates not implemented")
+}
+func (UnimplementedStorageServer) Delete(context.Context, *MetricRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedStorageServer) DeleteByPattern(context.Context, *PatternRequest) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteByPattern not implemented")
+}
+func (UnimplementedStorageServer) Reset(context.Context, *ResetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reset not implemented")
+}
 func (UnimplementedStorageServer) mustEmbedUnimplementedStorageServer() {}
 
 // UnsafeStorageServer may be embedded to opt out of forward compatibility for this service.
@@ -200,6 +324,122 @@ func _Storage_AllMetrics_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Storage_MetricsByLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LabelsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).MetricsByLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storage_MetricsByLabels_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).MetricsByLabels(ctx, req.(*LabelsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_QueryRange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).QueryRange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storage_QueryRange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).QueryRange(ctx, req.(*RangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_StreamUpdates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(StorageServer).StreamUpdates(&storageStreamUpdatesServer{stream})
+}
+
+type Storage_StreamUpdatesServer interface {
+	Send(*StreamUpdateResponse) error
+	Recv() (*StreamUpdateRequest, error)
+	grpc.ServerStream
+}
+
+type storageStreamUpdatesServer struct {
+	grpc.ServerStream
+}
+
+func (x *storageStreamUpdatesServer) Send(m *StreamUpdateResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *storageStreamUpdatesServer) Recv() (*StreamUpdateRequest, error) {
+	m := new(StreamUpdateRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Storage_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storage_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Delete(ctx, req.(*MetricRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_DeleteByPattern_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatternRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).DeleteByPattern(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storage_DeleteByPattern_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).DeleteByPattern(ctx, req.(*PatternRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Storage_Reset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageServer).Reset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Storage_Reset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageServer).Reset(ctx, req.(*ResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Storage_ServiceDesc is the grpc.ServiceDesc for Storage service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -223,6 +463,125 @@ var Storage_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AllMetrics",
 			Handler:    _Storage_AllMetrics_Handler,
 		},
+		{
+			MethodName: "MetricsByLabels",
+			Handler:    _Storage_MetricsByLabels_Handler,
+		},
+		{
+			MethodName: "QueryRange",
+			Handler:    _Storage_QueryRange_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _Storage_Delete_Handler,
+		},
+		{
+			MethodName: "DeleteByPattern",
+			Handler:    _Storage_DeleteByPattern_Handler,
+		},
+		{
+			MethodName: "Reset",
+			Handler:    _Storage_Reset_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamUpdates",
+			Handler:       _Storage_StreamUpdates_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "server.proto",
+}
+
+const (
+	Alerting_Alerts_FullMethodName = "/server.Alerting/Alerts"
+)
+
+// AlertingClient is the client API for Alerting service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AlertingClient interface {
+	// Alerts возвращает активные и недавно разрешённые оповещения.
+	Alerts(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AlertsResponse, error)
+}
+
+type alertingClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAlertingClient(cc grpc.ClientConnInterface) AlertingClient {
+	return &alertingClient{cc}
+}
+
+func (c *alertingClient) Alerts(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*AlertsResponse, error) {
+	out := new(AlertsResponse)
+	err := c.cc.Invoke(ctx, Alerting_Alerts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AlertingServer is the server API for Alerting service.
+// All implementations must embed UnimplementedAlertingServer
+// for forward compatibility
+type AlertingServer interface {
+	// Alerts возвращает активные и недавно разрешённые оповещения.
+	Alerts(context.Context, *emptypb.Empty) (*AlertsResponse, error)
+	mustEmbedUnimplementedAlertingServer()
+}
+
+// UnimplementedAlertingServer must be embedded to have forward compatible implementations.
+type UnimplementedAlertingServer struct {
+}
+
+func (UnimplementedAlertingServer) Alerts(context.Context, *emptypb.Empty) (*AlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Alerts not implemented")
+}
+func (UnimplementedAlertingServer) mustEmbedUnimplementedAlertingServer() {}
+
+// UnsafeAlertingServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AlertingServer will
+// result in compilation errors.
+type UnsafeAlertingServer interface {
+	mustEmbedUnimplementedAlertingServer()
+}
+
+func RegisterAlertingServer(s grpc.ServiceRegistrar, srv AlertingServer) {
+	s.RegisterService(&Alerting_ServiceDesc, srv)
+}
+
+func _Alerting_Alerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AlertingServer).Alerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Alerting_Alerts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AlertingServer).Alerts(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Alerting_ServiceDesc is the grpc.ServiceDesc for Alerting service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Alerting_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "server.Alerting",
+	HandlerType: (*AlertingServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Alerts",
+			Handler:    _Alerting_Alerts_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "server.proto",
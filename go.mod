@@ -14,6 +14,7 @@ require (
 	github.com/sirupsen/logrus v1.9.2
 	github.com/stretchr/testify v1.8.2
 	github.com/timakin/bodyclose v0.0.0-20230421092635-574207250966
+	go.opentelemetry.io/proto/otlp v0.19.0
 	golang.org/x/sync v0.3.0
 	golang.org/x/tools v0.13.0
 	google.golang.org/grpc v1.51.0
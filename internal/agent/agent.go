@@ -18,12 +18,19 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var ErrServerAddressAnknown = errors.New("unknown server address")
+var (
+	ErrServerAddressAnknown = errors.New("unknown server address")
+	// ErrUnknownServersMode возвращается NewAgent, если указан неизвестный режим работы с несколькими серверами.
+	ErrUnknownServersMode = errors.New("unknown servers mode")
+)
 
 // Agent содержит основные параметры агента.
 type Agent struct {
 	pollInterval   time.Duration
 	reportInterval time.Duration
+	collectors     []scheduledCollector
+	labels         metric.Labels
+	gauges         *gaugeAggregator
 	sender         sender.Sender
 	l              *log.Logger
 }
@@ -35,12 +42,30 @@ func NewAgent(cfg *config.Config, l *log.Logger) (*Agent, error) {
 		lg = l
 	}
 
-	var (
-		snd sender.Sender
-		err error
-	)
+	labels, err := metric.ParseLabels(cfg.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("labels parsing error: %w", err)
+	}
+
+	collectors, err := parseCollectors(cfg.Collectors, cfg.PollInterval.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("collectors initialization error: %w", err)
+	}
+
+	gauges, err := newGaugeAggregator(cfg.GaugeStats, cfg.GaugeStatsMode)
+	if err != nil {
+		return nil, fmt.Errorf("gauge statistics initialization error: %w", err)
+	}
+
+	var snd sender.Sender
 
 	switch {
+	case cfg.Servers != "" && cfg.ServersMode == config.ServersFailover:
+		snd, err = sender.NewFailoverSender(cfg, lg)
+	case cfg.Servers != "" && cfg.ServersMode == config.ServersFanout:
+		snd, err = sender.NewFanoutSender(cfg, lg)
+	case cfg.Servers != "":
+		return nil, fmt.Errorf("%w: %s", ErrUnknownServersMode, cfg.ServersMode)
 	case cfg.GRPCAddress != "":
 		snd, err = sender.NewGRPCSender(cfg, lg)
 	case cfg.HTTPAddress != "":
@@ -56,6 +81,9 @@ func NewAgent(cfg *config.Config, l *log.Logger) (*Agent, error) {
 	return &Agent{
 		pollInterval:   cfg.PollInterval.Duration,
 		reportInterval: cfg.ReportInterval.Duration,
+		collectors:     collectors,
+		labels:         labels,
+		gauges:         gauges,
 		sender:         snd,
 		l:              lg,
 	}, nil
@@ -65,14 +93,20 @@ func NewAgent(cfg *config.Config, l *log.Logger) (*Agent, error) {
 func (a *Agent) Run(ctx context.Context) error {
 	a.l.Info("Agent is running...")
 
+	defer func() {
+		if err := a.sender.Close(); err != nil {
+			a.l.Errorf("error closing sender: %s", err)
+		}
+	}()
+
 	var (
 		scanCount int64
+		results   = make(map[time.Duration][]metric.Metrics) // последние результаты сканирования по интервалам
+		counters  = make(map[string]metric.Metrics)          // приращения счётчиков с момента последней отправки
 		storage   []metric.Metrics
 		mtx       sync.Mutex
-		err       error
 	)
 
-	scanTicker := time.NewTicker(a.pollInterval)
 	sendTicker := time.NewTicker(a.reportInterval)
 
 	sigCtx, sigCancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -80,38 +114,62 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	g, gCtx := errgroup.WithContext(ctx)
 
-	g.Go(func() error {
-		for {
-			select {
-			case <-gCtx.Done():
-				return nil
+	// Коллекторы с одинаковым интервалом опроса сканируются совместно
+	for interval, collectors := range groupCollectors(a.collectors, a.pollInterval) {
+		interval, collectors := interval, collectors
 
-			case <-sigCtx.Done():
-				a.l.Info("Metrics scanner stopped gracefully")
+		g.Go(func() error {
+			scanTicker := time.NewTicker(interval)
+			defer scanTicker.Stop()
 
-				return nil
+			for {
+				select {
+				case <-gCtx.Done():
+					return nil
 
-			case <-scanTicker.C:
-				metricCh := ScanMetrics(gCtx)
+				case <-sigCtx.Done():
+					a.l.Infof("Metrics scanner (%s) stopped gracefully", interval)
 
-				mtx.Lock()
+					return nil
+
+				case <-scanTicker.C:
+					buf := make([]metric.Metrics, 0)
+					deltas := make([]metric.Metrics, 0)
 
-				storage = make([]metric.Metrics, 0)
+					for mtrc := range ScanMetrics(gCtx, collectors) {
+						if mtrc.err != nil {
+							a.l.Errorf("error scanning metrics: %s", mtrc.err)
 
-				for mtrc := range metricCh {
-					if mtrc.err != nil {
-						return err
+							continue
+						}
+
+						// Приращения счётчиков накапливаются до отправки, чтобы не потерять промежуточные опросы
+						if mtrc.mtrc.MType == metric.CounterMetric {
+							deltas = append(deltas, mtrc.mtrc)
+
+							continue
+						}
+
+						buf = append(buf, mtrc.mtrc)
 					}
 
-					storage = append(storage, mtrc.mtrc)
-				}
+					mtx.Lock()
 
-				scanCount++
+					results[interval] = buf
+					a.gauges.add(buf)
+					accumulateCounters(counters, deltas)
+					storage = mergeResults(results, counters)
 
-				mtx.Unlock()
+					// PollCount отражает количество опросов с основным интервалом агента
+					if interval == a.pollInterval {
+						scanCount++
+					}
+
+					mtx.Unlock()
+				}
 			}
-		}
-	})
+		})
+	}
 
 	g.Go(func() error {
 		for {
@@ -122,7 +180,7 @@ func (a *Agent) Run(ctx context.Context) error {
 			case <-sigCtx.Done():
 				mtx.Lock()
 
-				sndStorage, err := metricsPreparation(storage, scanCount)
+				sndStorage, err := metricsPreparation(a.gauges.aggregate(storage), scanCount, a.labels)
 				if err != nil {
 					return err
 				}
@@ -140,13 +198,20 @@ func (a *Agent) Run(ctx context.Context) error {
 			case <-sendTicker.C:
 				mtx.Lock()
 
-				sndStorage, err := metricsPreparation(storage, scanCount)
+				// Значения gauge, полученные за интервал отправки, заменяются их статистиками
+				sndStorage, err := metricsPreparation(a.gauges.aggregate(storage), scanCount, a.labels)
 				if err != nil {
 					return err
 				}
 
 				scanCount = 0
 
+				for key := range counters {
+					delete(counters, key)
+				}
+
+				storage = mergeResults(results, counters)
+
 				mtx.Unlock()
 
 				if err = a.sender.Send(ctx, sndStorage); err != nil {
@@ -160,7 +225,8 @@ func (a *Agent) Run(ctx context.Context) error {
 }
 
 // metricsPreparation выполняет подготовку метрик к отправке на сервер.
-func metricsPreparation(storage []metric.Metrics, scanCount int64) ([]metric.Metrics, error) {
+// Ко всем метрикам добавляется набор меток агента; собственные метки метрики имеют приоритет.
+func metricsPreparation(storage []metric.Metrics, scanCount int64, labels metric.Labels) ([]metric.Metrics, error) {
 	pollCount, err := metric.NewMetrics("PollCount", "", scanCount)
 	if err != nil {
 		return nil, err
@@ -171,5 +237,25 @@ func metricsPreparation(storage []metric.Metrics, scanCount int64) ([]metric.Met
 
 	copy(sndStorage, storage)
 
+	if len(labels) > 0 {
+		for i := range sndStorage {
+			sndStorage[i].Labels = mergeLabels(labels, sndStorage[i].Labels)
+		}
+	}
+
 	return sndStorage, nil
 }
+
+// mergeLabels объединяет наборы меток агента и метрики. Метки метрики имеют приоритет.
+func mergeLabels(agentLabels, metricLabels metric.Labels) metric.Labels {
+	if len(metricLabels) == 0 {
+		return agentLabels
+	}
+
+	res := agentLabels.Copy()
+	for name, value := range metricLabels {
+		res[name] = value
+	}
+
+	return res
+}
@@ -24,15 +24,15 @@ func TestRun(t *testing.T) {
 
 	agent, err := NewAgent(
 		&config.Config{
-			PollInterval:   utils.Duration{Duration: 1 * time.Second},
-			ReportInterval: utils.Duration{Duration: 2 * time.Second},
-			Retries:        "1",
-			HTTPAddress:    server.URL,
-			Key:            "secret",
-			RateLimit:      2,
-			ServerTimeout:  utils.Duration{Duration: 10 * time.Second},
-			BatchSize:      1,
-			PublicKey:      &privateKey.PublicKey,
+			PollInterval:     utils.Duration{Duration: 1 * time.Second},
+			ReportInterval:   utils.Duration{Duration: 2 * time.Second},
+			RetryMaxAttempts: 1,
+			HTTPAddress:      server.URL,
+			Key:              "secret",
+			RateLimit:        2,
+			ServerTimeout:    utils.Duration{Duration: 10 * time.Second},
+			BatchSize:        1,
+			PublicKey:        &privateKey.PublicKey,
 		},
 		logrus.StandardLogger(),
 	)
@@ -0,0 +1,218 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+// gaugeStatLabel - метка, содержащая название статистики в режиме config.GaugeStatsSummary.
+const gaugeStatLabel = "stat"
+
+var (
+	// ErrUnknownGaugeStat возвращается, если указана неизвестная статистика значений gauge.
+	ErrUnknownGaugeStat = errors.New("unknown gauge statistic")
+	// ErrUnknownGaugeStatsMode возвращается, если указан неизвестный режим отправки статистик значений gauge.
+	ErrUnknownGaugeStatsMode = errors.New("unknown gauge statistics mode")
+)
+
+// gaugeStat описывает статистику значений метрики типа gauge за интервал отправки.
+type gaugeStat struct {
+	name   string // название статистики, используемое в метке gaugeStatLabel
+	suffix string // суффикс имени метрики в режиме config.GaugeStatsMetrics
+	// compute вычисляет статистику по значениям в порядке получения values
+	// и тем же значениям, упорядоченным по возрастанию, sorted
+	compute func(values, sorted []float64) float64
+}
+
+// parseGaugeStats разбирает описание статистик в формате "last,min,max,mean,p50,p99.9".
+// Пустое описание соответствует единственной статистике last.
+func parseGaugeStats(spec string) ([]gaugeStat, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = "last"
+	}
+
+	res := make([]gaugeStat, 0)
+	seen := make(map[string]struct{})
+
+	for _, item := range strings.Split(spec, ",") {
+		name := strings.ToLower(strings.TrimSpace(item))
+
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate statistic %s", ErrUnknownGaugeStat, name)
+		}
+
+		seen[name] = struct{}{}
+
+		stat, err := newGaugeStat(name)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, stat)
+	}
+
+	return res, nil
+}
+
+// newGaugeStat создаёт статистику по её названию.
+func newGaugeStat(name string) (gaugeStat, error) {
+	switch name {
+	case "last":
+		return gaugeStat{name: name, compute: func(values, _ []float64) float64 {
+			return values[len(values)-1]
+		}}, nil
+	case "min":
+		return gaugeStat{name: name, suffix: "Min", compute: func(_, sorted []float64) float64 {
+			return sorted[0]
+		}}, nil
+	case "max":
+		return gaugeStat{name: name, suffix: "Max", compute: func(_, sorted []float64) float64 {
+			return sorted[len(sorted)-1]
+		}}, nil
+	case "mean":
+		return gaugeStat{name: name, suffix: "Mean", compute: func(values, _ []float64) float64 {
+			var sum float64
+			for _, v := range values {
+				sum += v
+			}
+
+			return sum / float64(len(values))
+		}}, nil
+	}
+
+	if !strings.HasPrefix(name, "p") {
+		return gaugeStat{}, fmt.Errorf("%w: %s", ErrUnknownGaugeStat, name)
+	}
+
+	p, err := strconv.ParseFloat(strings.TrimPrefix(name, "p"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return gaugeStat{}, fmt.Errorf("%w: %s", ErrUnknownGaugeStat, name)
+	}
+
+	return gaugeStat{
+		name:   name,
+		suffix: "P" + strings.ReplaceAll(strings.TrimPrefix(name, "p"), ".", "_"),
+		compute: func(_, sorted []float64) float64 {
+			return percentile(sorted, p)
+		},
+	}, nil
+}
+
+// percentile вычисляет перцентиль p упорядоченных по возрастанию значений sorted
+// с линейной интерполяцией между соседними значениями.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[lower] + (sorted[lower+1]-sorted[lower])*(rank-float64(lower))
+}
+
+// gaugeAggregator накапливает значения метрик типа gauge между отправками
+// и заменяет их статистиками за интервал отправки.
+type gaugeAggregator struct {
+	stats   []gaugeStat
+	summary bool
+	samples map[string][]float64 // значения метрик за текущий интервал отправки
+}
+
+// newGaugeAggregator создаёт агрегатор по описанию статистик spec и режиму их отправки mode.
+func newGaugeAggregator(spec, mode string) (*gaugeAggregator, error) {
+	stats, err := parseGaugeStats(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode != "" && mode != config.GaugeStatsMetrics && mode != config.GaugeStatsSummary {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownGaugeStatsMode, mode)
+	}
+
+	return &gaugeAggregator{
+		stats:   stats,
+		summary: mode == config.GaugeStatsSummary,
+		samples: make(map[string][]float64),
+	}, nil
+}
+
+// passthrough возвращает true, если агрегатор отправляет только последние значения метрик без изменений.
+func (g *gaugeAggregator) passthrough() bool {
+	return !g.summary && len(g.stats) == 1 && g.stats[0].name == "last"
+}
+
+// add добавляет значения метрик типа gauge из результатов опроса mtrcs.
+func (g *gaugeAggregator) add(mtrcs []metric.Metrics) {
+	if g.passthrough() {
+		return
+	}
+
+	for _, mtrc := range mtrcs {
+		if mtrc.MType != metric.GaugeMetric || mtrc.Value == nil {
+			continue
+		}
+
+		key := mtrc.ID + mtrc.Labels.String()
+		g.samples[key] = append(g.samples[key], *mtrc.Value)
+	}
+}
+
+// aggregate заменяет метрики типа gauge из storage статистиками их значений за интервал отправки
+// и начинает новый интервал. Для метрик без значений за интервал статистики вычисляются
+// по последнему известному значению. Метрики других типов не изменяются.
+func (g *gaugeAggregator) aggregate(storage []metric.Metrics) []metric.Metrics {
+	if g.passthrough() {
+		return storage
+	}
+
+	res := make([]metric.Metrics, 0, len(storage)*len(g.stats))
+
+	for _, mtrc := range storage {
+		if mtrc.MType != metric.GaugeMetric || mtrc.Value == nil {
+			res = append(res, mtrc)
+
+			continue
+		}
+
+		values := g.samples[mtrc.ID+mtrc.Labels.String()]
+		if len(values) == 0 {
+			values = []float64{*mtrc.Value}
+		}
+
+		sorted := make([]float64, len(values))
+		copy(sorted, values)
+		sort.Float64s(sorted)
+
+		for _, stat := range g.stats {
+			value := stat.compute(values, sorted)
+			aggr := metric.Metrics{ID: mtrc.ID, MType: metric.GaugeMetric, Value: &value, Labels: mtrc.Labels}
+
+			if g.summary {
+				aggr.Labels = mtrc.Labels.Copy()
+				if aggr.Labels == nil {
+					aggr.Labels = make(metric.Labels, 1)
+				}
+
+				aggr.Labels[gaugeStatLabel] = stat.name
+			} else {
+				aggr.ID += stat.suffix
+			}
+
+			res = append(res, aggr)
+		}
+	}
+
+	for key := range g.samples {
+		delete(g.samples, key)
+	}
+
+	return res
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gauges создаёт результаты опроса с метриками HeapAlloc и Load1 типа gauge.
+func gauges(heapAlloc, load float64) []metric.Metrics {
+	return []metric.Metrics{
+		{ID: "HeapAlloc", MType: metric.GaugeMetric, Value: &heapAlloc},
+		{ID: "Load1", MType: metric.GaugeMetric, Value: &load, Labels: metric.Labels{"host": "a"}},
+	}
+}
+
+// gaugeValues возвращает значения метрик по их имени и метке stat.
+func gaugeValues(mtrcs []metric.Metrics) map[string]float64 {
+	res := make(map[string]float64)
+
+	for _, mtrc := range mtrcs {
+		if mtrc.Value != nil {
+			res[mtrc.ID+mtrc.Labels[gaugeStatLabel]] = *mtrc.Value
+		}
+	}
+
+	return res
+}
+
+func TestParseGaugeStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected []string
+		wantErr  error
+	}{
+		{
+			name:     "Default statistics",
+			spec:     "",
+			expected: []string{""},
+		},
+		{
+			name:     "All statistics",
+			spec:     "last, min,max,MEAN,p50,p99.9",
+			expected: []string{"", "Min", "Max", "Mean", "P50", "P99_9"},
+		},
+		{
+			name:    "Unknown statistic",
+			spec:    "median",
+			wantErr: ErrUnknownGaugeStat,
+		},
+		{
+			name:    "Wrong percentile",
+			spec:    "p101",
+			wantErr: ErrUnknownGaugeStat,
+		},
+		{
+			name:    "Duplicate statistic",
+			spec:    "max,max",
+			wantErr: ErrUnknownGaugeStat,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stats, err := parseGaugeStats(test.spec)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			suffixes := make([]string, 0, len(stats))
+			for _, stat := range stats {
+				suffixes = append(suffixes, stat.suffix)
+			}
+
+			assert.Equal(t, test.expected, suffixes)
+		})
+	}
+
+	_, err := newGaugeAggregator("", "histogram")
+	assert.ErrorIs(t, err, ErrUnknownGaugeStatsMode)
+}
+
+func TestGaugeAggregate(t *testing.T) {
+	var counterVal int64 = 5
+
+	counter := metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}
+
+	t.Run("Separate metrics", func(t *testing.T) {
+		g, err := newGaugeAggregator("last,min,max,mean,p50", config.GaugeStatsMetrics)
+		require.NoError(t, err)
+
+		for _, v := range []float64{30, 10, 50, 20} {
+			g.add(gauges(v, 1))
+		}
+
+		last := gauges(20, 1)
+		res := g.aggregate(append(last, counter))
+
+		assert.Equal(
+			t,
+			map[string]float64{
+				"HeapAlloc": 20, "HeapAllocMin": 10, "HeapAllocMax": 50, "HeapAllocMean": 27.5, "HeapAllocP50": 25,
+				"Load1": 1, "Load1Min": 1, "Load1Max": 1, "Load1Mean": 1, "Load1P50": 1,
+			},
+			gaugeValues(res),
+		)
+		assert.Contains(t, res, counter, "Metrics of other types must not be modified")
+
+		for _, mtrc := range res {
+			if mtrc.ID == "Load1Max" {
+				assert.Equal(t, metric.Labels{"host": "a"}, mtrc.Labels)
+			}
+		}
+
+		// Новый интервал начинается после отправки
+		res = g.aggregate(gauges(70, 1))
+		assert.Equal(t, 70.0, gaugeValues(res)["HeapAllocMin"])
+	})
+
+	t.Run("Summary", func(t *testing.T) {
+		g, err := newGaugeAggregator("last,max", config.GaugeStatsSummary)
+		require.NoError(t, err)
+
+		g.add(gauges(100, 1))
+		g.add(gauges(40, 2))
+
+		res := g.aggregate(gauges(40, 2))
+		require.Len(t, res, 4)
+		assert.Equal(
+			t,
+			map[string]float64{"HeapAlloclast": 40, "HeapAllocmax": 100, "Load1last": 2, "Load1max": 2},
+			gaugeValues(res),
+		)
+		assert.Equal(t, metric.Labels{"host": "a", gaugeStatLabel: "max"}, res[3].Labels)
+	})
+
+	t.Run("Last value only", func(t *testing.T) {
+		g, err := newGaugeAggregator("", "")
+		require.NoError(t, err)
+
+		g.add(gauges(100, 1))
+
+		storage := gauges(40, 1)
+		assert.Equal(t, storage, g.aggregate(storage))
+		assert.Empty(t, g.samples)
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+
+	tests := []struct {
+		p        float64
+		expected float64
+	}{
+		{p: 100, expected: 50},
+		{p: 50, expected: 30},
+		{p: 90, expected: 46},
+		{p: 1, expected: 10.4},
+	}
+
+	for _, test := range tests {
+		assert.InDelta(t, test.expected, percentile(sorted, test.p), 1e-9)
+	}
+
+	assert.Equal(t, 7.0, percentile([]float64{7}, 99))
+}
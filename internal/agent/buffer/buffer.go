@@ -0,0 +1,416 @@
+// Package buffer содержит реализацию дискового буфера метрик модуля-агента.
+//
+// Буфер используется для сохранения наборов метрик, которые не удалось отправить на сервер,
+// и их повторной отправки в исходном порядке после восстановления связи.
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// segmentExt - расширение файлов сегментов буфера.
+const segmentExt = ".seg"
+
+var (
+	// ErrBatchTooLarge возвращается Queue.Push, если размер набора метрик превышает допустимый размер буфера.
+	ErrBatchTooLarge = errors.New("batch exceeds buffer size")
+	// ErrWrongSize возвращается New, если указан неположительный размер буфера или сегмента.
+	ErrWrongSize = errors.New("wrong buffer size")
+)
+
+// SendFunc - функция отправки набора метрик, используемая при воспроизведении буфера.
+type SendFunc func(ctx context.Context, batch metric.Batch) error
+
+// segment описывает файл сегмента буфера.
+type segment struct {
+	seq    uint64
+	size   int64
+	sealed bool // признак того, что новые наборы в сегмент не дописываются
+}
+
+// Queue - дисковая очередь наборов метрик.
+//
+// Наборы метрик записываются построчно в JSON в файлы-сегменты каталога dir.
+// При превышении segmentSize запись продолжается в новый сегмент.
+// При превышении общего размера maxSize удаляются самые старые сегменты.
+type Queue struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	replayMtx sync.Mutex // исключает одновременное воспроизведение очереди
+
+	mtx      sync.Mutex
+	segments []segment // сегменты от самого старого к самому новому
+	size     int64
+	nextSeq  uint64
+
+	l *log.Logger
+}
+
+// New создаёт дисковую очередь в каталоге dir.
+// Если каталог содержит сегменты, оставшиеся с предыдущего запуска, они будут воспроизведены первыми.
+func New(dir string, maxSize, segmentSize int64, l *log.Logger) (*Queue, error) {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	if maxSize <= 0 || segmentSize <= 0 {
+		return nil, ErrWrongSize
+	}
+
+	if segmentSize > maxSize {
+		segmentSize = maxSize
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Queue{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: segmentSize,
+		segments:    make([]segment, 0, len(entries)),
+		nextSeq:     1,
+		l:           lg,
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != segmentExt {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		q.segments = append(q.segments, segment{seq: seq, size: info.Size()})
+		q.size += info.Size()
+
+		if seq >= q.nextSeq {
+			q.nextSeq = seq + 1
+		}
+	}
+
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i].seq < q.segments[j].seq })
+
+	return q, nil
+}
+
+// Len возвращает количество сегментов в очереди.
+func (q *Queue) Len() int {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return len(q.segments)
+}
+
+// Size возвращает общий размер сегментов очереди в байтах.
+func (q *Queue) Size() int64 {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	return q.size
+}
+
+// Push добавляет набор метрик в конец очереди.
+func (q *Queue) Push(batch metric.Batch) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	if int64(len(data)) > q.maxSize {
+		return fmt.Errorf("%w: %d bytes", ErrBatchTooLarge, len(data))
+	}
+
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if len(q.segments) == 0 || q.segments[len(q.segments)-1].sealed ||
+		q.segments[len(q.segments)-1].size+int64(len(data)) > q.segmentSize {
+		q.segments = append(q.segments, segment{seq: q.nextSeq})
+		q.nextSeq++
+	}
+
+	last := &q.segments[len(q.segments)-1]
+
+	if err = appendFile(q.segmentPath(last.seq), data); err != nil {
+		return err
+	}
+
+	last.size += int64(len(data))
+	q.size += int64(len(data))
+
+	for q.size > q.maxSize && len(q.segments) > 1 {
+		q.l.Warnf("Metrics buffer size exceeded: segment %d dropped (%d bytes)", q.segments[0].seq, q.segments[0].size)
+
+		if err = q.dropSegment(0); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Replay выполняет отправку наборов метрик из очереди в порядке их добавления.
+// Успешно отправленные наборы удаляются из очереди.
+// При первой ошибке отправки воспроизведение прекращается, ошибка возвращается вызывающему.
+//
+// Отправка выполняется без блокировки очереди, поэтому Push может добавлять наборы во время
+// воспроизведения: они записываются в новые сегменты и отправляются при следующем вызове Replay.
+func (q *Queue) Replay(ctx context.Context, send SendFunc) error {
+	q.replayMtx.Lock()
+	defer q.replayMtx.Unlock()
+
+	for _, seq := range q.sealSegments() {
+		lines, found, err := q.readSegment(seq)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, bufio.ErrTooLong) {
+			// Сегмент удалён извне или записан с бо́льшим допустимым размером буфера
+			q.l.Errorf("Skipping unreadable buffer segment %d: %s", seq, err)
+
+			if err = q.removeSegment(seq); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			// Сегмент удалён Push при превышении размера буфера
+			continue
+		}
+
+		for i, line := range lines {
+			batch, err := unmarshalBatch(line)
+			if err != nil {
+				// Повреждённая запись (например, при аварийном завершении агента во время записи)
+				q.l.Errorf("Skipping malformed buffered batch in segment %d: %s", seq, err)
+
+				continue
+			}
+
+			if err = send(ctx, batch); err != nil {
+				if rewriteErr := q.rewriteSegment(seq, lines[i:]); rewriteErr != nil {
+					return fmt.Errorf("%w (segment rewrite error: %s)", err, rewriteErr)
+				}
+
+				return err
+			}
+		}
+
+		if err = q.removeSegment(seq); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sealSegments запрещает дописывать наборы в сегменты очереди
+// и возвращает их порядковые номера от самого старого к самому новому.
+func (q *Queue) sealSegments() []uint64 {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	seqs := make([]uint64, 0, len(q.segments))
+	for i := range q.segments {
+		q.segments[i].sealed = true
+		seqs = append(seqs, q.segments[i].seq)
+	}
+
+	return seqs
+}
+
+// readSegment возвращает записи сегмента с порядковым номером seq.
+// Если сегмент уже удалён из очереди, возвращается false.
+func (q *Queue) readSegment(seq uint64) ([][]byte, bool, error) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.segmentIndex(seq) < 0 {
+		return nil, false, nil
+	}
+
+	lines, err := readLines(q.segmentPath(seq), q.maxSize)
+
+	return lines, true, err
+}
+
+// removeSegment удаляет сегмент с порядковым номером seq, если он ещё находится в очереди.
+func (q *Queue) removeSegment(seq uint64) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	i := q.segmentIndex(seq)
+	if i < 0 {
+		return nil
+	}
+
+	return q.dropSegment(i)
+}
+
+// segmentIndex возвращает индекс сегмента с порядковым номером seq или -1, если его нет в очереди.
+func (q *Queue) segmentIndex(seq uint64) int {
+	for i := range q.segments {
+		if q.segments[i].seq == seq {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// dropSegment удаляет сегмент очереди с индексом i.
+func (q *Queue) dropSegment(i int) error {
+	seg := q.segments[i]
+
+	if err := os.Remove(q.segmentPath(seg.seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	q.segments = append(q.segments[:i], q.segments[i+1:]...)
+	q.size -= seg.size
+
+	return nil
+}
+
+// rewriteSegment перезаписывает сегмент с порядковым номером seq оставшимися в нём наборами метрик lines.
+// Если сегмент уже удалён из очереди, перезапись не выполняется.
+func (q *Queue) rewriteSegment(seq uint64, lines [][]byte) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	i := q.segmentIndex(seq)
+	if i < 0 {
+		return nil
+	}
+
+	seg := &q.segments[i]
+
+	data := append(bytes.Join(lines, []byte{'\n'}), '\n')
+	if int64(len(data)) == seg.size {
+		return nil
+	}
+
+	path := q.segmentPath(seg.seq)
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	q.size += int64(len(data)) - seg.size
+	seg.size = int64(len(data))
+
+	return nil
+}
+
+// unmarshalBatch разбирает запись сегмента.
+// Записи, сделанные до появления идентификаторов наборов, содержат только массив метрик;
+// таким наборам присваивается новый идентификатор.
+func unmarshalBatch(line []byte) (metric.Batch, error) {
+	if bytes.HasPrefix(line, []byte("[")) {
+		var mtrcs []metric.Metrics
+		if err := json.Unmarshal(line, &mtrcs); err != nil {
+			return metric.Batch{}, err
+		}
+
+		return metric.NewBatch(mtrcs), nil
+	}
+
+	var batch metric.Batch
+	err := json.Unmarshal(line, &batch)
+
+	return batch, err
+}
+
+// segmentPath возвращает путь до файла сегмента с порядковым номером seq.
+func (q *Queue) segmentPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// appendFile дописывает data в конец файла path.
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// readLines возвращает непустые строки файла path длиной не более maxLen.
+func readLines(path string, maxLen int64) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	lines := make([][]byte, 0)
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), int(maxLen)+1)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+
+	return lines, scanner.Err()
+}
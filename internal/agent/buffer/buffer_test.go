@@ -0,0 +1,183 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSend = errors.New("send error")
+
+// newBatch создаёт набор из одной метрики типа counter.
+func newBatch(name string, delta int64) metric.Batch {
+	return metric.NewBatch([]metric.Metrics{{ID: name, MType: metric.CounterMetric, Delta: &delta}})
+}
+
+// collect возвращает функцию отправки, сохраняющую имена отправленных метрик.
+// После отправки limit наборов функция возвращает errSend.
+func collect(sent *[]string, limit int) SendFunc {
+	return func(ctx context.Context, batch metric.Batch) error {
+		if len(*sent) >= limit {
+			return errSend
+		}
+
+		for _, mtrc := range batch.Metrics {
+			*sent = append(*sent, mtrc.ID)
+		}
+
+		return nil
+	}
+}
+
+func TestQueueReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := New(dir, 1<<20, 64, nil)
+	require.NoError(t, err)
+
+	names := []string{"m1", "m2", "m3", "m4", "m5"}
+	for i, name := range names {
+		require.NoError(t, q.Push(newBatch(name, int64(i))))
+	}
+
+	assert.Greater(t, q.Len(), 1, "Batches must be split into segments")
+
+	// Отправка прерывается на третьем наборе
+	sent := make([]string, 0)
+	err = q.Replay(context.Background(), collect(&sent, 2))
+	assert.ErrorIs(t, err, errSend)
+	assert.Equal(t, names[:2], sent)
+
+	// Оставшиеся наборы сохраняются между запусками агента
+	q, err = New(dir, 1<<20, 64, nil)
+	require.NoError(t, err)
+
+	sent = make([]string, 0)
+	require.NoError(t, q.Replay(context.Background(), collect(&sent, len(names))))
+	assert.Equal(t, names[2:], sent)
+	assert.Zero(t, q.Len())
+	assert.Zero(t, q.Size())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Нумерация сегментов продолжается после опустошения очереди
+	require.NoError(t, q.Push(newBatch("m6", 6)))
+
+	sent = make([]string, 0)
+	require.NoError(t, q.Replay(context.Background(), collect(&sent, 1)))
+	assert.Equal(t, []string{"m6"}, sent)
+}
+
+func TestQueueSizeLimit(t *testing.T) {
+	batch := newBatch("m0", 0)
+
+	q, err := New(t.TempDir(), 200, 1, nil)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, q.Push(newBatch("m"+string(rune('0'+i)), int64(i))))
+	}
+
+	assert.LessOrEqual(t, q.Size(), int64(200))
+
+	sent := make([]string, 0)
+	require.NoError(t, q.Replay(context.Background(), collect(&sent, 10)))
+	require.NotEmpty(t, sent)
+	assert.Equal(t, "m9", sent[len(sent)-1], "Oldest segments must be dropped first")
+	assert.NotContains(t, sent, "m0")
+
+	q, err = New(t.TempDir(), 10, 10, nil)
+	require.NoError(t, err)
+	assert.ErrorIs(t, q.Push(batch), ErrBatchTooLarge)
+
+	_, err = New(t.TempDir(), 0, 10, nil)
+	assert.ErrorIs(t, err, ErrWrongSize)
+}
+
+func TestQueueMalformedBatch(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := New(dir, 1<<20, 1<<10, nil)
+	require.NoError(t, err)
+	require.NoError(t, q.Push(newBatch("m1", 1)))
+
+	// Запись, оборванная при аварийном завершении агента
+	file, err := os.OpenFile(filepath.Join(dir, "00000000000000000001.seg"), os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`[{"id":"m2"` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	q, err = New(dir, 1<<20, 1<<10, nil)
+	require.NoError(t, err)
+	require.NoError(t, q.Push(newBatch("m3", 3)))
+
+	sent := make([]string, 0)
+	require.NoError(t, q.Replay(context.Background(), collect(&sent, 10)))
+	assert.Equal(t, []string{"m1", "m3"}, sent)
+}
+
+func TestQueueBatchID(t *testing.T) {
+	dir := t.TempDir()
+
+	q, err := New(dir, 1<<20, 1<<10, nil)
+	require.NoError(t, err)
+
+	batch := newBatch("m1", 1)
+	require.NoError(t, q.Push(batch))
+
+	// Запись, сделанная до появления идентификаторов наборов
+	file, err := os.OpenFile(filepath.Join(dir, "00000000000000000001.seg"), os.O_WRONLY|os.O_APPEND, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`[{"id":"m2","type":"counter","delta":2}]` + "\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	q, err = New(dir, 1<<20, 1<<10, nil)
+	require.NoError(t, err)
+
+	ids := make([]string, 0)
+	err = q.Replay(context.Background(), func(ctx context.Context, b metric.Batch) error {
+		ids = append(ids, b.ID)
+
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+	assert.Equal(t, batch.ID, ids[0], "Batch ID must be preserved in the buffer")
+	assert.NotEmpty(t, ids[1])
+}
+
+func TestQueuePushDuringReplay(t *testing.T) {
+	q, err := New(t.TempDir(), 1<<20, 1<<10, nil)
+	require.NoError(t, err)
+	require.NoError(t, q.Push(newBatch("m1", 1)))
+
+	// Очередь не блокируется на время отправки, а набор, добавленный во время воспроизведения,
+	// не дописывается в отправляемый сегмент и не удаляется вместе с ним
+	sent := make([]string, 0)
+	err = q.Replay(context.Background(), func(ctx context.Context, batch metric.Batch) error {
+		for _, mtrc := range batch.Metrics {
+			sent = append(sent, mtrc.ID)
+		}
+
+		return q.Push(newBatch("m2", 2))
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"m1"}, sent)
+	assert.Equal(t, 1, q.Len())
+
+	sent = make([]string, 0)
+	require.NoError(t, q.Replay(context.Background(), collect(&sent, 10)))
+	assert.Equal(t, []string{"m2"}, sent)
+	assert.Zero(t, q.Len())
+	assert.Zero(t, q.Size())
+}
@@ -0,0 +1,150 @@
+// Package collector содержит источники метрик модуля-агента.
+//
+// Коллекторы регистрируются в реестре под уникальным именем и подключаются
+// через конфигурацию агента. Сторонние коллекторы могут быть добавлены вызовом Register.
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+var (
+	// ErrUnknownCollector возвращается, если коллектор с указанным именем не зарегистрирован.
+	ErrUnknownCollector = errors.New("unknown collector")
+	// ErrCollectorExists возвращается Register, если коллектор с указанным именем уже зарегистрирован.
+	ErrCollectorExists = errors.New("collector is already registered")
+)
+
+// Collector описывает источник метрик агента.
+type Collector interface {
+	// Collect выполняет сбор метрик.
+	Collect(ctx context.Context) ([]metric.Metrics, error)
+}
+
+// Factory создаёт новый экземпляр коллектора.
+type Factory func() (Collector, error)
+
+var (
+	registryMtx sync.RWMutex
+	registry    = map[string]Factory{
+		RuntimeName:  func() (Collector, error) { return NewRuntime(nil), nil },
+		PSUtilName:   func() (Collector, error) { return NewPSUtil(), nil },
+		DiskName:     func() (Collector, error) { return NewDisk(), nil },
+		NetName:      func() (Collector, error) { return NewNet(), nil },
+		LoadName:     func() (Collector, error) { return NewLoad(), nil },
+		SwapName:     func() (Collector, error) { return NewSwap(), nil },
+		FDName:       func() (Collector, error) { return NewFD(""), nil },
+		CPUTimesName: func() (Collector, error) { return NewCPUTimes(), nil },
+	}
+)
+
+// Register регистрирует фабрику коллектора под именем name.
+func Register(name string, factory Factory) error {
+	registryMtx.Lock()
+	defer registryMtx.Unlock()
+
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("%w: %s", ErrCollectorExists, name)
+	}
+
+	registry[name] = factory
+
+	return nil
+}
+
+// Lookup возвращает фабрику коллектора, зарегистрированного под именем name.
+func Lookup(name string) (Factory, error) {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	factory, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCollector, name)
+	}
+
+	return factory, nil
+}
+
+// Names возвращает отсортированный список имён зарегистрированных коллекторов.
+func Names() []string {
+	registryMtx.RLock()
+	defer registryMtx.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// newMetrics создаёт набор метрик по их значениям.
+func newMetrics(values map[string]interface{}) ([]metric.Metrics, error) {
+	res := make([]metric.Metrics, 0, len(values))
+
+	for mName, mVal := range values {
+		mtrc, err := metric.NewMetrics(mName, "", mVal)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, mtrc)
+	}
+
+	return res, nil
+}
+
+// newGauge создаёт метрику типа gauge с набором меток labels.
+func newGauge(name string, value float64, labels metric.Labels) metric.Metrics {
+	return metric.Metrics{ID: name, MType: metric.GaugeMetric, Value: &value, Labels: labels}
+}
+
+// newCounter создаёт метрику типа counter с набором меток labels.
+func newCounter(name string, delta int64, labels metric.Labels) metric.Metrics {
+	return metric.Metrics{ID: name, MType: metric.CounterMetric, Delta: &delta, Labels: labels}
+}
+
+// counterTracker преобразует накопительные значения системных счётчиков
+// в приращения между опросами, как того требует тип counter.
+type counterTracker struct {
+	mtx  sync.Mutex
+	prev map[string]uint64
+}
+
+// newCounterTracker создаёт новый counterTracker.
+func newCounterTracker() *counterTracker {
+	return &counterTracker{prev: make(map[string]uint64)}
+}
+
+// add добавляет в mtrcs приращение счётчика name с метками labels относительно предыдущего опроса.
+// При первом опросе счётчика запоминается только его значение.
+// Уменьшение значения считается сбросом счётчика, в этом случае приращением является текущее значение.
+func (t *counterTracker) add(
+	mtrcs []metric.Metrics, name string, value uint64, labels metric.Labels,
+) []metric.Metrics {
+	key := name + labels.String()
+
+	t.mtx.Lock()
+	prev, ok := t.prev[key]
+	t.prev[key] = value
+	t.mtx.Unlock()
+
+	if !ok {
+		return mtrcs
+	}
+
+	delta := value
+	if value >= prev {
+		delta = value - prev
+	}
+
+	return append(mtrcs, newCounter(name, int64(delta), labels))
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticCollector struct{}
+
+func (c staticCollector) Collect(_ context.Context) ([]metric.Metrics, error) {
+	mtrc, err := metric.NewMetrics("Static", "", 1.0)
+	if err != nil {
+		return nil, err
+	}
+
+	return []metric.Metrics{mtrc}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	assert.Subset(t, Names(), []string{RuntimeName, PSUtilName})
+
+	_, err := Lookup("unknown")
+	assert.ErrorIs(t, err, ErrUnknownCollector)
+
+	err = Register(RuntimeName, func() (Collector, error) { return staticCollector{}, nil })
+	assert.ErrorIs(t, err, ErrCollectorExists)
+
+	err = Register("static", func() (Collector, error) { return staticCollector{}, nil })
+	require.NoError(t, err)
+
+	factory, err := Lookup("static")
+	require.NoError(t, err)
+
+	c, err := factory()
+	require.NoError(t, err)
+
+	mtrcs, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	require.Len(t, mtrcs, 1)
+	assert.Equal(t, "Static", mtrcs[0].ID)
+}
+
+func TestCounterTracker(t *testing.T) {
+	tracker := newCounterTracker()
+	labels := metric.Labels{"device": "sda"}
+
+	mtrcs := tracker.add(nil, "DiskReadBytes", 100, labels)
+	assert.Empty(t, mtrcs, "First observation must only set the baseline")
+
+	mtrcs = tracker.add(nil, "DiskReadBytes", 150, labels)
+	require.Len(t, mtrcs, 1)
+	assert.Equal(t, metric.CounterMetric, mtrcs[0].MType)
+	assert.EqualValues(t, 50, *mtrcs[0].Delta)
+	assert.Equal(t, labels, mtrcs[0].Labels)
+
+	// Сброс счётчика
+	mtrcs = tracker.add(nil, "DiskReadBytes", 20, labels)
+	require.Len(t, mtrcs, 1)
+	assert.EqualValues(t, 20, *mtrcs[0].Delta)
+
+	mtrcs = tracker.add(nil, "DiskReadBytes", 10, metric.Labels{"device": "sdb"})
+	assert.Empty(t, mtrcs, "Counters with different labels must be tracked separately")
+}
@@ -0,0 +1,145 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/cpu"
+)
+
+// CPUTimesName - имя коллектора накопительного времени CPU.
+const CPUTimesName = "cputimes"
+
+// cpuTotalLabel - значение метки cpu для метрик, относящихся ко всем ядрам.
+const cpuTotalLabel = "total"
+
+// msInSecond - количество миллисекунд в секунде.
+const msInSecond = 1000
+
+// percent - коэффициент перевода доли в проценты.
+const percent = 100
+
+// cpuUsage вычисляет утилизацию CPU между двумя последовательными снимками времени CPU.
+type cpuUsage struct {
+	mtx  sync.Mutex
+	prev []cpu.TimesStat
+}
+
+// collect добавляет в mtrcs процент утилизации CPU по ядрам и суммарно с момента предыдущего снимка times.
+// При первом снимке, а также при изменении количества ядер, запоминается только сам снимок.
+//
+// Для каждого ядра публикуются метрики CPUutilization<N> (доля времени, проведённого не в простое),
+// а также CPUUser, CPUSystem, CPUIowait и CPUIdle с меткой cpu. Суммарные значения по всем ядрам
+// публикуются как CPUutilizationTotal и с меткой cpu=total.
+func (u *cpuUsage) collect(mtrcs []metric.Metrics, times []cpu.TimesStat) []metric.Metrics {
+	u.mtx.Lock()
+	prev := u.prev
+	u.prev = times
+	u.mtx.Unlock()
+
+	if len(prev) != len(times) {
+		return mtrcs
+	}
+
+	var prevTotal, curTotal cpu.TimesStat
+
+	for i := range times {
+		mtrcs = appendCPUUsage(mtrcs, prev[i], times[i], fmt.Sprintf("CPUutilization%d", i), strconv.Itoa(i))
+
+		prevTotal = sumCPUTimes(prevTotal, prev[i])
+		curTotal = sumCPUTimes(curTotal, times[i])
+	}
+
+	return appendCPUUsage(mtrcs, prevTotal, curTotal, "CPUutilizationTotal", cpuTotalLabel)
+}
+
+// appendCPUUsage добавляет в mtrcs метрики утилизации CPU между снимками prev и cur.
+// Если время между снимками не изменилось, метрики не добавляются.
+func appendCPUUsage(mtrcs []metric.Metrics, prev, cur cpu.TimesStat, name, label string) []metric.Metrics {
+	elapsed := cpuTime(cur) - cpuTime(prev)
+	if elapsed <= 0 {
+		return mtrcs
+	}
+
+	share := func(prev, cur float64) float64 {
+		if cur < prev {
+			return 0
+		}
+
+		return (cur - prev) / elapsed * percent
+	}
+
+	labels := metric.Labels{"cpu": label}
+	idle := share(prev.Idle, cur.Idle)
+	iowait := share(prev.Iowait, cur.Iowait)
+
+	busy := percent - idle - iowait
+	if busy < 0 {
+		busy = 0
+	}
+
+	return append(
+		mtrcs,
+		newGauge(name, busy, nil),
+		newGauge("CPUUser", share(prev.User, cur.User), labels),
+		newGauge("CPUSystem", share(prev.System, cur.System), labels),
+		newGauge("CPUIowait", iowait, labels),
+		newGauge("CPUIdle", idle, labels),
+	)
+}
+
+// cpuTime возвращает полное время CPU в снимке.
+// Время Guest и GuestNice не учитывается, т.к. уже включено в User и Nice.
+func cpuTime(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// sumCPUTimes возвращает сумму снимков времени CPU.
+func sumCPUTimes(a, b cpu.TimesStat) cpu.TimesStat {
+	return cpu.TimesStat{
+		CPU:     cpuTotalLabel,
+		User:    a.User + b.User,
+		System:  a.System + b.System,
+		Idle:    a.Idle + b.Idle,
+		Nice:    a.Nice + b.Nice,
+		Iowait:  a.Iowait + b.Iowait,
+		Irq:     a.Irq + b.Irq,
+		Softirq: a.Softirq + b.Softirq,
+		Steal:   a.Steal + b.Steal,
+	}
+}
+
+// CPUTimes - коллектор накопительного времени CPU.
+// Время в режимах user, system, iowait и idle публикуется по ядрам в виде счётчиков в миллисекундах.
+type CPUTimes struct {
+	counters *counterTracker
+}
+
+// NewCPUTimes создаёт новый коллектор накопительного времени CPU.
+func NewCPUTimes() *CPUTimes {
+	return &CPUTimes{counters: newCounterTracker()}
+}
+
+// Collect выполняет сбор накопительного времени CPU.
+func (c *CPUTimes) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	times, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]metric.Metrics, 0)
+
+	for i, ts := range times {
+		labels := metric.Labels{"cpu": strconv.Itoa(i)}
+
+		res = c.counters.add(res, "CPUTimeUser", uint64(ts.User*msInSecond), labels)
+		res = c.counters.add(res, "CPUTimeSystem", uint64(ts.System*msInSecond), labels)
+		res = c.counters.add(res, "CPUTimeIowait", uint64(ts.Iowait*msInSecond), labels)
+		res = c.counters.add(res, "CPUTimeIdle", uint64(ts.Idle*msInSecond), labels)
+	}
+
+	return res, nil
+}
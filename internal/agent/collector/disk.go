@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/disk"
+)
+
+// DiskName - имя коллектора метрик дисков.
+const DiskName = "disk"
+
+// Disk - коллектор метрик использования дисков (по точкам монтирования)
+// и дискового ввода-вывода (по устройствам).
+type Disk struct {
+	counters *counterTracker
+}
+
+// NewDisk создаёт новый коллектор метрик дисков.
+func NewDisk() *Disk {
+	return &Disk{counters: newCounterTracker()}
+}
+
+// Collect выполняет сбор метрик дисков.
+// Точки монтирования, для которых не удалось получить статистику, пропускаются.
+func (c *Disk) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	partitions, err := disk.PartitionsWithContext(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]metric.Metrics, 0)
+
+	for _, p := range partitions {
+		usage, err := disk.UsageWithContext(ctx, p.Mountpoint)
+		if err != nil || usage.Total == 0 {
+			continue
+		}
+
+		labels := metric.Labels{"mountpoint": p.Mountpoint}
+
+		res = append(res,
+			newGauge("DiskTotal", float64(usage.Total), labels),
+			newGauge("DiskUsed", float64(usage.Used), labels),
+			newGauge("DiskFree", float64(usage.Free), labels),
+			newGauge("DiskUsedPercent", usage.UsedPercent, labels),
+			newGauge("DiskInodesUsedPercent", usage.InodesUsedPercent, labels),
+		)
+	}
+
+	ioStat, err := disk.IOCountersWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, io := range ioStat {
+		labels := metric.Labels{"device": name}
+
+		res = c.counters.add(res, "DiskReadBytes", io.ReadBytes, labels)
+		res = c.counters.add(res, "DiskWriteBytes", io.WriteBytes, labels)
+		res = c.counters.add(res, "DiskReadCount", io.ReadCount, labels)
+		res = c.counters.add(res, "DiskWriteCount", io.WriteCount, labels)
+		res = c.counters.add(res, "DiskIOTime", io.IoTime, labels)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,67 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+// FDName - имя коллектора метрик файловых дескрипторов.
+const FDName = "fd"
+
+// fileNrPath - путь до файла со статистикой файловых дескрипторов системы (Linux).
+const fileNrPath = "/proc/sys/fs/file-nr"
+
+// ErrWrongFileNr возвращается, если содержимое файла статистики файловых дескрипторов не соответствует формату.
+var ErrWrongFileNr = errors.New("wrong file-nr format")
+
+// FD - коллектор метрик открытых файловых дескрипторов системы.
+type FD struct {
+	path string
+}
+
+// NewFD создаёт новый коллектор метрик файловых дескрипторов.
+// Если путь path не задан, используется /proc/sys/fs/file-nr.
+func NewFD(path string) *FD {
+	if path == "" {
+		path = fileNrPath
+	}
+
+	return &FD{path: path}
+}
+
+// Collect выполняет сбор метрик файловых дескрипторов.
+// Файл статистики содержит три числа: количество выделенных дескрипторов,
+// количество выделенных, но неиспользуемых дескрипторов, и максимальное количество дескрипторов.
+func (c *FD) Collect(_ context.Context) ([]metric.Metrics, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%w: %q", ErrWrongFileNr, data)
+	}
+
+	values := make([]float64, 0, len(fields))
+
+	for _, field := range fields {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrWrongFileNr, err)
+		}
+
+		values = append(values, float64(v))
+	}
+
+	return []metric.Metrics{
+		newGauge("OpenFileDescriptors", values[0]-values[1], nil),
+		newGauge("MaxFileDescriptors", values[2], nil),
+	}, nil
+}
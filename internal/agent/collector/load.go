@@ -0,0 +1,33 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/load"
+)
+
+// LoadName - имя коллектора средней загрузки системы.
+const LoadName = "load"
+
+// Load - коллектор средней загрузки системы (load average).
+type Load struct{}
+
+// NewLoad создаёт новый коллектор средней загрузки системы.
+func NewLoad() *Load {
+	return &Load{}
+}
+
+// Collect выполняет сбор средней загрузки системы за 1, 5 и 15 минут.
+func (c *Load) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return []metric.Metrics{
+		newGauge("Load1", avg.Load1, nil),
+		newGauge("Load5", avg.Load5, nil),
+		newGauge("Load15", avg.Load15, nil),
+	}, nil
+}
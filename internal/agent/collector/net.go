@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/net"
+)
+
+// NetName - имя коллектора сетевых метрик.
+const NetName = "net"
+
+// Net - коллектор метрик сетевых интерфейсов.
+type Net struct {
+	counters *counterTracker
+}
+
+// NewNet создаёт новый коллектор сетевых метрик.
+func NewNet() *Net {
+	return &Net{counters: newCounterTracker()}
+}
+
+// Collect выполняет сбор сетевых метрик по каждому интерфейсу.
+func (c *Net) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	ioStat, err := net.IOCountersWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]metric.Metrics, 0)
+
+	for _, io := range ioStat {
+		labels := metric.Labels{"interface": io.Name}
+
+		res = c.counters.add(res, "NetBytesSent", io.BytesSent, labels)
+		res = c.counters.add(res, "NetBytesRecv", io.BytesRecv, labels)
+		res = c.counters.add(res, "NetPacketsSent", io.PacketsSent, labels)
+		res = c.counters.add(res, "NetPacketsRecv", io.PacketsRecv, labels)
+		res = c.counters.add(res, "NetErrin", io.Errin, labels)
+		res = c.counters.add(res, "NetErrout", io.Errout, labels)
+		res = c.counters.add(res, "NetDropin", io.Dropin, labels)
+		res = c.counters.add(res, "NetDropout", io.Dropout, labels)
+	}
+
+	return res, nil
+}
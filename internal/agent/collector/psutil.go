@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/shirou/gopsutil/mem"
+)
+
+// PSUtilName - имя коллектора метрик gopsutil.
+const PSUtilName = "psutil"
+
+// PSUtil - коллектор метрик, относящихся к PSUtil.
+type PSUtil struct {
+	cpu *cpuUsage
+}
+
+// NewPSUtil создаёт новый коллектор метрик gopsutil.
+func NewPSUtil() *PSUtil {
+	return &PSUtil{cpu: &cpuUsage{}}
+}
+
+// Collect выполняет сбор метрик gopsutil.
+// Утилизация CPU вычисляется относительно предыдущего вызова, поэтому при первом вызове не публикуется.
+func (c *PSUtil) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	res := make([]metric.Metrics, 0)
+
+	vmStat, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res = append(
+		res,
+		newGauge("TotalMemory", float64(vmStat.Total), nil),
+		newGauge("FreeMemory", float64(vmStat.Free), nil),
+	)
+
+	cpuStat, err := cpu.TimesWithContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.cpu.collect(res, cpuStat), nil
+}
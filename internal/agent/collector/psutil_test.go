@@ -0,0 +1,96 @@
+package collector
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/cpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPSUtilCollect(t *testing.T) {
+	keys := []string{"TotalMemory", "FreeMemory"}
+
+	mtrcs, err := NewPSUtil().Collect(context.Background())
+	require.NoError(t, err)
+
+	// Утилизация CPU при первом вызове не вычисляется
+	assert.ElementsMatch(t, keys, metricIDs(mtrcs))
+}
+
+func TestCPUUsageCollect(t *testing.T) {
+	usage := &cpuUsage{}
+
+	prev := []cpu.TimesStat{
+		{CPU: "cpu0", User: 10, System: 5, Idle: 80, Iowait: 5},
+		{CPU: "cpu1", User: 20, System: 10, Idle: 70},
+	}
+	cur := []cpu.TimesStat{
+		{CPU: "cpu0", User: 15, System: 10, Idle: 150, Iowait: 25},
+		{CPU: "cpu1", User: 80, System: 30, Idle: 90},
+	}
+
+	assert.Empty(t, usage.collect(nil, prev), "First sample must only be stored")
+
+	mtrcs := usage.collect(nil, cur)
+
+	values := make(map[string]float64, len(mtrcs))
+	for _, mtrc := range mtrcs {
+		values[mtrc.ID+mtrc.Labels.String()] = *mtrc.Value
+	}
+
+	expected := map[string]float64{
+		"CPUutilization0":        10,
+		`CPUUser{cpu="0"}`:       5,
+		`CPUSystem{cpu="0"}`:     5,
+		`CPUIowait{cpu="0"}`:     20,
+		`CPUIdle{cpu="0"}`:       70,
+		"CPUutilization1":        80,
+		`CPUUser{cpu="1"}`:       60,
+		`CPUSystem{cpu="1"}`:     20,
+		`CPUIowait{cpu="1"}`:     0,
+		`CPUIdle{cpu="1"}`:       20,
+		"CPUutilizationTotal":    45,
+		`CPUUser{cpu="total"}`:   32.5,
+		`CPUSystem{cpu="total"}`: 12.5,
+		`CPUIowait{cpu="total"}`: 10,
+		`CPUIdle{cpu="total"}`:   45,
+	}
+
+	assert.Equal(t, expected, values)
+
+	// Количество ядер изменилось: снимок только запоминается
+	assert.Empty(t, usage.collect(nil, cur[:1]))
+	// Время не изменилось: утилизацию вычислить невозможно
+	assert.Empty(t, usage.collect(nil, cur[:1]))
+}
+
+func TestCPUTimesCollect(t *testing.T) {
+	c := NewCPUTimes()
+
+	mtrcs, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, mtrcs, "First poll must only set counters baseline")
+
+	mtrcs, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	// NumCPU используется для проверки того, что в результате находится по 4 счётчика на ядро
+	assert.Len(t, mtrcs, 4*runtime.NumCPU())
+
+	for _, mtrc := range mtrcs {
+		assert.Equal(t, metric.CounterMetric, mtrc.MType)
+		assert.Contains(t, mtrc.Labels, "cpu")
+	}
+}
+
+func BenchmarkPSUtilCollect(b *testing.B) {
+	ctx := context.Background()
+	c := NewPSUtil()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = c.Collect(ctx)
+	}
+}
@@ -0,0 +1,69 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+// RuntimeName - имя коллектора метрик runtime.
+const RuntimeName = "runtime"
+
+// Runtime - коллектор метрик, относящихся к runtime.
+type Runtime struct {
+	rnd *rand.Rand
+}
+
+// NewRuntime создаёт новый коллектор метрик runtime.
+// Если генератор rnd не передан, создаётся генератор, инициализированный текущим временем.
+func NewRuntime(rnd *rand.Rand) *Runtime {
+	if rnd == nil {
+		rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	return &Runtime{rnd: rnd}
+}
+
+// Collect выполняет сбор метрик runtime.
+func (c *Runtime) Collect(_ context.Context) ([]metric.Metrics, error) {
+	buf := make(map[string]interface{})
+
+	buf["RandomValue"] = float64(c.rnd.Int())
+
+	rtm := &runtime.MemStats{}
+
+	runtime.ReadMemStats(rtm)
+
+	buf["Alloc"] = float64(rtm.Alloc)
+	buf["BuckHashSys"] = float64(rtm.BuckHashSys)
+	buf["Frees"] = float64(rtm.Frees)
+	buf["GCCPUFraction"] = rtm.GCCPUFraction
+	buf["GCSys"] = float64(rtm.GCSys)
+	buf["HeapAlloc"] = float64(rtm.HeapAlloc)
+	buf["HeapIdle"] = float64(rtm.HeapIdle)
+	buf["HeapInuse"] = float64(rtm.HeapInuse)
+	buf["HeapObjects"] = float64(rtm.HeapObjects)
+	buf["HeapReleased"] = float64(rtm.HeapReleased)
+	buf["HeapSys"] = float64(rtm.HeapSys)
+	buf["LastGC"] = float64(rtm.LastGC)
+	buf["Lookups"] = float64(rtm.Lookups)
+	buf["MCacheInuse"] = float64(rtm.MCacheInuse)
+	buf["MCacheSys"] = float64(rtm.MCacheSys)
+	buf["MSpanInuse"] = float64(rtm.MSpanInuse)
+	buf["MSpanSys"] = float64(rtm.MSpanSys)
+	buf["Mallocs"] = float64(rtm.Mallocs)
+	buf["NextGC"] = float64(rtm.NextGC)
+	buf["NumForcedGC"] = float64(rtm.NumForcedGC)
+	buf["NumGC"] = float64(rtm.NumGC)
+	buf["OtherSys"] = float64(rtm.OtherSys)
+	buf["PauseTotalNs"] = float64(rtm.PauseTotalNs)
+	buf["StackInuse"] = float64(rtm.StackInuse)
+	buf["StackSys"] = float64(rtm.StackSys)
+	buf["Sys"] = float64(rtm.Sys)
+	buf["TotalAlloc"] = float64(rtm.TotalAlloc)
+
+	return newMetrics(buf)
+}
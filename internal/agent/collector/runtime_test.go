@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeCollect(t *testing.T) {
+	tests := []struct {
+		name string
+		rnd  *rand.Rand
+		keys []string
+	}{
+		{
+			name: "Correct test",
+			rnd:  rand.New(rand.NewSource(1)),
+			keys: []string{
+				"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys",
+				"HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups",
+				"MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
+				"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue",
+			},
+		},
+		{
+			name: "Nil random generator",
+			keys: []string{
+				"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys",
+				"HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups",
+				"MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
+				"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mtrcs, err := NewRuntime(test.rnd).Collect(context.Background())
+			require.NoError(t, err)
+
+			keys := make([]string, 0, len(test.keys))
+			for _, mtrc := range mtrcs {
+				keys = append(keys, mtrc.ID)
+			}
+
+			assert.ElementsMatch(t, keys, test.keys)
+		})
+	}
+}
+
+func BenchmarkRuntimeCollect(b *testing.B) {
+	ctx := context.Background()
+	c := NewRuntime(rand.New(rand.NewSource(100)))
+
+	for i := 0; i < b.N; i++ {
+		_, _ = c.Collect(ctx)
+	}
+}
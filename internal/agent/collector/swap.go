@@ -0,0 +1,41 @@
+package collector
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/shirou/gopsutil/mem"
+)
+
+// SwapName - имя коллектора метрик файла подкачки.
+const SwapName = "swap"
+
+// Swap - коллектор метрик файла подкачки.
+type Swap struct {
+	counters *counterTracker
+}
+
+// NewSwap создаёт новый коллектор метрик файла подкачки.
+func NewSwap() *Swap {
+	return &Swap{counters: newCounterTracker()}
+}
+
+// Collect выполняет сбор метрик файла подкачки.
+func (c *Swap) Collect(ctx context.Context) ([]metric.Metrics, error) {
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []metric.Metrics{
+		newGauge("SwapTotal", float64(swap.Total), nil),
+		newGauge("SwapUsed", float64(swap.Used), nil),
+		newGauge("SwapFree", float64(swap.Free), nil),
+		newGauge("SwapUsedPercent", swap.UsedPercent, nil),
+	}
+
+	res = c.counters.add(res, "SwapIn", swap.Sin, nil)
+	res = c.counters.add(res, "SwapOut", swap.Sout, nil)
+
+	return res, nil
+}
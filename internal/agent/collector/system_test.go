@@ -0,0 +1,143 @@
+package collector
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metricIDs возвращает имена метрик из набора.
+func metricIDs(mtrcs []metric.Metrics) []string {
+	ids := make([]string, 0, len(mtrcs))
+	for _, mtrc := range mtrcs {
+		ids = append(ids, mtrc.ID)
+	}
+
+	return ids
+}
+
+func TestLoadCollect(t *testing.T) {
+	mtrcs, err := NewLoad().Collect(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"Load1", "Load5", "Load15"}, metricIDs(mtrcs))
+}
+
+func TestSwapCollect(t *testing.T) {
+	c := NewSwap()
+
+	mtrcs, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"SwapTotal", "SwapUsed", "SwapFree", "SwapUsedPercent"}, metricIDs(mtrcs))
+
+	mtrcs, err = c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Subset(t, metricIDs(mtrcs), []string{"SwapIn", "SwapOut"})
+}
+
+func TestNetCollect(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("loopback interface name is platform specific")
+	}
+
+	c := NewNet()
+
+	mtrcs, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, mtrcs, "First poll must only set counters baseline")
+
+	mtrcs, err = c.Collect(context.Background())
+	require.NoError(t, err)
+
+	found := false
+
+	for _, mtrc := range mtrcs {
+		assert.Equal(t, metric.CounterMetric, mtrc.MType)
+		assert.Contains(t, mtrc.Labels, "interface")
+
+		if mtrc.ID == "NetBytesSent" && mtrc.Labels["interface"] == "lo" {
+			found = true
+		}
+	}
+
+	assert.True(t, found, "Loopback interface metrics must be reported")
+}
+
+func TestDiskCollect(t *testing.T) {
+	c := NewDisk()
+
+	_, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	mtrcs, err := c.Collect(context.Background())
+	require.NoError(t, err)
+
+	for _, mtrc := range mtrcs {
+		switch mtrc.MType {
+		case metric.GaugeMetric:
+			assert.Contains(t, mtrc.Labels, "mountpoint")
+		case metric.CounterMetric:
+			assert.Contains(t, mtrc.Labels, "device")
+		default:
+			assert.Fail(t, "unexpected metric type", mtrc.MType)
+		}
+	}
+}
+
+func TestFDCollect(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]float64
+		wantErr  bool
+	}{
+		{
+			name:     "Correct file",
+			content:  "1024\t24\t65536\n",
+			expected: map[string]float64{"OpenFileDescriptors": 1000, "MaxFileDescriptors": 65536},
+		},
+		{
+			name:    "Wrong fields count",
+			content: "1024 24\n",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong value",
+			content: "1024 a 65536\n",
+			wantErr: true,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i)))
+			require.NoError(t, os.WriteFile(path, []byte(test.content), 0o600))
+
+			mtrcs, err := NewFD(path).Collect(context.Background())
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongFileNr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			values := make(map[string]float64, len(mtrcs))
+			for _, mtrc := range mtrcs {
+				values[mtrc.ID] = *mtrc.Value
+			}
+
+			assert.Equal(t, test.expected, values)
+		})
+	}
+
+	_, err := NewFD(filepath.Join(dir, "missing")).Collect(context.Background())
+	assert.Error(t, err)
+}
@@ -24,10 +24,45 @@ const (
 	key            = ""               // Значения ключа аутентификации по умолчанию
 	rateLimit      = 3                // Количество одновременно исходящих запросов на сервер по умолчанию
 	cryptoKey      = ""               // Путь до файла с публичным ключом
+	labels         = ""               // Набор меток, добавляемых к метрикам, по умолчанию
+	collectors     = "runtime,psutil" // Набор коллекторов метрик по умолчанию
+	bufferDir      = ""               // Каталог дискового буфера метрик по умолчанию
+	bufferSize     = 64 << 20         // Максимальный размер дискового буфера метрик в байтах по умолчанию
+	servers        = ""               // Список серверов-хранилищ по умолчанию
+	serversMode    = ServersFailover  // Режим работы с несколькими серверами по умолчанию
+	serversBackoff = 30 * time.Second // Время исключения недоступного сервера из ротации по умолчанию
+
+	gaugeStats     = "last"            // Набор статистик значений gauge за интервал отправки по умолчанию
+	gaugeStatsMode = GaugeStatsMetrics // Режим отправки статистик значений gauge по умолчанию
 
 	httpTimeout = 5 * time.Second // Таймаут соединения с сервером по умолчанию
 	batchSize   = 5               // Количество посылаемых за раз метрик по умолчанию
-	retries     = "1,3,5"         // Интервалы попыток соединения с сервером через запятую по умолчанию
+
+	retryMaxAttempts  = 4                         // Максимальное количество попыток отправки по умолчанию
+	retryBaseDelay    = time.Second               // Задержка перед первой повторной попыткой по умолчанию
+	retryMaxDelay     = 10 * time.Second          // Максимальная задержка между попытками по умолчанию
+	retryJitter       = 0.2                       // Допустимое случайное отклонение задержки (доля) по умолчанию
+	retryHTTPStatuses = "408,429,500,502,503,504" // Коды ответа HTTP, при которых попытка повторяется, по умолчанию
+	// Коды статуса gRPC, при которых попытка повторяется, по умолчанию
+	retryGRPCCodes = "Unavailable,ResourceExhausted,DeadlineExceeded,Aborted"
+
+	bufferSegmentSize = 1 << 20 // Максимальный размер сегмента дискового буфера метрик в байтах по умолчанию
+)
+
+const (
+	// ServersFailover - метрики отправляются на первый доступный сервер из списка.
+	ServersFailover = "failover"
+	// ServersFanout - метрики отправляются на все серверы из списка.
+	ServersFanout = "fanout"
+)
+
+const (
+	// GaugeStatsMetrics - каждая статистика значений gauge отправляется отдельной метрикой
+	// с суффиксом статистики в имени (HeapAllocMax, HeapAllocP99). Статистика last отправляется без суффикса.
+	GaugeStatsMetrics = "metrics"
+	// GaugeStatsSummary - статистики значений gauge отправляются под именем исходной метрики
+	// и различаются меткой stat.
+	GaugeStatsSummary = "summary"
 )
 
 // ErrPublicKeyNotFound возвращается, если не был найден публичный ключ шифрования.
@@ -49,13 +84,49 @@ type Config struct {
 	RateLimit uint `env:"RATE_LIMIT" json:"-"`
 	// CryptoKey - Путь до файла с публичным ключом
 	CryptoKey string `env:"CRYPTO_KEY" json:"crypto_key"`
+	// Labels - Набор меток, добавляемых к метрикам, в формате "name1=value1,name2=value2"
+	Labels string `env:"LABELS" json:"labels"`
+	// Collectors - Набор коллекторов метрик в формате "name1[:interval1],name2[:interval2]".
+	// Для коллекторов без интервала используется PollInterval
+	Collectors string `env:"COLLECTORS" json:"collectors"`
+	// Servers - Список серверов-хранилищ через запятую в формате "http://host:port,grpc://host:port".
+	// Адреса без схемы считаются адресами HTTP-серверов. Если указан, HTTPAddress и GRPCAddress не используются
+	Servers string `env:"SERVERS" json:"servers"`
+	// ServersMode - Режим работы с несколькими серверами: ServersFailover или ServersFanout
+	ServersMode string `env:"SERVERS_MODE" json:"servers_mode"`
+	// ServersBackoff - Время, на которое сервер исключается из ротации после неудачной отправки
+	// в режиме ServersFailover
+	ServersBackoff utils.Duration `env:"SERVERS_BACKOFF" json:"servers_backoff"`
+	// GaugeStats - Набор статистик значений gauge за интервал отправки через запятую:
+	// last, min, max, mean и перцентили вида p50, p99.9
+	GaugeStats string `env:"GAUGE_STATS" json:"gauge_stats"`
+	// GaugeStatsMode - Режим отправки статистик значений gauge: GaugeStatsMetrics или GaugeStatsSummary
+	GaugeStatsMode string `env:"GAUGE_STATS_MODE" json:"gauge_stats_mode"`
+	// BufferDir - Каталог дискового буфера для метрик, которые не удалось отправить.
+	// Если не указан, буфер не используется
+	BufferDir string `env:"BUFFER_DIR" json:"buffer_dir"`
+	// BufferSize - Максимальный размер дискового буфера метрик в байтах
+	BufferSize int64 `env:"BUFFER_SIZE" json:"buffer_size"`
+	// RetryMaxAttempts - Максимальное количество попыток отправки набора метрик
+	RetryMaxAttempts uint `env:"RETRY_MAX_ATTEMPTS" json:"retry_max_attempts"`
+	// RetryBaseDelay - Задержка перед первой повторной попыткой. Каждая следующая задержка удваивается
+	RetryBaseDelay utils.Duration `env:"RETRY_BASE_DELAY" json:"retry_base_delay"`
+	// RetryMaxDelay - Максимальная задержка между попытками
+	RetryMaxDelay utils.Duration `env:"RETRY_MAX_DELAY" json:"retry_max_delay"`
+	// RetryJitter - Допустимое случайное отклонение задержки между попытками в долях от 0 до 1
+	RetryJitter float64 `env:"RETRY_JITTER" json:"retry_jitter"`
+	// RetryHTTPStatuses - Коды ответа HTTP-сервера через запятую, при которых попытка отправки повторяется
+	RetryHTTPStatuses string `env:"RETRY_HTTP_STATUSES" json:"retry_http_statuses"`
+	// RetryGRPCCodes - Коды статуса gRPC через запятую (например, "Unavailable"),
+	// при которых попытка отправки повторяется
+	RetryGRPCCodes string `env:"RETRY_GRPC_CODES" json:"retry_grpc_codes"`
 
 	// ServerTimeout - Таймаут соединения с сервером
 	ServerTimeout utils.Duration `json:"-"`
 	// BatchSize - Количество посылаемых за раз метрик
 	BatchSize uint `json:"-"`
-	// Retries - Интервалы попыток соединения с сервером через запятую
-	Retries string `json:"-"`
+	// BufferSegmentSize - Максимальный размер сегмента дискового буфера метрик в байтах
+	BufferSegmentSize int64 `json:"-"`
 	// PublicKey - Значение публичного ключа
 	PublicKey *rsa.PublicKey `json:"-"`
 }
@@ -76,10 +147,25 @@ func NewConfig() (*Config, error) {
 	flag.StringVar(&cfg.Key, "k", key, "Server key")
 	flag.UintVar(&cfg.RateLimit, "l", rateLimit, "Number of concurrent requests")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", cryptoKey, "Path to file with public cryptographic key")
+	flag.StringVar(&cfg.Labels, "labels", labels, "Metric labels (name1=value1,name2=value2)")
+	flag.StringVar(&cfg.Collectors, "collectors", collectors, "Metric collectors (name1[:interval1],name2[:interval2])")
+	flag.StringVar(&cfg.Servers, "servers", servers, "Server endpoints (http://host:port,grpc://host:port)")
+	flag.StringVar(&cfg.ServersMode, "servers-mode", serversMode, "Multiple servers mode (failover, fanout)")
+	flag.DurationVar(&cfg.ServersBackoff.Duration, "servers-backoff", serversBackoff, "Failed server backoff time")
+	flag.StringVar(&cfg.GaugeStats, "gauge-stats", gaugeStats, "Gauge statistics (last,min,max,mean,p99)")
+	flag.StringVar(&cfg.GaugeStatsMode, "gauge-stats-mode", gaugeStatsMode, "Gauge statistics mode (metrics, summary)")
+	flag.StringVar(&cfg.BufferDir, "buffer-dir", bufferDir, "Directory of the on-disk buffer for unsent metrics")
+	flag.Int64Var(&cfg.BufferSize, "buffer-size", bufferSize, "Maximum on-disk buffer size in bytes")
 
 	flag.DurationVar(&cfg.ServerTimeout.Duration, "timeout", httpTimeout, "Server connection timeout")
 	flag.UintVar(&cfg.BatchSize, "batch", batchSize, "Metrics batch size")
-	flag.StringVar(&cfg.Retries, "retries", retries, "Server connection retry intervals")
+	flag.UintVar(&cfg.RetryMaxAttempts, "retry-attempts", retryMaxAttempts, "Maximum number of send attempts")
+	flag.DurationVar(&cfg.RetryBaseDelay.Duration, "retry-base-delay", retryBaseDelay, "Delay before the first retry")
+	flag.DurationVar(&cfg.RetryMaxDelay.Duration, "retry-max-delay", retryMaxDelay, "Maximum delay between retries")
+	flag.Float64Var(&cfg.RetryJitter, "retry-jitter", retryJitter, "Retry delay jitter fraction (0..1)")
+	flag.StringVar(&cfg.RetryHTTPStatuses, "retry-http-statuses", retryHTTPStatuses, "Retryable HTTP statuses")
+	flag.StringVar(&cfg.RetryGRPCCodes, "retry-grpc-codes", retryGRPCCodes, "Retryable gRPC codes")
+	flag.Int64Var(&cfg.BufferSegmentSize, "buffer-segment", bufferSegmentSize, "On-disk buffer segment size in bytes")
 
 	flag.Parse()
 
@@ -150,5 +236,71 @@ func (cfg *Config) parseFile(path string) error {
 		cfg.CryptoKey = fileConf.CryptoKey
 	}
 
+	if !utils.IsFlagPassed("labels") {
+		cfg.Labels = fileConf.Labels
+	}
+
+	if !utils.IsFlagPassed("collectors") {
+		cfg.Collectors = fileConf.Collectors
+	}
+
+	if !utils.IsFlagPassed("servers") {
+		cfg.Servers = fileConf.Servers
+	}
+
+	if !utils.IsFlagPassed("servers-mode") && fileConf.ServersMode != "" {
+		cfg.ServersMode = fileConf.ServersMode
+	}
+
+	if !utils.IsFlagPassed("servers-backoff") && fileConf.ServersBackoff.Duration != 0 {
+		cfg.ServersBackoff = fileConf.ServersBackoff
+	}
+
+	if !utils.IsFlagPassed("gauge-stats") && fileConf.GaugeStats != "" {
+		cfg.GaugeStats = fileConf.GaugeStats
+	}
+
+	if !utils.IsFlagPassed("gauge-stats-mode") && fileConf.GaugeStatsMode != "" {
+		cfg.GaugeStatsMode = fileConf.GaugeStatsMode
+	}
+
+	if !utils.IsFlagPassed("buffer-dir") {
+		cfg.BufferDir = fileConf.BufferDir
+	}
+
+	if !utils.IsFlagPassed("buffer-size") && fileConf.BufferSize != 0 {
+		cfg.BufferSize = fileConf.BufferSize
+	}
+
+	cfg.parseRetryPolicy(fileConf)
+
 	return nil
 }
+
+// parseRetryPolicy переносит параметры политики повторных попыток из конфигурационного файла.
+// Параметры, не указанные в файле, сохраняют значения по умолчанию.
+func (cfg *Config) parseRetryPolicy(fileConf *Config) {
+	if !utils.IsFlagPassed("retry-attempts") && fileConf.RetryMaxAttempts != 0 {
+		cfg.RetryMaxAttempts = fileConf.RetryMaxAttempts
+	}
+
+	if !utils.IsFlagPassed("retry-base-delay") && fileConf.RetryBaseDelay.Duration != 0 {
+		cfg.RetryBaseDelay = fileConf.RetryBaseDelay
+	}
+
+	if !utils.IsFlagPassed("retry-max-delay") && fileConf.RetryMaxDelay.Duration != 0 {
+		cfg.RetryMaxDelay = fileConf.RetryMaxDelay
+	}
+
+	if !utils.IsFlagPassed("retry-jitter") && fileConf.RetryJitter != 0 {
+		cfg.RetryJitter = fileConf.RetryJitter
+	}
+
+	if !utils.IsFlagPassed("retry-http-statuses") && fileConf.RetryHTTPStatuses != "" {
+		cfg.RetryHTTPStatuses = fileConf.RetryHTTPStatuses
+	}
+
+	if !utils.IsFlagPassed("retry-grpc-codes") && fileConf.RetryGRPCCodes != "" {
+		cfg.RetryGRPCCodes = fileConf.RetryGRPCCodes
+	}
+}
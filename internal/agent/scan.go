@@ -3,46 +3,125 @@ package agent
 import (
 	"context"
 	"fmt"
-	"math/rand"
-	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/KryukovO/metricscollector/internal/agent/collector"
 	"github.com/KryukovO/metricscollector/internal/metric"
-	"github.com/shirou/gopsutil/cpu"
-	"github.com/shirou/gopsutil/mem"
 )
 
+// DefaultCollectors - коллекторы, используемые, если в конфигурации агента набор коллекторов не задан.
+const DefaultCollectors = collector.RuntimeName + "," + collector.PSUtilName
+
+// scheduledCollector описывает коллектор, опрашиваемый с определённым интервалом.
+type scheduledCollector struct {
+	name      string
+	collector collector.Collector
+	interval  time.Duration
+}
+
+// parseCollectors создаёт коллекторы по их описанию в формате "name1[:interval1],name2[:interval2]".
+// Для коллекторов без указанного интервала используется defaultInterval.
+// Пустое описание соответствует DefaultCollectors.
+func parseCollectors(spec string, defaultInterval time.Duration) ([]scheduledCollector, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = DefaultCollectors
+	}
+
+	res := make([]scheduledCollector, 0)
+	seen := make(map[string]struct{})
+
+	for _, item := range strings.Split(spec, ",") {
+		name, intervalStr, hasInterval := strings.Cut(strings.TrimSpace(item), ":")
+
+		if _, ok := seen[name]; ok {
+			return nil, fmt.Errorf("%w: duplicate collector %s", collector.ErrCollectorExists, name)
+		}
+
+		seen[name] = struct{}{}
+
+		interval := defaultInterval
+
+		if hasInterval {
+			d, err := time.ParseDuration(intervalStr)
+			if err != nil {
+				return nil, fmt.Errorf("collector %s: %w", name, err)
+			}
+
+			if d <= 0 {
+				return nil, fmt.Errorf("collector %s: non-positive poll interval %s", name, d)
+			}
+
+			interval = d
+		}
+
+		factory, err := collector.Lookup(name)
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := factory()
+		if err != nil {
+			return nil, fmt.Errorf("collector %s: %w", name, err)
+		}
+
+		res = append(res, scheduledCollector{name: name, collector: c, interval: interval})
+	}
+
+	return res, nil
+}
+
+// groupCollectors группирует коллекторы по интервалам опроса.
+// Группа с интервалом pollInterval присутствует в результате всегда, даже если она пуста.
+func groupCollectors(
+	collectors []scheduledCollector, pollInterval time.Duration,
+) map[time.Duration][]collector.Collector {
+	groups := map[time.Duration][]collector.Collector{pollInterval: nil}
+
+	for _, c := range collectors {
+		groups[c.interval] = append(groups[c.interval], c.collector)
+	}
+
+	return groups
+}
+
 // ScanResult описывает результат сканирования метрик.
 type ScanResult struct {
 	mtrc metric.Metrics
 	err  error
 }
 
-// ScanMetrics выполняет сканирование метрик из различных источников.
-func ScanMetrics(ctx context.Context) chan ScanResult {
-	channels := []chan ScanResult{
-		scanRuntimeMetrics(ctx, rand.New(rand.NewSource(time.Now().UnixNano()))),
-		scanPSUtilMetrics(ctx),
-	}
-
-	outCh := make(chan ScanResult, len(channels))
+// ScanMetrics выполняет сканирование метрик из коллекторов collectors.
+func ScanMetrics(ctx context.Context, collectors []collector.Collector) chan ScanResult {
+	outCh := make(chan ScanResult, len(collectors))
 	wg := new(sync.WaitGroup)
 
-	for _, ch := range channels {
+	for _, c := range collectors {
 		wg.Add(1)
 
-		go func(inCh <-chan ScanResult) {
+		go func(c collector.Collector) {
 			defer wg.Done()
 
-			for data := range inCh {
+			mtrcs, err := c.Collect(ctx)
+			if err != nil {
+				select {
+				case <-ctx.Done():
+				case outCh <- ScanResult{err: err}:
+				}
+
+				return
+			}
+
+			for _, mtrc := range mtrcs {
 				select {
 				case <-ctx.Done():
 					return
-				case outCh <- data:
+				case outCh <- ScanResult{mtrc: mtrc}:
 				}
 			}
-		}(ch)
+		}(c)
 	}
 
 	go func() {
@@ -54,110 +133,53 @@ func ScanMetrics(ctx context.Context) chan ScanResult {
 	return outCh
 }
 
-// scanRuntimeMetrics выполняет сканирование метрик, относящихся к runtime.
-func scanRuntimeMetrics(ctx context.Context, rnd *rand.Rand) chan ScanResult {
-	outCh := make(chan ScanResult, 1)
-
-	go func() {
-		defer close(outCh)
+// accumulateCounters добавляет приращения счётчиков deltas к накопленным значениям counters.
+func accumulateCounters(counters map[string]metric.Metrics, deltas []metric.Metrics) {
+	for _, mtrc := range deltas {
+		key := mtrc.ID + mtrc.Labels.String()
 
-		buf := make(map[string]interface{})
+		acc, ok := counters[key]
+		if !ok {
+			delta := *mtrc.Delta
+			mtrc.Delta = &delta
+			counters[key] = mtrc
 
-		rndVal := float64(rand.Int())
-		if rnd != nil {
-			rndVal = float64(rnd.Int())
+			continue
 		}
 
-		buf["RandomValue"] = rndVal
-
-		rtm := &runtime.MemStats{}
-
-		runtime.ReadMemStats(rtm)
-
-		buf["Alloc"] = float64(rtm.Alloc)
-		buf["BuckHashSys"] = float64(rtm.BuckHashSys)
-		buf["Frees"] = float64(rtm.Frees)
-		buf["GCCPUFraction"] = rtm.GCCPUFraction
-		buf["GCSys"] = float64(rtm.GCSys)
-		buf["HeapAlloc"] = float64(rtm.HeapAlloc)
-		buf["HeapIdle"] = float64(rtm.HeapIdle)
-		buf["HeapInuse"] = float64(rtm.HeapInuse)
-		buf["HeapObjects"] = float64(rtm.HeapObjects)
-		buf["HeapReleased"] = float64(rtm.HeapReleased)
-		buf["HeapSys"] = float64(rtm.HeapSys)
-		buf["LastGC"] = float64(rtm.LastGC)
-		buf["Lookups"] = float64(rtm.Lookups)
-		buf["MCacheInuse"] = float64(rtm.MCacheInuse)
-		buf["MCacheSys"] = float64(rtm.MCacheSys)
-		buf["MSpanInuse"] = float64(rtm.MSpanInuse)
-		buf["MSpanSys"] = float64(rtm.MSpanSys)
-		buf["Mallocs"] = float64(rtm.Mallocs)
-		buf["NextGC"] = float64(rtm.NextGC)
-		buf["NumForcedGC"] = float64(rtm.NumForcedGC)
-		buf["NumGC"] = float64(rtm.NumGC)
-		buf["OtherSys"] = float64(rtm.OtherSys)
-		buf["PauseTotalNs"] = float64(rtm.PauseTotalNs)
-		buf["StackInuse"] = float64(rtm.StackInuse)
-		buf["StackSys"] = float64(rtm.StackSys)
-		buf["Sys"] = float64(rtm.Sys)
-		buf["TotalAlloc"] = float64(rtm.TotalAlloc)
-
-		for mName, mVal := range buf {
-			mtrc, err := metric.NewMetrics(mName, "", mVal)
-			data := ScanResult{mtrc: mtrc, err: err}
-
-			select {
-			case <-ctx.Done():
-				return
-			case outCh <- data:
-			}
-		}
-	}()
-
-	return outCh
+		*acc.Delta += *mtrc.Delta
+	}
 }
 
-// scanPSUtilMetrics выполняет сканирование метрик, относящихся к PSUtil.
-func scanPSUtilMetrics(ctx context.Context) chan ScanResult {
-	outCh := make(chan ScanResult, 1)
-
-	go func() {
-		defer close(outCh)
-
-		buf := make(map[string]interface{})
-
-		vmStat, err := mem.VirtualMemoryWithContext(ctx)
-		if err != nil {
-			outCh <- ScanResult{err: err}
-
-			return
-		}
+// mergeResults объединяет последние результаты сканирования всех групп коллекторов
+// и накопленные приращения счётчиков.
+func mergeResults(results map[time.Duration][]metric.Metrics, counters map[string]metric.Metrics) []metric.Metrics {
+	intervals := make([]time.Duration, 0, len(results))
+	for interval := range results {
+		intervals = append(intervals, interval)
+	}
 
-		buf["TotalMemory"] = float64(vmStat.Total)
-		buf["FreeMemory"] = float64(vmStat.Free)
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
 
-		cpuStat, err := cpu.TimesWithContext(ctx, true)
-		if err != nil {
-			outCh <- ScanResult{err: err}
+	res := make([]metric.Metrics, 0)
+	for _, interval := range intervals {
+		res = append(res, results[interval]...)
+	}
 
-			return
-		}
+	keys := make([]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
 
-		for i, ts := range cpuStat {
-			buf[fmt.Sprintf("CPUutilization%d", i)] = ts.Idle
-		}
+	sort.Strings(keys)
 
-		for mName, mVal := range buf {
-			mtrc, err := metric.NewMetrics(mName, "", mVal)
-			data := ScanResult{mtrc: mtrc, err: err}
+	for _, key := range keys {
+		mtrc := counters[key]
+		delta := *mtrc.Delta
+		mtrc.Delta = &delta
 
-			select {
-			case <-ctx.Done():
-				return
-			case outCh <- data:
-			}
-		}
-	}()
+		res = append(res, mtrc)
+	}
 
-	return outCh
+	return res
 }
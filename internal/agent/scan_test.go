@@ -2,13 +2,24 @@ package agent
 
 import (
 	"context"
-	"math/rand"
-	"runtime"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/KryukovO/metricscollector/internal/agent/collector"
+	"github.com/KryukovO/metricscollector/internal/metric"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+var errCollect = errors.New("collect error")
+
+type failingCollector struct{}
+
+func (c failingCollector) Collect(_ context.Context) ([]metric.Metrics, error) {
+	return nil, errCollect
+}
+
 func TestScanMetrics(t *testing.T) {
 	keys := []string{
 		"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys",
@@ -18,112 +29,146 @@ func TestScanMetrics(t *testing.T) {
 		"TotalMemory", "FreeMemory",
 	}
 
-	metricCh := ScanMetrics(context.Background())
+	metricCh := ScanMetrics(
+		context.Background(),
+		[]collector.Collector{collector.NewRuntime(nil), collector.NewPSUtil(), failingCollector{}},
+	)
 
 	result := make([]string, 0, len(keys))
+	errs := 0
 
 	for data := range metricCh {
-		assert.NoError(t, data.err)
+		if data.err != nil {
+			assert.ErrorIs(t, data.err, errCollect)
+
+			errs++
+
+			continue
+		}
 
 		result = append(result, data.mtrc.ID)
 	}
 
-	assert.Subset(t, result, keys)
-	// NumCPU используется для проверки того, что в результате находится N метрик CPUutilization
-	assert.Len(t, result, len(keys)+runtime.NumCPU())
+	assert.Equal(t, 1, errs)
+	// Утилизация CPU при первом сканировании не вычисляется
+	assert.ElementsMatch(t, keys, result)
 }
 
-func TestScanRuntimeMetrics(t *testing.T) {
+func TestParseCollectors(t *testing.T) {
 	tests := []struct {
-		name string
-		rnd  *rand.Rand
-		keys []string
+		name      string
+		spec      string
+		names     []string
+		intervals []time.Duration
+		wantErr   error
 	}{
 		{
-			name: "Correct test",
-			rnd:  rand.New(rand.NewSource(1)),
-			keys: []string{
-				"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys",
-				"HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups",
-				"MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
-				"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue",
-			},
+			name:      "Default collectors",
+			spec:      "",
+			names:     []string{collector.RuntimeName, collector.PSUtilName},
+			intervals: []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+		{
+			name:      "Custom interval",
+			spec:      "psutil:10s",
+			names:     []string{collector.PSUtilName},
+			intervals: []time.Duration{10 * time.Second},
+		},
+		{
+			name:    "Unknown collector",
+			spec:    "runtime,unknown",
+			wantErr: collector.ErrUnknownCollector,
 		},
 		{
-			name: "Nil random generator",
-			keys: []string{
-				"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys",
-				"HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "LastGC", "Lookups",
-				"MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC",
-				"OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue",
-			},
+			name:    "Duplicate collector",
+			spec:    "runtime,runtime:5s",
+			wantErr: collector.ErrCollectorExists,
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			metricCh := scanRuntimeMetrics(context.Background(), test.rnd)
+			collectors, err := parseCollectors(test.spec, 2*time.Second)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
 
-			keys := make([]string, 0, len(test.keys))
-			for data := range metricCh {
-				assert.NoError(t, data.err)
-
-				keys = append(keys, data.mtrc.ID)
+				return
 			}
 
-			assert.ElementsMatch(t, keys, test.keys)
-		})
-	}
-}
-
-func TestScanPSUtilMetrics(t *testing.T) {
-	tests := []struct {
-		name string
-		keys []string
-	}{
-		{
-			name: "Correct test",
-			keys: []string{"TotalMemory", "FreeMemory"},
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			metricCh := scanPSUtilMetrics(context.Background())
+			require.NoError(t, err)
 
-			keys := make([]string, 0, len(test.keys))
-			for data := range metricCh {
-				assert.NoError(t, data.err)
+			names := make([]string, 0, len(collectors))
+			intervals := make([]time.Duration, 0, len(collectors))
 
-				keys = append(keys, data.mtrc.ID)
+			for _, c := range collectors {
+				names = append(names, c.name)
+				intervals = append(intervals, c.interval)
 			}
 
-			assert.Subset(t, keys, test.keys)
-			// NumCPU используется для проверки того, что в результате находится N метрик CPUutilization
-			assert.Len(t, keys, len(test.keys)+runtime.NumCPU())
+			assert.Equal(t, test.names, names)
+			assert.Equal(t, test.intervals, intervals)
 		})
 	}
+
+	_, err := parseCollectors("runtime:abc", 2*time.Second)
+	assert.Error(t, err)
 }
 
 func BenchmarkScan(b *testing.B) {
 	ctx := context.Background()
-	rnd := rand.New(rand.NewSource(100))
+	collectors := []collector.Collector{collector.NewRuntime(nil), collector.NewPSUtil()}
 
-	b.Run("full", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			ScanMetrics(ctx)
+	for i := 0; i < b.N; i++ {
+		for data := range ScanMetrics(ctx, collectors) {
+			_ = data
 		}
-	})
+	}
+}
 
-	b.Run("runtime", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			scanRuntimeMetrics(ctx, rnd)
-		}
-	})
+func TestAccumulateCounters(t *testing.T) {
+	var (
+		first  int64 = 10
+		second int64 = 5
+		other  int64 = 7
+	)
 
-	b.Run("psutil", func(b *testing.B) {
-		for i := 0; i < b.N; i++ {
-			ScanMetrics(ctx)
-		}
+	counters := make(map[string]metric.Metrics)
+
+	accumulateCounters(counters, []metric.Metrics{
+		{ID: "NetBytesSent", MType: metric.CounterMetric, Delta: &first, Labels: metric.Labels{"interface": "lo"}},
+		{ID: "NetBytesSent", MType: metric.CounterMetric, Delta: &other, Labels: metric.Labels{"interface": "eth0"}},
 	})
+	accumulateCounters(counters, []metric.Metrics{
+		{ID: "NetBytesSent", MType: metric.CounterMetric, Delta: &second, Labels: metric.Labels{"interface": "lo"}},
+	})
+
+	assert.EqualValues(t, 10, first, "Source metrics must not be modified")
+
+	gauge := 1.5
+	res := mergeResults(
+		map[time.Duration][]metric.Metrics{time.Second: {{ID: "Load1", MType: metric.GaugeMetric, Value: &gauge}}},
+		counters,
+	)
+
+	require.Len(t, res, 3)
+	assert.Equal(t, "Load1", res[0].ID)
+
+	deltas := make(map[string]int64)
+	for _, mtrc := range res[1:] {
+		deltas[mtrc.Labels["interface"]] = *mtrc.Delta
+	}
+
+	assert.Equal(t, map[string]int64{"lo": 15, "eth0": 7}, deltas)
+}
+
+func TestMergeLabels(t *testing.T) {
+	agentLabels := metric.Labels{"host": "a"}
+
+	assert.Equal(t, agentLabels, mergeLabels(agentLabels, nil))
+	assert.Equal(
+		t,
+		metric.Labels{"host": "a", "mountpoint": "/"},
+		mergeLabels(agentLabels, metric.Labels{"mountpoint": "/"}),
+	)
+	assert.Equal(t, metric.Labels{"host": "a"}, agentLabels, "Agent labels must not be modified")
 }
@@ -0,0 +1,102 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// grpcScheme - схема адреса gRPC-сервера в списке серверов.
+const grpcScheme = "grpc://"
+
+// ErrNoServers возвращается, если список серверов пуст.
+var ErrNoServers = errors.New("no server endpoints")
+
+// unsafePathChars - символы адреса сервера, недопустимые в имени каталога.
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// endpoint описывает сервер-хранилище, используемый при работе с несколькими серверами.
+type endpoint interface {
+	Sender
+	// deliver выполняет отправку набора метрик с повторными попытками.
+	deliver(ctx context.Context, batch metric.Batch) error
+}
+
+// namedEndpoint - сервер-хранилище с адресом, под которым он указан в конфигурации.
+type namedEndpoint struct {
+	endpoint
+
+	name string
+}
+
+// newEndpoints создаёт отправителей для каждого сервера из списка cfg.Servers.
+// Если buffered, каждому серверу выделяется собственный каталог дискового буфера внутри cfg.BufferDir,
+// иначе дисковый буфер отправителями не используется.
+func newEndpoints(cfg *config.Config, buffered bool, l *log.Logger) ([]namedEndpoint, error) {
+	addresses := splitList(cfg.Servers)
+	if len(addresses) == 0 {
+		return nil, ErrNoServers
+	}
+
+	endpoints := make([]namedEndpoint, 0, len(addresses))
+
+	closeAll := func() {
+		for _, ep := range endpoints {
+			_ = ep.Close()
+		}
+	}
+
+	for _, address := range addresses {
+		epCfg := *cfg
+		epCfg.HTTPAddress, epCfg.GRPCAddress = "", ""
+		epCfg.BufferDir = ""
+
+		if buffered && cfg.BufferDir != "" {
+			epCfg.BufferDir = filepath.Join(cfg.BufferDir, unsafePathChars.ReplaceAllString(address, "_"))
+		}
+
+		var (
+			ep  endpoint
+			err error
+		)
+
+		if strings.HasPrefix(address, grpcScheme) {
+			epCfg.GRPCAddress = strings.TrimPrefix(address, grpcScheme)
+			ep, err = NewGRPCSender(&epCfg, l)
+		} else {
+			epCfg.HTTPAddress = address
+			ep, err = NewHTTPSender(&epCfg, l)
+		}
+
+		if err != nil {
+			closeAll()
+
+			return nil, fmt.Errorf("server %s: %w", address, err)
+		}
+
+		endpoints = append(endpoints, namedEndpoint{endpoint: ep, name: address})
+	}
+
+	return endpoints, nil
+}
+
+// closeEndpoints закрывает отправителей всех серверов.
+func closeEndpoints(endpoints []namedEndpoint) error {
+	var res error
+
+	for _, ep := range endpoints {
+		if err := ep.Close(); err != nil && res == nil {
+			res = fmt.Errorf("server %s: %w", ep.name, err)
+		}
+	}
+
+	return res
+}
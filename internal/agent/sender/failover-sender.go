@@ -0,0 +1,197 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/agent/buffer"
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrAllServersFailed возвращается, если набор метрик не удалось отправить ни на один из серверов.
+var ErrAllServersFailed = errors.New("all servers failed")
+
+// serverHealth содержит сведения о доступности сервера.
+type serverHealth struct {
+	namedEndpoint
+
+	mtx       sync.Mutex
+	failures  uint      // количество неудачных отправок подряд
+	downUntil time.Time // момент, до которого сервер исключён из ротации
+}
+
+// available проверяет, участвует ли сервер в ротации в момент now.
+func (h *serverHealth) available(now time.Time) bool {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	return !now.Before(h.downUntil)
+}
+
+// FailoverSender предоставляет функционал отправки метрик на первый доступный сервер из списка.
+//
+// Серверы перебираются в порядке их указания в конфигурации. Сервер, отправка на который
+// завершилась ошибкой, исключается из ротации на время backoff. Если исключены все серверы,
+// перебираются все серверы списка. Наборы, которые не удалось отправить ни на один сервер,
+// сохраняются в дисковый буфер, если он используется.
+type FailoverSender struct {
+	servers   []*serverHealth
+	rateLimit uint
+	batchSize uint
+	backoff   time.Duration
+	buffer    *buffer.Queue
+	l         *log.Logger
+}
+
+// NewFailoverSender создаёт новый объект FailoverSender для серверов из cfg.Servers.
+func NewFailoverSender(cfg *config.Config, l *log.Logger) (*FailoverSender, error) {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	var (
+		buf *buffer.Queue
+		err error
+	)
+
+	if cfg.BufferDir != "" {
+		buf, err = buffer.New(cfg.BufferDir, cfg.BufferSize, cfg.BufferSegmentSize, lg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	endpoints, err := newEndpoints(cfg, false, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	servers := make([]*serverHealth, 0, len(endpoints))
+	for _, ep := range endpoints {
+		servers = append(servers, &serverHealth{namedEndpoint: ep})
+	}
+
+	return &FailoverSender{
+		servers:   servers,
+		rateLimit: cfg.RateLimit,
+		batchSize: cfg.BatchSize,
+		backoff:   cfg.ServersBackoff.Duration,
+		buffer:    buf,
+		l:         lg,
+	}, nil
+}
+
+// Send инициирует отправку набора метрик в хранилище.
+func (snd *FailoverSender) Send(ctx context.Context, storage []metric.Metrics) error {
+	if storage == nil {
+		return ErrStorageIsNil
+	}
+
+	if !replayBuffer(ctx, snd.buffer, snd.deliver, storage, snd.batchSize, snd.l) {
+		return nil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+	tasks := generateSendTasks(ctx, storage, snd.rateLimit, snd.batchSize)
+
+	for w := 1; w <= int(snd.rateLimit); w++ {
+		id := w
+
+		g.Go(func() error {
+			for batch := range tasks {
+				if err := snd.deliver(ctx, batch); err != nil {
+					snd.l.Errorf("[worker %d] error sending metric values: %s", id, err)
+					spill(snd.buffer, batch, snd.l)
+
+					continue
+				}
+
+				snd.l.Debugf("[worker %d] metrics sent: %d", id, len(batch.Metrics))
+			}
+
+			return nil
+		})
+	}
+
+	return g.Wait()
+}
+
+// Close закрывает отправителей всех серверов.
+func (snd *FailoverSender) Close() error {
+	return closeEndpoints(snd.endpoints())
+}
+
+// deliver выполняет отправку набора метрик на первый доступный сервер.
+func (snd *FailoverSender) deliver(ctx context.Context, batch metric.Batch) error {
+	now := time.Now()
+	candidates := make([]*serverHealth, 0, len(snd.servers))
+
+	for _, srv := range snd.servers {
+		if srv.available(now) {
+			candidates = append(candidates, srv)
+		}
+	}
+
+	// Все серверы исключены из ротации: перебираются все серверы
+	if len(candidates) == 0 {
+		candidates = snd.servers
+	}
+
+	for _, srv := range candidates {
+		err := srv.deliver(ctx, batch)
+		if err == nil {
+			snd.markUp(srv)
+
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		snd.markDown(srv, err)
+	}
+
+	return ErrAllServersFailed
+}
+
+// markUp возвращает сервер srv в ротацию.
+func (snd *FailoverSender) markUp(srv *serverHealth) {
+	srv.mtx.Lock()
+	defer srv.mtx.Unlock()
+
+	if srv.failures > 0 {
+		snd.l.Infof("Server %s is available again", srv.name)
+	}
+
+	srv.failures = 0
+	srv.downUntil = time.Time{}
+}
+
+// markDown исключает сервер srv из ротации на время backoff после ошибки отправки err.
+func (snd *FailoverSender) markDown(srv *serverHealth, err error) {
+	srv.mtx.Lock()
+	defer srv.mtx.Unlock()
+
+	srv.failures++
+	srv.downUntil = time.Now().Add(snd.backoff)
+
+	snd.l.Warnf("Server %s is unavailable (failures: %d): %s", srv.name, srv.failures, err)
+}
+
+// endpoints возвращает отправителей всех серверов.
+func (snd *FailoverSender) endpoints() []namedEndpoint {
+	res := make([]namedEndpoint, 0, len(snd.servers))
+	for _, srv := range snd.servers {
+		res = append(res, srv.namedEndpoint)
+	}
+
+	return res
+}
@@ -0,0 +1,63 @@
+package sender
+
+import (
+	"context"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// FanoutSender предоставляет функционал отправки метрик на все серверы из списка.
+//
+// Отправка на каждый сервер выполняется независимо. Если используется дисковый буфер,
+// каждому серверу выделяется собственный каталог буфера, поэтому недоступность одного сервера
+// не задерживает отправку на остальные.
+type FanoutSender struct {
+	servers []namedEndpoint
+	l       *log.Logger
+}
+
+// NewFanoutSender создаёт новый объект FanoutSender для серверов из cfg.Servers.
+func NewFanoutSender(cfg *config.Config, l *log.Logger) (*FanoutSender, error) {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	servers, err := newEndpoints(cfg, true, lg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FanoutSender{
+		servers: servers,
+		l:       lg,
+	}, nil
+}
+
+// Send инициирует отправку набора метрик на все серверы.
+func (snd *FanoutSender) Send(ctx context.Context, storage []metric.Metrics) error {
+	if storage == nil {
+		return ErrStorageIsNil
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	for _, srv := range snd.servers {
+		srv := srv
+
+		g.Go(func() error {
+			return srv.Send(ctx, storage)
+		})
+	}
+
+	return g.Wait()
+}
+
+// Close закрывает отправителей всех серверов.
+func (snd *FanoutSender) Close() error {
+	return closeEndpoints(snd.servers)
+}
@@ -3,13 +3,12 @@ package sender
 import (
 	"context"
 	"errors"
-	"fmt"
-	"strconv"
-	"strings"
-	"syscall"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/agent/buffer"
 	"github.com/KryukovO/metricscollector/internal/agent/config"
 	"github.com/KryukovO/metricscollector/internal/metric"
 	"github.com/KryukovO/metricscollector/internal/utils"
@@ -17,20 +16,31 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
 // GRPCSender предоставляет функционал взаимодействия с сервером-хранилищем посредством gRPC.
+//
+// Наборы метрик передаются через долгоживущий поток StreamUpdates. Если сервер не поддерживает
+// потоковую передачу, используется унарный вызов UpdateMany.
 type GRPCSender struct {
-	serverAddress string
-	rateLimit     uint
-	timeout       time.Duration
-	batchSize     uint
-	retries       []int
-	ip            string
-	l             *log.Logger
+	rateLimit uint
+	timeout   time.Duration
+	batchSize uint
+	retry     *RetryPolicy
+	ip        string
+	buffer    *buffer.Queue
+	conn      *grpc.ClientConn
+	client    pb.StorageClient
+
+	stream          *updateStream
+	streamMtx       sync.Mutex
+	streamsDisabled atomic.Bool // признак отсутствия поддержки StreamUpdates на сервере
+
+	l *log.Logger
 }
 
 // NewGRPCSender создаёт новый объект GRPCSender.
@@ -40,39 +50,67 @@ func NewGRPCSender(cfg *config.Config, l *log.Logger) (*GRPCSender, error) {
 		lg = l
 	}
 
-	retries := []int{0}
+	retry, err := NewRetryPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, err := utils.LocalIP()
+	if err != nil {
+		return nil, err
+	}
 
-	for _, r := range strings.Split(cfg.Retries, ",") {
-		interval, err := strconv.Atoi(r)
+	var buf *buffer.Queue
+
+	if cfg.BufferDir != "" {
+		buf, err = buffer.New(cfg.BufferDir, cfg.BufferSize, cfg.BufferSegmentSize, lg)
 		if err != nil {
 			return nil, err
 		}
-
-		retries = append(retries, interval)
 	}
 
-	ip, err := utils.LocalIP()
+	conn, err := grpc.Dial(cfg.GRPCAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		return nil, err
 	}
 
 	return &GRPCSender{
-		serverAddress: cfg.GRPCAddress,
-		rateLimit:     cfg.RateLimit,
-		timeout:       cfg.ServerTimeout.Duration,
-		batchSize:     cfg.BatchSize,
-		retries:       retries,
-		ip:            ip.String(),
-		l:             lg,
+		rateLimit: cfg.RateLimit,
+		timeout:   cfg.ServerTimeout.Duration,
+		batchSize: cfg.BatchSize,
+		retry:     retry,
+		ip:        ip.String(),
+		buffer:    buf,
+		conn:      conn,
+		client:    pb.NewStorageClient(conn),
+		l:         lg,
 	}, nil
 }
 
+// Close выполняет закрытие потока StreamUpdates и соединения с сервером.
+func (snd *GRPCSender) Close() error {
+	snd.streamMtx.Lock()
+
+	if snd.stream != nil {
+		snd.stream.close()
+		snd.stream = nil
+	}
+
+	snd.streamMtx.Unlock()
+
+	return snd.conn.Close()
+}
+
 // Send инициирует отправку набора метрик в хранилище.
 func (snd *GRPCSender) Send(ctx context.Context, storage []metric.Metrics) error {
 	if storage == nil {
 		return ErrStorageIsNil
 	}
 
+	if !replayBuffer(ctx, snd.buffer, snd.send, storage, snd.batchSize, snd.l) {
+		return nil
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	tasks := generateSendTasks(ctx, storage, snd.rateLimit, snd.batchSize)
 
@@ -89,48 +127,24 @@ func (snd *GRPCSender) Send(ctx context.Context, storage []metric.Metrics) error
 
 // sendTaskWorker выполняет сканирование канала на наличие в нем сообщений, содержащих метрики,
 // и инициирует отправку их в хранилище посредством gRPC.
-func (snd *GRPCSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan []metric.Metrics) error {
-	conn, err := grpc.Dial(snd.serverAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return err
-	}
-
-	defer conn.Close()
-
-	client := pb.NewStorageClient(conn)
+func (snd *GRPCSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan metric.Batch) error {
+	var err error
 
 	for batch := range tasks {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			send := func() error {
-				sendCtx, cancel := context.WithTimeout(ctx, snd.timeout)
-				defer cancel()
-
-				return snd.sendMetrics(sendCtx, client, batch)
-			}
-
-			for _, t := range snd.retries {
-				err = utils.Wait(ctx, time.Duration(t)*time.Second)
-				if err != nil {
-					break
-				}
-
-				err = send()
-				if err == nil || !errors.Is(err, syscall.ECONNREFUSED) {
-					break
-				}
-			}
-
+			err = snd.deliver(ctx, batch)
 			if err != nil {
 				if errors.Is(err, ErrClientIsNil) {
 					return err
 				}
 
 				snd.l.Errorf("[worker %d] error sending metric values: %s", id, err.Error())
+				spill(snd.buffer, batch, snd.l)
 			} else {
-				snd.l.Debugf("[worker %d] metrics sent: %d", id, len(batch))
+				snd.l.Debugf("[worker %d] metrics sent: %d", id, len(batch.Metrics))
 			}
 		}
 	}
@@ -138,41 +152,103 @@ func (snd *GRPCSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan
 	return nil
 }
 
+// deliver выполняет отправку набора метрик с повторными попытками согласно политике retry.
+func (snd *GRPCSender) deliver(ctx context.Context, batch metric.Batch) error {
+	return snd.retry.Do(ctx, func(ctx context.Context) error {
+		return snd.send(ctx, batch)
+	})
+}
+
+// send выполняет однократную отправку набора метрик с таймаутом соединения с сервером.
+func (snd *GRPCSender) send(ctx context.Context, batch metric.Batch) error {
+	sendCtx, cancel := context.WithTimeout(ctx, snd.timeout)
+	defer cancel()
+
+	return snd.sendMetrics(sendCtx, snd.client, batch)
+}
+
 // sendMetrics выполняет отправку метрик посредством gRPC.
-func (snd *GRPCSender) sendMetrics(ctx context.Context, client pb.StorageClient, batch []metric.Metrics) error {
+func (snd *GRPCSender) sendMetrics(ctx context.Context, client pb.StorageClient, batch metric.Batch) error {
 	if client == nil {
 		return ErrClientIsNil
 	}
 
 	metrics := &pb.UpdateManyRequest{
-		Metrics: make([]*pb.MetricDescr, 0, len(batch)),
+		Metrics: make([]*pb.MetricDescr, 0, len(batch.Metrics)),
 	}
 
-	for _, mtrc := range batch {
+	for _, mtrc := range batch.Metrics {
 		m := &pb.MetricDescr{
-			Id:   mtrc.ID,
-			Type: metric.MapMetricTypeToGRPC[mtrc.MType],
+			Id:     mtrc.ID,
+			Type:   metric.MapMetricTypeToGRPC[mtrc.MType],
+			Labels: mtrc.Labels,
 		}
 
 		switch {
 		case mtrc.Delta != nil:
 			m.Delta = *mtrc.Delta
 		case mtrc.Value != nil:
+			// Поле value заполняется для серверов, не поддерживающих value_double
 			m.Value = float32(*mtrc.Value)
+			m.ValueDouble = mtrc.Value
+		case mtrc.Histogram != nil:
+			m.Histogram = metric.HistogramToGRPC(mtrc.Histogram)
 		}
 
 		metrics.Metrics = append(metrics.GetMetrics(), m)
 	}
 
+	if !snd.streamsDisabled.Load() {
+		err := snd.sendStream(ctx, client, &pb.StreamUpdateRequest{Metrics: metrics.GetMetrics(), BatchId: batch.ID})
+		if status.Code(err) != codes.Unimplemented {
+			return err
+		}
+
+		snd.l.Info("server does not support StreamUpdates, falling back to UpdateMany")
+		snd.streamsDisabled.Store(true)
+	}
+
 	md := metadata.New(map[string]string{"X-Real-IP": snd.ip})
+	if batch.ID != "" {
+		md.Set(metric.BatchIDHeader, batch.ID)
+	}
+
 	grpcCtx := metadata.NewOutgoingContext(ctx, md)
 
 	_, err := client.UpdateMany(grpcCtx, metrics)
-	if err != nil {
-		st, _ := status.FromError(err)
 
-		return fmt.Errorf("%s: %w", st.Code(), ErrUnexpectedStatus)
+	return grpcError(err)
+}
+
+// sendStream выполняет отправку метрик через поток StreamUpdates.
+// Завершённый поток открывается заново при следующей отправке.
+// Если сервер не поддерживает StreamUpdates, возвращается ошибка со статусом codes.Unimplemented.
+func (snd *GRPCSender) sendStream(ctx context.Context, client pb.StorageClient, req *pb.StreamUpdateRequest) error {
+	snd.streamMtx.Lock()
+
+	if snd.stream == nil || snd.stream.closed() {
+		if snd.stream != nil {
+			snd.stream.close()
+		}
+
+		stream, err := newUpdateStream(client, snd.ip)
+		if err != nil {
+			snd.streamMtx.Unlock()
+
+			return grpcError(err)
+		}
+
+		snd.stream = stream
 	}
 
-	return nil
+	stream := snd.stream
+
+	snd.streamMtx.Unlock()
+
+	err := stream.send(ctx, req)
+	if status.Code(err) == codes.Unimplemented {
+		return err
+	}
+
+	return grpcError(err)
 }
@@ -0,0 +1,128 @@
+package sender
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+	sgrpc "github.com/KryukovO/metricscollector/internal/server/grpc"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// unaryStorageServer - сервер, не поддерживающий StreamUpdates.
+type unaryStorageServer struct {
+	pb.UnimplementedStorageServer
+
+	srv *sgrpc.StorageServer
+}
+
+func (s *unaryStorageServer) UpdateMany(ctx context.Context, req *pb.UpdateManyRequest) (*emptypb.Empty, error) {
+	return s.srv.UpdateMany(ctx, req)
+}
+
+func TestGRPCSend(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 1234567890.123456
+		metrics          = []metric.Metrics{
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal},
+			{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal},
+			{ID: "Alloc", MType: metric.GaugeMetric, Value: &gaugeVal},
+		}
+	)
+
+	tests := []struct {
+		name      string
+		streaming bool
+	}{
+		{
+			name:      "Streaming server",
+			streaming: true,
+		},
+		{
+			name:      "Server without StreamUpdates",
+			streaming: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo, err := memstorage.NewMemStorage(
+				context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil,
+			)
+			require.NoError(t, err)
+
+			storageServer, err := sgrpc.NewStorageServer(storage.NewMetricsStorage(repo, time.Second), nil)
+			require.NoError(t, err)
+
+			listen, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+
+			grpcServer := grpc.NewServer()
+			if test.streaming {
+				pb.RegisterStorageServer(grpcServer, storageServer)
+			} else {
+				pb.RegisterStorageServer(grpcServer, &unaryStorageServer{srv: storageServer})
+			}
+
+			go func() {
+				_ = grpcServer.Serve(listen)
+			}()
+
+			defer grpcServer.Stop()
+
+			snd, err := NewGRPCSender(
+				&config.Config{
+					RetryMaxAttempts: 1,
+					GRPCAddress:      listen.Addr().String(),
+					RateLimit:        2,
+					ServerTimeout:    utils.Duration{Duration: 5 * time.Second},
+					BatchSize:        1,
+				},
+				nil,
+			)
+			require.NoError(t, err)
+
+			defer snd.Close()
+
+			for i := 0; i < 2; i++ {
+				err = snd.Send(context.Background(), metrics)
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, !test.streaming, snd.streamsDisabled.Load())
+
+			values, err := repo.GetAll(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, values, len(metrics))
+
+			v, err := repo.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+			require.NoError(t, err)
+			assert.EqualValues(t, 2*counterVal, *v.Delta)
+
+			v, err = repo.GetValue(context.Background(), metric.GaugeMetric, "RandomValue", nil)
+			require.NoError(t, err)
+			assert.Equal(t, gaugeVal, *v.Value, "Gauge value must be transferred with double precision")
+
+			// Повторно отправленный набор не применяется сервером
+			batch := metric.NewBatch(metrics[:1])
+			for i := 0; i < 2; i++ {
+				require.NoError(t, snd.deliver(context.Background(), batch))
+			}
+
+			v, err = repo.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+			require.NoError(t, err)
+			assert.EqualValues(t, 3*counterVal, *v.Delta)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ErrStreamClosed возвращается, если поток StreamUpdates был закрыт сервером до получения подтверждения.
+var ErrStreamClosed = errors.New("update stream closed")
+
+// updateStream - долгоживущий поток StreamUpdates.
+// Наборы метрик отправляются с порядковым номером,
+// подтверждения сервера сопоставляются с ожидающими отправителями по этому номеру.
+type updateStream struct {
+	stream  pb.Storage_StreamUpdatesClient
+	cancel  context.CancelFunc
+	sendMtx sync.Mutex // сериализует отправку сообщений в поток
+
+	mtx     sync.Mutex
+	seq     uint64
+	pending map[uint64]chan *pb.StreamUpdateResponse
+	done    chan struct{} // закрывается при завершении потока
+	err     error         // причина завершения потока
+}
+
+// newUpdateStream открывает новый поток StreamUpdates.
+func newUpdateStream(client pb.StorageClient, ip string) (*updateStream, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	md := metadata.New(map[string]string{"X-Real-IP": ip})
+
+	stream, err := client.StreamUpdates(metadata.NewOutgoingContext(ctx, md))
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	s := &updateStream{
+		stream:  stream,
+		cancel:  cancel,
+		pending: make(map[uint64]chan *pb.StreamUpdateResponse),
+		done:    make(chan struct{}),
+	}
+
+	go s.receive()
+
+	return s, nil
+}
+
+// receive выполняет чтение подтверждений из потока и передаёт их ожидающим отправителям.
+func (s *updateStream) receive() {
+	for {
+		resp, err := s.stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = ErrStreamClosed
+			}
+
+			s.mtx.Lock()
+			s.err = err
+			s.mtx.Unlock()
+
+			close(s.done)
+
+			return
+		}
+
+		s.mtx.Lock()
+		ch, ok := s.pending[resp.GetSeq()]
+		delete(s.pending, resp.GetSeq())
+		s.mtx.Unlock()
+
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+// send выполняет отправку набора метрик в поток и ожидает подтверждения его обработки.
+// Порядковый номер набора в потоке присваивается при отправке.
+func (s *updateStream) send(ctx context.Context, req *pb.StreamUpdateRequest) error {
+	ch := make(chan *pb.StreamUpdateResponse, 1)
+
+	s.mtx.Lock()
+
+	if s.err != nil {
+		s.mtx.Unlock()
+
+		return s.err
+	}
+
+	s.seq++
+	seq := s.seq
+	s.pending[seq] = ch
+
+	s.mtx.Unlock()
+
+	defer func() {
+		s.mtx.Lock()
+		delete(s.pending, seq)
+		s.mtx.Unlock()
+	}()
+
+	s.sendMtx.Lock()
+	req.Seq = seq
+	err := s.stream.Send(req)
+	s.sendMtx.Unlock()
+
+	if err != nil {
+		// При io.EOF фактическая причина завершения потока доступна только через Recv
+		if errors.Is(err, io.EOF) {
+			<-s.done
+
+			return s.err
+		}
+
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if code := codes.Code(resp.GetCode()); code != codes.OK {
+			return status.Error(code, resp.GetMessage())
+		}
+
+		return nil
+	case <-s.done:
+		return s.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// closed проверяет, завершён ли поток.
+func (s *updateStream) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close выполняет закрытие потока.
+func (s *updateStream) close() {
+	s.sendMtx.Lock()
+	_ = s.stream.CloseSend()
+	s.sendMtx.Unlock()
+
+	s.cancel()
+}
@@ -4,20 +4,17 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
+	"github.com/KryukovO/metricscollector/internal/agent/buffer"
 	"github.com/KryukovO/metricscollector/internal/agent/config"
 	"github.com/KryukovO/metricscollector/internal/metric"
 	"github.com/KryukovO/metricscollector/internal/utils"
@@ -31,10 +28,12 @@ type HTTPSender struct {
 	rateLimit     uint
 	timeout       time.Duration
 	batchSize     uint
-	retries       []int
+	retry         *RetryPolicy
 	key           string
 	publicKey     *rsa.PublicKey
 	ip            string
+	client        *http.Client
+	buffer        *buffer.Queue
 	l             *log.Logger
 }
 
@@ -45,15 +44,9 @@ func NewHTTPSender(cfg *config.Config, l *log.Logger) (*HTTPSender, error) {
 		lg = l
 	}
 
-	retries := []int{0}
-
-	for _, r := range strings.Split(cfg.Retries, ",") {
-		interval, err := strconv.Atoi(r)
-		if err != nil {
-			return nil, err
-		}
-
-		retries = append(retries, interval)
+	retry, err := NewRetryPolicy(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	ip, err := utils.LocalIP()
@@ -61,15 +54,26 @@ func NewHTTPSender(cfg *config.Config, l *log.Logger) (*HTTPSender, error) {
 		return nil, err
 	}
 
+	var buf *buffer.Queue
+
+	if cfg.BufferDir != "" {
+		buf, err = buffer.New(cfg.BufferDir, cfg.BufferSize, cfg.BufferSegmentSize, lg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &HTTPSender{
 		serverAddress: cfg.HTTPAddress,
 		rateLimit:     cfg.RateLimit,
 		timeout:       cfg.ServerTimeout.Duration,
 		batchSize:     cfg.BatchSize,
-		retries:       retries,
+		retry:         retry,
 		key:           cfg.Key,
 		publicKey:     cfg.PublicKey,
 		ip:            ip.String(),
+		client:        &http.Client{},
+		buffer:        buf,
 		l:             lg,
 	}, nil
 }
@@ -80,6 +84,10 @@ func (snd *HTTPSender) Send(ctx context.Context, storage []metric.Metrics) error
 		return ErrStorageIsNil
 	}
 
+	if !replayBuffer(ctx, snd.buffer, snd.send, storage, snd.batchSize, snd.l) {
+		return nil
+	}
+
 	g, ctx := errgroup.WithContext(ctx)
 	tasks := generateSendTasks(ctx, storage, snd.rateLimit, snd.batchSize)
 
@@ -94,46 +102,31 @@ func (snd *HTTPSender) Send(ctx context.Context, storage []metric.Metrics) error
 	return g.Wait()
 }
 
+// Close освобождает ресурсы HTTPSender.
+func (snd *HTTPSender) Close() error {
+	return nil
+}
+
 // sendTaskWorker выполняет сканирование канала на наличие в нем сообщений, содержащих метрики,
 // и инициирует отправку их в хранилище посредством HTTP.
-func (snd *HTTPSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan []metric.Metrics) error {
-	var (
-		err    error
-		client http.Client
-	)
+func (snd *HTTPSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan metric.Batch) error {
+	var err error
 
 	for batch := range tasks {
 		select {
 		case <-ctx.Done():
 			return nil
 		default:
-			send := func() error {
-				sendCtx, cancel := context.WithTimeout(ctx, snd.timeout)
-				defer cancel()
-
-				return snd.sendMetrics(sendCtx, &client, batch)
-			}
-
-			for _, t := range snd.retries {
-				err = utils.Wait(ctx, time.Duration(t)*time.Second)
-				if err != nil {
-					break
-				}
-
-				err = send()
-				if err == nil || !errors.Is(err, syscall.ECONNREFUSED) {
-					break
-				}
-			}
-
+			err = snd.deliver(ctx, batch)
 			if err != nil {
 				if errors.Is(err, ErrClientIsNil) {
 					return err
 				}
 
 				snd.l.Errorf("[worker %d] error sending metric values: %s", id, err.Error())
+				spill(snd.buffer, batch, snd.l)
 			} else {
-				snd.l.Debugf("[worker %d] metrics sent: %d", id, len(batch))
+				snd.l.Debugf("[worker %d] metrics sent: %d", id, len(batch.Metrics))
 			}
 		}
 	}
@@ -141,25 +134,40 @@ func (snd *HTTPSender) sendTaskWorker(ctx context.Context, id int, tasks <-chan
 	return nil
 }
 
+// deliver выполняет отправку набора метрик с повторными попытками согласно политике retry.
+func (snd *HTTPSender) deliver(ctx context.Context, batch metric.Batch) error {
+	return snd.retry.Do(ctx, func(ctx context.Context) error {
+		return snd.send(ctx, batch)
+	})
+}
+
+// send выполняет однократную отправку набора метрик с таймаутом соединения с сервером.
+func (snd *HTTPSender) send(ctx context.Context, batch metric.Batch) error {
+	sendCtx, cancel := context.WithTimeout(ctx, snd.timeout)
+	defer cancel()
+
+	return snd.sendMetrics(sendCtx, snd.client, batch)
+}
+
 // sendMetrics выполняет отправку метрик посредством HTTP.
-func (snd *HTTPSender) sendMetrics(ctx context.Context, client *http.Client, batch []metric.Metrics) error {
+func (snd *HTTPSender) sendMetrics(ctx context.Context, client *http.Client, batch metric.Batch) error {
 	if client == nil {
 		return ErrClientIsNil
 	}
 
 	url := fmt.Sprintf("%s/updates/", snd.serverAddress)
 
-	if !strings.HasPrefix(url, "http://") {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		url = fmt.Sprintf("http://%s", url)
 	}
 
-	body, err := json.Marshal(batch)
+	body, err := json.Marshal(batch.Metrics)
 	if err != nil {
 		return err
 	}
 
 	if snd.publicKey != nil {
-		body, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, snd.publicKey, body, nil)
+		body, err = utils.EncryptHybrid(snd.publicKey, body)
 		if err != nil {
 			return err
 		}
@@ -183,6 +191,14 @@ func (snd *HTTPSender) sendMetrics(ctx context.Context, client *http.Client, bat
 	req.Header.Set("Content-Encoding", "gzip")
 	req.Header.Set("X-Real-IP", snd.ip)
 
+	if batch.ID != "" {
+		req.Header.Set(metric.BatchIDHeader, batch.ID)
+	}
+
+	if snd.publicKey != nil {
+		req.Header.Set(utils.EncryptionVersionHeader, utils.EncryptionHybrid)
+	}
+
 	if snd.key != "" {
 		hash, hashErr := utils.HashSHA256(body, []byte(snd.key))
 		if hashErr != nil {
@@ -206,7 +222,7 @@ func (snd *HTTPSender) sendMetrics(ctx context.Context, client *http.Client, bat
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%s: %w", resp.Status, ErrUnexpectedStatus)
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	return nil
@@ -1,9 +1,14 @@
 package sender
 
 import (
+	"compress/gzip"
 	"context"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
@@ -41,13 +46,13 @@ func TestSend(t *testing.T) {
 
 	sender, err := NewHTTPSender(
 		&config.Config{
-			Retries:       "1",
-			HTTPAddress:   server.URL,
-			Key:           "secret",
-			RateLimit:     2,
-			ServerTimeout: utils.Duration{Duration: 10 * time.Second},
-			BatchSize:     1,
-			PublicKey:     &privateKey.PublicKey,
+			RetryMaxAttempts: 1,
+			HTTPAddress:      server.URL,
+			Key:              "secret",
+			RateLimit:        2,
+			ServerTimeout:    utils.Duration{Duration: 10 * time.Second},
+			BatchSize:        1,
+			PublicKey:        &privateKey.PublicKey,
 		},
 		nil,
 	)
@@ -62,3 +67,73 @@ func TestSend(t *testing.T) {
 
 	<-ctx.Done()
 }
+
+func TestSendBuffered(t *testing.T) {
+	var (
+		mtx      sync.Mutex
+		failing  = true
+		received = make([]string, 0)
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+
+		var batch []metric.Metrics
+		require.NoError(t, json.NewDecoder(gz).Decode(&batch))
+
+		for _, mtrc := range batch {
+			received = append(received, mtrc.ID)
+		}
+	}))
+	defer server.Close()
+
+	sender, err := NewHTTPSender(
+		&config.Config{
+			RetryMaxAttempts:  1,
+			HTTPAddress:       server.URL,
+			RateLimit:         1,
+			ServerTimeout:     utils.Duration{Duration: time.Second},
+			BatchSize:         1,
+			BufferDir:         t.TempDir(),
+			BufferSize:        1 << 20,
+			BufferSegmentSize: 1 << 10,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	newStorage := func(names ...string) []metric.Metrics {
+		storage := make([]metric.Metrics, 0, len(names))
+
+		for _, name := range names {
+			val := 1.0
+			storage = append(storage, metric.Metrics{ID: name, MType: metric.GaugeMetric, Value: &val})
+		}
+
+		return storage
+	}
+
+	// Сервер недоступен: наборы сохраняются в буфер
+	require.NoError(t, sender.Send(context.Background(), newStorage("m1", "m2")))
+	require.NoError(t, sender.Send(context.Background(), newStorage("m3")))
+	assert.Positive(t, sender.buffer.Size())
+
+	mtx.Lock()
+	failing = false
+	mtx.Unlock()
+
+	// Накопленные наборы отправляются первыми в порядке сбора
+	require.NoError(t, sender.Send(context.Background(), newStorage("m4")))
+	assert.Equal(t, []string{"m1", "m2", "m3", "m4"}, received)
+	assert.Zero(t, sender.buffer.Size())
+}
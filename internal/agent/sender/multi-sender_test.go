@@ -0,0 +1,138 @@
+package sender
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/metric"
+	sgrpc "github.com/KryukovO/metricscollector/internal/server/grpc"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// startGRPCStorage запускает gRPC-сервер с хранилищем в памяти.
+// Возвращает адрес сервера и хранилище.
+func startGRPCStorage(t *testing.T) (string, *memstorage.MemStorage) {
+	t.Helper()
+
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	storageServer, err := sgrpc.NewStorageServer(storage.NewMetricsStorage(repo, time.Second), nil)
+	require.NoError(t, err)
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterStorageServer(grpcServer, storageServer)
+
+	go func() {
+		_ = grpcServer.Serve(listen)
+	}()
+
+	t.Cleanup(grpcServer.Stop)
+
+	return listen.Addr().String(), repo
+}
+
+// startHTTPStorage запускает HTTP-сервер, отвечающий кодом status. Возвращает сервер и счётчик запросов.
+func startHTTPStorage(t *testing.T, status int) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	requests := &atomic.Int64{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+// newMultiConfig возвращает конфигурацию агента для списка серверов servers.
+func newMultiConfig(servers string) *config.Config {
+	return &config.Config{
+		Servers:          servers,
+		ServersBackoff:   utils.Duration{Duration: time.Minute},
+		RetryMaxAttempts: 1,
+		RateLimit:        1,
+		ServerTimeout:    utils.Duration{Duration: 5 * time.Second},
+		BatchSize:        1,
+	}
+}
+
+func TestFailoverSend(t *testing.T) {
+	var (
+		counterVal int64 = 10
+		metrics          = []metric.Metrics{{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}}
+	)
+
+	failing, failingRequests := startHTTPStorage(t, http.StatusInternalServerError)
+	grpcAddress, repo := startGRPCStorage(t)
+
+	snd, err := NewFailoverSender(newMultiConfig(failing.URL+",grpc://"+grpcAddress), nil)
+	require.NoError(t, err)
+
+	defer snd.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, snd.Send(context.Background(), metrics))
+	}
+
+	// Недоступный сервер исключается из ротации после первой ошибки
+	assert.EqualValues(t, 1, failingRequests.Load())
+
+	v, err := repo.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3*counterVal, *v.Delta)
+
+	// Все серверы исключены из ротации: перебираются все серверы
+	snd, err = NewFailoverSender(newMultiConfig(failing.URL), nil)
+	require.NoError(t, err)
+
+	defer snd.Close()
+
+	assert.ErrorIs(t, snd.deliver(context.Background(), metric.NewBatch(metrics)), ErrAllServersFailed)
+	assert.ErrorIs(t, snd.deliver(context.Background(), metric.NewBatch(metrics)), ErrAllServersFailed)
+	assert.EqualValues(t, 3, failingRequests.Load())
+}
+
+func TestFanoutSend(t *testing.T) {
+	var (
+		counterVal int64 = 10
+		metrics          = []metric.Metrics{{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}}
+	)
+
+	httpServer, httpRequests := startHTTPStorage(t, http.StatusOK)
+	grpcAddress, repo := startGRPCStorage(t)
+
+	snd, err := NewFanoutSender(newMultiConfig(httpServer.URL+", grpc://"+grpcAddress), nil)
+	require.NoError(t, err)
+
+	defer snd.Close()
+
+	require.NoError(t, snd.Send(context.Background(), metrics))
+
+	assert.EqualValues(t, 1, httpRequests.Load())
+
+	v, err := repo.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, counterVal, *v.Delta)
+
+	_, err = NewFanoutSender(newMultiConfig(" , "), nil)
+	assert.ErrorIs(t, err, ErrNoServers)
+}
@@ -0,0 +1,243 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/utils"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxGRPCCode - наибольший код статуса gRPC.
+const maxGRPCCode = codes.Unauthenticated
+
+var (
+	// ErrWrongRetryPolicy возвращается NewRetryPolicy, если параметры политики повторных попыток некорректны.
+	ErrWrongRetryPolicy = errors.New("wrong retry policy")
+	// ErrUnknownGRPCCode возвращается NewRetryPolicy, если указан неизвестный код статуса gRPC.
+	ErrUnknownGRPCCode = errors.New("unknown gRPC code")
+)
+
+// StatusError - ошибка отправки, вызванная кодом ответа HTTP-сервера, отличным от 200 OK.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+// Error возвращает текст ошибки.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, ErrUnexpectedStatus)
+}
+
+// Unwrap возвращает ErrUnexpectedStatus.
+func (e *StatusError) Unwrap() error {
+	return ErrUnexpectedStatus
+}
+
+// GRPCStatusError - ошибка отправки, вызванная статусом ответа gRPC-сервера, отличным от codes.OK.
+type GRPCStatusError struct {
+	Code    codes.Code
+	Message string
+}
+
+// Error возвращает текст ошибки.
+func (e *GRPCStatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s: %s", e.Code, ErrUnexpectedStatus)
+	}
+
+	return fmt.Sprintf("%s (%s): %s", e.Code, e.Message, ErrUnexpectedStatus)
+}
+
+// Unwrap возвращает ErrUnexpectedStatus.
+func (e *GRPCStatusError) Unwrap() error {
+	return ErrUnexpectedStatus
+}
+
+// grpcError преобразует ошибку вызова gRPC в GRPCStatusError.
+// Ошибки, не содержащие статуса gRPC, возвращаются без изменений.
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if st, ok := status.FromError(err); ok {
+		return &GRPCStatusError{Code: st.Code(), Message: st.Message()}
+	}
+
+	return err
+}
+
+// RetryPolicy описывает политику повторных попыток отправки метрик:
+// экспоненциальное увеличение задержки между попытками со случайным отклонением
+// и классификацию ошибок, при которых попытка повторяется.
+type RetryPolicy struct {
+	maxAttempts  uint
+	baseDelay    time.Duration
+	maxDelay     time.Duration
+	jitter       float64
+	httpStatuses map[int]struct{}
+	grpcCodes    map[codes.Code]struct{}
+
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewRetryPolicy создаёт политику повторных попыток по параметрам конфигурации агента.
+func NewRetryPolicy(cfg *config.Config) (*RetryPolicy, error) {
+	if cfg.RetryMaxAttempts == 0 || cfg.RetryJitter < 0 || cfg.RetryJitter > 1 ||
+		cfg.RetryBaseDelay.Duration < 0 || cfg.RetryMaxDelay.Duration < cfg.RetryBaseDelay.Duration {
+		return nil, ErrWrongRetryPolicy
+	}
+
+	policy := &RetryPolicy{
+		maxAttempts:  cfg.RetryMaxAttempts,
+		baseDelay:    cfg.RetryBaseDelay.Duration,
+		maxDelay:     cfg.RetryMaxDelay.Duration,
+		jitter:       cfg.RetryJitter,
+		httpStatuses: make(map[int]struct{}),
+		grpcCodes:    make(map[codes.Code]struct{}),
+		rnd:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, s := range splitList(cfg.RetryHTTPStatuses) {
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: HTTP status %q", ErrWrongRetryPolicy, s)
+		}
+
+		policy.httpStatuses[code] = struct{}{}
+	}
+
+	for _, s := range splitList(cfg.RetryGRPCCodes) {
+		code, err := parseGRPCCode(s)
+		if err != nil {
+			return nil, err
+		}
+
+		policy.grpcCodes[code] = struct{}{}
+	}
+
+	return policy, nil
+}
+
+// Do выполняет send, повторяя попытки в соответствии с политикой.
+// Возвращается ошибка последней попытки.
+func (p *RetryPolicy) Do(ctx context.Context, send func(ctx context.Context) error) error {
+	for attempt := uint(1); ; attempt++ {
+		err := send(ctx)
+		if err == nil || attempt >= p.maxAttempts || !p.Retryable(err) {
+			return err
+		}
+
+		if waitErr := utils.Wait(ctx, p.Delay(attempt)); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// Delay возвращает задержку перед повторной попыткой с номером attempt (начиная с 1).
+// Задержка удваивается с каждой попыткой, не превышая maxDelay,
+// и случайным образом отклоняется от расчётной не более чем на долю jitter.
+func (p *RetryPolicy) Delay(attempt uint) time.Duration {
+	delay := p.baseDelay
+
+	for i := uint(1); i < attempt && delay < p.maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+
+	if p.jitter == 0 || delay == 0 {
+		return delay
+	}
+
+	p.mtx.Lock()
+	factor := 1 + p.jitter*(2*p.rnd.Float64()-1)
+	p.mtx.Unlock()
+
+	return time.Duration(float64(delay) * factor)
+}
+
+// Retryable проверяет, следует ли повторить попытку отправки после ошибки err.
+//
+// Повторяются попытки после сетевых ошибок (отказ или сброс соединения, таймаут,
+// закрытие потока StreamUpdates), ответов HTTP-сервера с кодами из httpStatuses
+// и ответов gRPC-сервера с кодами из grpcCodes.
+func (p *RetryPolicy) Retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		_, ok := p.httpStatuses[statusErr.StatusCode]
+
+		return ok
+	}
+
+	var grpcErr *GRPCStatusError
+	if errors.As(err, &grpcErr) {
+		_, ok := p.grpcCodes[grpcErr.Code]
+
+		return ok
+	}
+
+	if st, ok := status.FromError(err); ok {
+		_, ok = p.grpcCodes[st.Code()]
+
+		return ok
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrStreamClosed) {
+		return true
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseGRPCCode возвращает код статуса gRPC по его имени (например, "Unavailable") или числовому значению.
+func parseGRPCCode(s string) (codes.Code, error) {
+	if num, err := strconv.ParseUint(s, 10, 32); err == nil && codes.Code(num) <= maxGRPCCode {
+		return codes.Code(num), nil
+	}
+
+	for code := codes.OK; code <= maxGRPCCode; code++ {
+		if strings.EqualFold(code.String(), s) {
+			return code, nil
+		}
+	}
+
+	return codes.Unknown, fmt.Errorf("%w: %q", ErrUnknownGRPCCode, s)
+}
+
+// splitList разбивает список значений, перечисленных через запятую.
+func splitList(s string) []string {
+	res := make([]string, 0)
+
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+
+	return res
+}
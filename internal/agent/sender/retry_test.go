@@ -0,0 +1,229 @@
+package sender
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/agent/config"
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRetryConfig возвращает конфигурацию политики повторных попыток без задержек.
+func newTestRetryConfig() *config.Config {
+	return &config.Config{
+		RetryMaxAttempts:  3,
+		RetryHTTPStatuses: "429,503",
+		RetryGRPCCodes:    "Unavailable,8",
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.Config)
+		wantErr error
+	}{
+		{
+			name:   "Correct policy",
+			modify: func(cfg *config.Config) {},
+		},
+		{
+			name:    "Zero attempts",
+			modify:  func(cfg *config.Config) { cfg.RetryMaxAttempts = 0 },
+			wantErr: ErrWrongRetryPolicy,
+		},
+		{
+			name:    "Wrong jitter",
+			modify:  func(cfg *config.Config) { cfg.RetryJitter = 1.5 },
+			wantErr: ErrWrongRetryPolicy,
+		},
+		{
+			name: "Max delay less than base delay",
+			modify: func(cfg *config.Config) {
+				cfg.RetryBaseDelay = utils.Duration{Duration: time.Second}
+			},
+			wantErr: ErrWrongRetryPolicy,
+		},
+		{
+			name:    "Wrong HTTP status",
+			modify:  func(cfg *config.Config) { cfg.RetryHTTPStatuses = "5xx" },
+			wantErr: ErrWrongRetryPolicy,
+		},
+		{
+			name:    "Unknown gRPC code",
+			modify:  func(cfg *config.Config) { cfg.RetryGRPCCodes = "Unreachable" },
+			wantErr: ErrUnknownGRPCCode,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := newTestRetryConfig()
+			test.modify(cfg)
+
+			_, err := NewRetryPolicy(cfg)
+			assert.ErrorIs(t, err, test.wantErr)
+		})
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	policy, err := NewRetryPolicy(newTestRetryConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{
+			name:     "Connection refused",
+			err:      fmt.Errorf("dial: %w", syscall.ECONNREFUSED),
+			expected: true,
+		},
+		{
+			name:     "Connection reset",
+			err:      fmt.Errorf("read: %w", syscall.ECONNRESET),
+			expected: true,
+		},
+		{
+			name:     "Timeout",
+			err:      fmt.Errorf("request: %w", context.DeadlineExceeded),
+			expected: true,
+		},
+		{
+			name:     "Retryable HTTP status",
+			err:      &StatusError{StatusCode: http.StatusServiceUnavailable, Status: "503 Service Unavailable"},
+			expected: true,
+		},
+		{
+			name:     "Non-retryable HTTP status",
+			err:      &StatusError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"},
+			expected: false,
+		},
+		{
+			name:     "Retryable gRPC code by name",
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			expected: true,
+		},
+		{
+			name:     "Retryable gRPC code by number",
+			err:      status.Error(codes.ResourceExhausted, "exhausted"),
+			expected: true,
+		},
+		{
+			name:     "Retryable gRPC status error",
+			err:      grpcError(status.Error(codes.Unavailable, "connection refused")),
+			expected: true,
+		},
+		{
+			name:     "Non-retryable gRPC code",
+			err:      status.Error(codes.InvalidArgument, "invalid"),
+			expected: false,
+		},
+		{
+			name:     "Other error",
+			err:      errors.New("other"),
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, policy.Retryable(test.err))
+		})
+	}
+
+	assert.ErrorIs(t, &StatusError{StatusCode: http.StatusBadRequest}, ErrUnexpectedStatus)
+	assert.ErrorIs(t, grpcError(status.Error(codes.Internal, "")), ErrUnexpectedStatus)
+	assert.ErrorIs(t, grpcError(context.DeadlineExceeded), context.DeadlineExceeded)
+}
+
+func TestRetryDelay(t *testing.T) {
+	cfg := newTestRetryConfig()
+	cfg.RetryBaseDelay = utils.Duration{Duration: 100 * time.Millisecond}
+	cfg.RetryMaxDelay = utils.Duration{Duration: time.Second}
+
+	policy, err := NewRetryPolicy(cfg)
+	require.NoError(t, err)
+
+	expected := []time.Duration{
+		100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond,
+		800 * time.Millisecond, time.Second, time.Second,
+	}
+
+	for i, delay := range expected {
+		assert.Equal(t, delay, policy.Delay(uint(i+1)))
+	}
+
+	cfg.RetryJitter = 0.5
+
+	policy, err = NewRetryPolicy(cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		delay := policy.Delay(2)
+		assert.GreaterOrEqual(t, delay, 100*time.Millisecond)
+		assert.LessOrEqual(t, delay, 300*time.Millisecond)
+	}
+}
+
+func TestRetryDo(t *testing.T) {
+	policy, err := NewRetryPolicy(newTestRetryConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		errs     []error
+		attempts int
+		wantErr  bool
+	}{
+		{
+			name:     "Success after retries",
+			errs:     []error{syscall.ECONNREFUSED, status.Error(codes.Unavailable, "")},
+			attempts: 3,
+		},
+		{
+			name: "Attempts exhausted",
+			errs: []error{
+				syscall.ECONNREFUSED, syscall.ECONNREFUSED, syscall.ECONNREFUSED, syscall.ECONNREFUSED,
+			},
+			attempts: 3,
+			wantErr:  true,
+		},
+		{
+			name:     "Non-retryable error",
+			errs:     []error{&StatusError{StatusCode: http.StatusBadRequest}},
+			attempts: 1,
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+
+			err := policy.Do(context.Background(), func(ctx context.Context) error {
+				attempts++
+
+				if attempts <= len(test.errs) {
+					return test.errs[attempts-1]
+				}
+
+				return nil
+			})
+
+			assert.Equal(t, test.wantErr, err != nil)
+			assert.Equal(t, test.attempts, attempts)
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 
+	"github.com/KryukovO/metricscollector/internal/agent/buffer"
 	"github.com/KryukovO/metricscollector/internal/metric"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -17,17 +20,21 @@ var (
 	ErrUnexpectedStatus = errors.New("unexpected response status")
 )
 
+// Sender описывает способ отправки метрик в хранилище.
 type Sender interface {
+	// Send выполняет отправку набора метрик.
 	Send(ctx context.Context, storage []metric.Metrics) error
+	// Close освобождает ресурсы, связанные с отправкой.
+	Close() error
 }
 
 // generateSendTasks разбивает набор метрик на батчи определенного размера.
-// Передаёт батчи через возвращаемый канал.
+// Каждому батчу присваивается уникальный идентификатор. Передаёт батчи через возвращаемый канал.
 func generateSendTasks(
 	ctx context.Context, storage []metric.Metrics,
 	rateLimit, batchSize uint,
-) chan []metric.Metrics {
-	outCh := make(chan []metric.Metrics, rateLimit)
+) chan metric.Batch {
+	outCh := make(chan metric.Batch, rateLimit)
 
 	go func() {
 		defer close(outCh)
@@ -41,7 +48,7 @@ func generateSendTasks(
 				select {
 				case <-ctx.Done():
 					return
-				case outCh <- batch:
+				case outCh <- metric.NewBatch(batch):
 				}
 
 				batch = make([]metric.Metrics, 0, batchSize)
@@ -52,10 +59,55 @@ func generateSendTasks(
 			select {
 			case <-ctx.Done():
 				return
-			case outCh <- batch:
+			case outCh <- metric.NewBatch(batch):
 			}
 		}
 	}()
 
 	return outCh
 }
+
+// replayBuffer выполняет отправку метрик, накопленных в дисковом буфере.
+// Если отправить накопленные метрики не удалось, новый набор storage также сохраняется в буфер,
+// чтобы метрики поступили на сервер в порядке их сбора. В этом случае возвращается false.
+func replayBuffer(
+	ctx context.Context, buf *buffer.Queue, send buffer.SendFunc,
+	storage []metric.Metrics, batchSize uint, l *log.Logger,
+) bool {
+	if buf == nil {
+		return true
+	}
+
+	err := buf.Replay(ctx, send)
+	if err == nil {
+		return true
+	}
+
+	l.Errorf("error sending buffered metric values: %s", err)
+
+	for start := 0; start < len(storage); start += int(batchSize) {
+		end := start + int(batchSize)
+		if end > len(storage) {
+			end = len(storage)
+		}
+
+		spill(buf, metric.NewBatch(storage[start:end]), l)
+	}
+
+	return false
+}
+
+// spill сохраняет неотправленный набор метрик в дисковый буфер, если он используется.
+func spill(buf *buffer.Queue, batch metric.Batch, l *log.Logger) {
+	if buf == nil {
+		return
+	}
+
+	if err := buf.Push(batch); err != nil {
+		l.Errorf("error buffering metric values: %s", err)
+
+		return
+	}
+
+	l.Debugf("metrics buffered: %d", len(batch.Metrics))
+}
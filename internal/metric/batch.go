@@ -0,0 +1,22 @@
+package metric
+
+import (
+	"github.com/google/uuid"
+)
+
+// BatchIDHeader - заголовок HTTP-запроса и ключ метаданных gRPC, содержащие идентификатор набора метрик.
+const BatchIDHeader = "X-Batch-ID"
+
+// Batch описывает набор метрик, отправляемый агентом на сервер за один запрос.
+//
+// Идентификатор набора сохраняется при повторных отправках, что позволяет серверу
+// не применять один и тот же набор дважды.
+type Batch struct {
+	ID      string    `json:"id"`      // Уникальный идентификатор набора
+	Metrics []Metrics `json:"metrics"` // Метрики набора
+}
+
+// NewBatch создаёт набор метрик со случайным уникальным идентификатором.
+func NewBatch(mtrcs []Metrics) Batch {
+	return Batch{ID: uuid.NewString(), Metrics: mtrcs}
+}
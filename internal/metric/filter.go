@@ -0,0 +1,119 @@
+package metric
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Ограничения размера страницы выборки метрик.
+const (
+	DefaultFilterLimit = 100  // Размер страницы по умолчанию
+	MaxFilterLimit     = 1000 // Максимальный размер страницы
+)
+
+// ErrWrongFilter возвращается, если условия выборки метрик некорректны.
+var ErrWrongFilter = errors.New("wrong metrics filter")
+
+// SortField - поле сортировки метрик при выборке.
+type SortField string
+
+const (
+	SortByName SortField = "name" // По имени, затем по типу и набору меток
+	SortByType SortField = "type" // По типу, затем по имени и набору меток
+)
+
+// Cursor описывает позицию в упорядоченной выборке метрик:
+// следующая страница начинается с метрики, идущей после неё.
+type Cursor struct {
+	Name   string     `json:"n"` // Имя метрики
+	Type   MetricType `json:"t"` // Тип метрики
+	Labels string     `json:"l"` // Ключ набора меток; формат определяется репозиторием
+}
+
+// ParseCursor разбирает курсор, полученный от Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong cursor", ErrWrongFilter)
+	}
+
+	cursor := &Cursor{}
+	if err = json.Unmarshal(data, cursor); err != nil {
+		return nil, fmt.Errorf("%w: wrong cursor", ErrWrongFilter)
+	}
+
+	return cursor, nil
+}
+
+// String возвращает непрозрачное строковое представление курсора для передачи клиенту.
+func (c Cursor) String() string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Compare сравнивает позиции c и other в выборке, упорядоченной по полю sort по возрастанию.
+// Возвращает -1, если c предшествует other, 1, если следует за ним, и 0 при совпадении.
+// Строки сравниваются побайтово.
+func (c Cursor) Compare(other Cursor, sort SortField) int {
+	first, second := [2]string{c.Name, string(c.Type)}, [2]string{other.Name, string(other.Type)}
+	if sort == SortByType {
+		first[0], first[1] = first[1], first[0]
+		second[0], second[1] = second[1], second[0]
+	}
+
+	if res := strings.Compare(first[0], second[0]); res != 0 {
+		return res
+	}
+
+	if res := strings.Compare(first[1], second[1]); res != 0 {
+		return res
+	}
+
+	return strings.Compare(c.Labels, other.Labels)
+}
+
+// Filter описывает условия выборки метрик из хранилища, порядок сортировки и страницу результата.
+type Filter struct {
+	Types      []MetricType // Допустимые типы метрик; пустой набор - любые
+	NamePrefix string       // Префикс имени метрики
+	NameRegexp string       // Регулярное выражение, которому должно соответствовать имя (синтаксис RE2)
+	Labels     Labels       // Метки, которые должен содержать набор меток метрики
+	Sort       SortField    // Поле сортировки; по умолчанию SortByName
+	Desc       bool         // Признак сортировки по убыванию
+	Limit      int          // Размер страницы; по умолчанию DefaultFilterLimit
+	After      *Cursor      // Позиция, после которой начинается страница; nil - с начала выборки
+}
+
+// Validate проверяет корректность условий выборки.
+func (f *Filter) Validate() error {
+	for _, t := range f.Types {
+		if !t.IsValid() {
+			return fmt.Errorf("%w: %s", ErrWrongFilter, ErrWrongMetricType)
+		}
+	}
+
+	if f.NameRegexp != "" {
+		if _, err := regexp.Compile(f.NameRegexp); err != nil {
+			return fmt.Errorf("%w: %s", ErrWrongFilter, err)
+		}
+	}
+
+	if err := f.Labels.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrWrongFilter, err)
+	}
+
+	if f.Sort != "" && f.Sort != SortByName && f.Sort != SortByType {
+		return fmt.Errorf("%w: unknown sort field %q", ErrWrongFilter, f.Sort)
+	}
+
+	if f.Limit < 0 {
+		return fmt.Errorf("%w: negative limit", ErrWrongFilter)
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	cursor := Cursor{Name: "cpu_user", Type: GaugeMetric, Labels: `{host="a"}`}
+
+	parsed, err := ParseCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, *parsed)
+
+	_, err = ParseCursor("not a cursor")
+	assert.ErrorIs(t, err, ErrWrongFilter)
+
+	other := Cursor{Name: "cpu_system", Type: HistogramMetric}
+	assert.Equal(t, 1, cursor.Compare(other, SortByName))
+	assert.Equal(t, -1, cursor.Compare(other, SortByType))
+	assert.Equal(t, 0, cursor.Compare(cursor, SortByType))
+	assert.Equal(t, 1, cursor.Compare(Cursor{Name: "cpu_user", Type: GaugeMetric}, SortByName))
+}
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  Filter
+		wantErr bool
+	}{
+		{
+			name:   "Empty filter",
+			filter: Filter{},
+		},
+		{
+			name: "Correct filter",
+			filter: Filter{
+				Types: []MetricType{GaugeMetric}, NameRegexp: "^cpu_", Labels: Labels{"host": "a"},
+				Sort: SortByType, Limit: 10,
+			},
+		},
+		{
+			name:    "Wrong type",
+			filter:  Filter{Types: []MetricType{"summary"}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong regexp",
+			filter:  Filter{NameRegexp: "("},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong labels",
+			filter:  Filter{Labels: Labels{"": "a"}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong sort field",
+			filter:  Filter{Sort: "value"},
+			wantErr: true,
+		},
+		{
+			name:    "Negative limit",
+			filter:  Filter{Limit: -1},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.filter.Validate()
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongFilter)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
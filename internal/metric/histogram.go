@@ -0,0 +1,178 @@
+package metric
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+)
+
+// DefaultHistogramBounds - границы корзин гистограммы по умолчанию.
+var DefaultHistogramBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram описывает значение метрики типа histogram.
+//
+// Counts содержит количество наблюдений в каждой корзине (не накопительно):
+// Counts[i] - количество значений v, для которых Bounds[i-1] < v <= Bounds[i].
+// Последний элемент Counts соответствует корзине +Inf, поэтому len(Counts) == len(Bounds)+1.
+type Histogram struct {
+	Bounds []float64 `json:"bounds"` // Верхние границы корзин по возрастанию
+	Counts []uint64  `json:"counts"` // Количество наблюдений в корзинах
+	Sum    float64   `json:"sum"`    // Сумма наблюдений
+	Count  uint64    `json:"count"`  // Количество наблюдений
+}
+
+// NewHistogram создаёт пустую гистограмму с переданными границами корзин.
+func NewHistogram(bounds []float64) *Histogram {
+	b := make([]float64, len(bounds))
+	copy(b, bounds)
+
+	return &Histogram{
+		Bounds: b,
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe добавляет наблюдение в гистограмму.
+func (h *Histogram) Observe(v float64) {
+	i := 0
+	for i < len(h.Bounds) && v > h.Bounds[i] {
+		i++
+	}
+
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+// Validate осуществляет валидацию гистограммы.
+func (h *Histogram) Validate() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return ErrWrongMetricValue
+	}
+
+	for i, b := range h.Bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= h.Bounds[i-1]) {
+			return ErrWrongMetricValue
+		}
+	}
+
+	var count uint64
+	for _, c := range h.Counts {
+		count += c
+	}
+
+	if count != h.Count {
+		return ErrWrongMetricValue
+	}
+
+	return nil
+}
+
+// SameBounds проверяет совпадение границ корзин двух гистограмм.
+func (h *Histogram) SameBounds(other *Histogram) bool {
+	if len(h.Bounds) != len(other.Bounds) {
+		return false
+	}
+
+	for i := range h.Bounds {
+		if h.Bounds[i] != other.Bounds[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Merge добавляет к гистограмме наблюдения из other.
+// Гистограммы должны иметь одинаковые границы корзин.
+func (h *Histogram) Merge(other *Histogram) error {
+	if !h.SameBounds(other) {
+		return ErrHistogramBoundsMismatch
+	}
+
+	for i := range h.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+
+	h.Sum += other.Sum
+	h.Count += other.Count
+
+	return nil
+}
+
+// Copy возвращает копию гистограммы.
+func (h *Histogram) Copy() *Histogram {
+	if h == nil {
+		return nil
+	}
+
+	res := &Histogram{
+		Bounds: make([]float64, len(h.Bounds)),
+		Counts: make([]uint64, len(h.Counts)),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+
+	copy(res.Bounds, h.Bounds)
+	copy(res.Counts, h.Counts)
+
+	return res
+}
+
+// String возвращает текстовое представление гистограммы вида
+// "count=5 sum=1.25 buckets={0.1:2 0.5:3 +Inf:0}".
+func (h *Histogram) String() string {
+	builder := strings.Builder{}
+
+	builder.WriteString("count=")
+	builder.WriteString(strconv.FormatUint(h.Count, 10))
+	builder.WriteString(" sum=")
+	builder.WriteString(strconv.FormatFloat(h.Sum, 'f', -1, 64))
+	builder.WriteString(" buckets={")
+
+	for i, c := range h.Counts {
+		if i > 0 {
+			builder.WriteString(" ")
+		}
+
+		if i < len(h.Bounds) {
+			builder.WriteString(strconv.FormatFloat(h.Bounds[i], 'f', -1, 64))
+		} else {
+			builder.WriteString("+Inf")
+		}
+
+		builder.WriteString(":")
+		builder.WriteString(strconv.FormatUint(c, 10))
+	}
+
+	builder.WriteString("}")
+
+	return builder.String()
+}
+
+// HistogramToGRPC преобразует гистограмму в её описание для запроса/ответа gRPC.
+func HistogramToGRPC(h *Histogram) *pb.Histogram {
+	return &pb.Histogram{
+		Bounds: h.Bounds,
+		Counts: h.Counts,
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
+
+// HistogramFromGRPC преобразует описание гистограммы из запроса/ответа gRPC в Histogram.
+// Для отсутствующей гистограммы возвращается nil.
+func HistogramFromGRPC(h *pb.Histogram) *Histogram {
+	if h == nil {
+		return nil
+	}
+
+	return &Histogram{
+		Bounds: h.GetBounds(),
+		Counts: h.GetCounts(),
+		Sum:    h.GetSum(),
+		Count:  h.GetCount(),
+	}
+}
@@ -0,0 +1,103 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := NewHistogram([]float64{0.1, 1})
+
+	h.Observe(0.05)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(5)
+
+	assert.Equal(t, []uint64{2, 1, 1}, h.Counts)
+	assert.EqualValues(t, 4, h.Count)
+	assert.InDelta(t, 5.65, h.Sum, 1e-9)
+	assert.NoError(t, h.Validate())
+	assert.Equal(t, "count=4 sum=5.65 buckets={0.1:2 1:1 +Inf:1}", h.String())
+}
+
+func TestHistogramValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		h       Histogram
+		wantErr bool
+	}{
+		{
+			name: "Correct histogram",
+			h:    Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Sum: 10, Count: 3},
+		},
+		{
+			name: "Without bounds",
+			h:    Histogram{Counts: []uint64{3}, Sum: 10, Count: 3},
+		},
+		{
+			name:    "Wrong counts length",
+			h:       Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 2}, Count: 3},
+			wantErr: true,
+		},
+		{
+			name:    "Unsorted bounds",
+			h:       Histogram{Bounds: []float64{2, 1}, Counts: []uint64{0, 0, 0}},
+			wantErr: true,
+		},
+		{
+			name:    "Count mismatch",
+			h:       Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 3},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.h.Validate()
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongMetricValue)
+
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	h := &Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 2}, Sum: 10, Count: 3}
+
+	err := h.Merge(&Histogram{Bounds: []float64{1, 2}, Counts: []uint64{0, 1, 0}, Sum: 1.5, Count: 1})
+	require.NoError(t, err)
+	assert.Equal(t, &Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 1, 2}, Sum: 11.5, Count: 4}, h)
+
+	err = h.Merge(&Histogram{Bounds: []float64{1}, Counts: []uint64{0, 1}, Sum: 1.5, Count: 1})
+	assert.ErrorIs(t, err, ErrHistogramBoundsMismatch)
+	assert.ErrorIs(t, err, ErrWrongMetricValue)
+}
+
+func TestNewMetricsHistogram(t *testing.T) {
+	mtrc, err := NewMetrics("Latency", HistogramMetric, 0.3)
+	require.NoError(t, err)
+	require.NotNil(t, mtrc.Histogram)
+	assert.Equal(t, DefaultHistogramBounds, mtrc.Histogram.Bounds)
+	assert.EqualValues(t, 1, mtrc.Histogram.Count)
+	assert.NoError(t, mtrc.Validate())
+
+	h := NewHistogram([]float64{1})
+
+	mtrc, err = NewMetrics("Latency", "", h)
+	require.NoError(t, err)
+	assert.Equal(t, HistogramMetric, mtrc.MType)
+	assert.Equal(t, h, mtrc.Histogram)
+	assert.NotSame(t, h, mtrc.Histogram)
+
+	_, err = NewMetrics("Latency", HistogramMetric, "0.3")
+	assert.ErrorIs(t, err, ErrWrongMetricValue)
+
+	mtrc = Metrics{ID: "Latency", MType: HistogramMetric}
+	assert.ErrorIs(t, mtrc.Validate(), ErrWrongMetricValue)
+}
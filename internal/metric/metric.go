@@ -3,6 +3,10 @@ package metric
 
 import (
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	pb "github.com/KryukovO/metricscollector/api/serverpb"
 )
@@ -14,40 +18,173 @@ var (
 	ErrWrongMetricName = errors.New("wrong metric name")
 	// ErrWrongMetricType возвращается, если тип значения метрики не соответствует её типу.
 	ErrWrongMetricValue = errors.New("wrong metric value")
+	// ErrWrongMetricLabels возвращается, если набор меток метрики некорректен.
+	ErrWrongMetricLabels = errors.New("wrong metric labels")
+	// ErrHistogramBoundsMismatch возвращается при попытке объединить гистограммы с разными границами корзин.
+	ErrHistogramBoundsMismatch = fmt.Errorf("%w: histogram bounds mismatch", ErrWrongMetricValue)
 )
 
 // MetricType - тип метрики.
 type MetricType string
 
 const (
-	GaugeMetric   MetricType = "gauge"
-	CounterMetric MetricType = "counter"
+	GaugeMetric     MetricType = "gauge"
+	CounterMetric   MetricType = "counter"
+	HistogramMetric MetricType = "histogram"
 )
 
+// IsValid проверяет, является ли тип метрики допустимым.
+func (t MetricType) IsValid() bool {
+	return t == GaugeMetric || t == CounterMetric || t == HistogramMetric
+}
+
 // MapGRPCToMetricType - маппинг типа метрики в запросе/ответе gRPC в MetricType.
 var MapGRPCToMetricType = map[pb.MetricType]MetricType{
-	pb.MetricType_COUNTER: CounterMetric,
-	pb.MetricType_GAUGE:   GaugeMetric,
+	pb.MetricType_COUNTER:   CounterMetric,
+	pb.MetricType_GAUGE:     GaugeMetric,
+	pb.MetricType_HISTOGRAM: HistogramMetric,
 }
 
 // MapMetricTypeToGRPC - маппинг MetricType в тип метрики в запросе/ответе gRPC.
 var MapMetricTypeToGRPC = map[MetricType]pb.MetricType{
-	CounterMetric: pb.MetricType_COUNTER,
-	GaugeMetric:   pb.MetricType_GAUGE,
+	CounterMetric:   pb.MetricType_COUNTER,
+	GaugeMetric:     pb.MetricType_GAUGE,
+	HistogramMetric: pb.MetricType_HISTOGRAM,
+}
+
+// Labels - набор меток метрики (например, host, service, env).
+//
+// Метки входят в идентификатор метрики: метрики с одинаковыми именем и типом,
+// но разными наборами меток хранятся раздельно.
+type Labels map[string]string
+
+// ParseLabels разбирает набор меток из строки вида "name1=value1,name2=value2".
+func ParseLabels(s string) (Labels, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	labels := make(Labels)
+
+	for _, pair := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrWrongMetricLabels, pair)
+		}
+
+		labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// Validate осуществляет валидацию набора меток.
+func (l Labels) Validate() error {
+	for name := range l {
+		if name == "" {
+			return ErrWrongMetricLabels
+		}
+	}
+
+	return nil
+}
+
+// Equal проверяет совпадение наборов меток. Пустой набор и nil считаются равными.
+func (l Labels) Equal(other Labels) bool {
+	if len(l) != len(other) {
+		return false
+	}
+
+	for name, value := range l {
+		if v, ok := other[name]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Match проверяет, содержит ли набор меток все метки из selector.
+func (l Labels) Match(selector Labels) bool {
+	for name, value := range selector {
+		if v, ok := l[name]; !ok || v != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Names возвращает отсортированный список имён меток.
+func (l Labels) Names() []string {
+	names := make([]string, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Copy возвращает копию набора меток.
+func (l Labels) Copy() Labels {
+	if l == nil {
+		return nil
+	}
+
+	res := make(Labels, len(l))
+	for name, value := range l {
+		res[name] = value
+	}
+
+	return res
+}
+
+// String возвращает каноническое представление набора меток вида {name1="value1",name2="value2"}.
+// Для пустого набора возвращается пустая строка.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(l))
+	for _, name := range l.Names() {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", name, l[name]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
 }
 
 // Metrics описывает структуру метрики.
 type Metrics struct {
-	ID    string     `json:"id"`              // Имя метрики
-	MType MetricType `json:"type"`            // Тип метрики (gauge или counter)
-	Delta *int64     `json:"delta,omitempty"` // Значение метрики в случае передачи counter
-	Value *float64   `json:"value,omitempty"` // Значение метрики в случае передачи gauge
+	ID        string     `json:"id"`                  // Имя метрики
+	MType     MetricType `json:"type"`                // Тип метрики (gauge, counter или histogram)
+	Delta     *int64     `json:"delta,omitempty"`     // Значение метрики в случае передачи counter
+	Value     *float64   `json:"value,omitempty"`     // Значение метрики в случае передачи gauge
+	Histogram *Histogram `json:"histogram,omitempty"` // Значение метрики в случае передачи histogram
+	Labels    Labels     `json:"labels,omitempty"`    // Набор меток метрики
+}
+
+// Sample описывает значение метрики, принятое сервером в определённый момент времени.
+//
+// Для counter поле Delta содержит принятое приращение, а не накопленное значение.
+type Sample struct {
+	Metrics
+	Timestamp time.Time `json:"timestamp"` // Время получения значения сервером
 }
 
 // NewMetrics создает структуру метрики.
 //
 // Если параметр mType не заполнен, тип метрики определяется по переданному значению value:
-// float64 => gauge; int64 => counter.
+// float64 => gauge; int64 => counter; Histogram или *Histogram => histogram.
+//
+// Для histogram в качестве value также может быть передано единичное наблюдение (float64 или int64),
+// в этом случае создаётся гистограмма с границами корзин DefaultHistogramBounds.
 func NewMetrics(mName string, mType MetricType, value interface{}) (Metrics, error) {
 	if mName == "" {
 		return Metrics{}, ErrWrongMetricName
@@ -85,6 +222,17 @@ func NewMetrics(mName string, mType MetricType, value interface{}) (Metrics, err
 			MType: GaugeMetric,
 			Value: &vf,
 		}, nil
+	case HistogramMetric:
+		h, err := histogramFromValue(value)
+		if err != nil {
+			return Metrics{}, err
+		}
+
+		return Metrics{
+			ID:        mName,
+			MType:     HistogramMetric,
+			Histogram: h,
+		}, nil
 	case "":
 		return newMetricsByValue(mName, value)
 	default:
@@ -106,17 +254,58 @@ func newMetricsByValue(mName string, value interface{}) (Metrics, error) {
 			MType: GaugeMetric,
 			Value: &v,
 		}, nil
+	case Histogram, *Histogram:
+		h, err := histogramFromValue(v)
+		if err != nil {
+			return Metrics{}, err
+		}
+
+		return Metrics{
+			ID:        mName,
+			MType:     HistogramMetric,
+			Histogram: h,
+		}, nil
 	default:
 		return Metrics{}, ErrWrongMetricValue
 	}
 }
 
+// histogramFromValue создаёт гистограмму по значению, переданному в NewMetrics.
+func histogramFromValue(value interface{}) (*Histogram, error) {
+	switch v := value.(type) {
+	case Histogram:
+		return v.Copy(), nil
+	case *Histogram:
+		if v == nil {
+			return nil, ErrWrongMetricValue
+		}
+
+		return v.Copy(), nil
+	case float64:
+		h := NewHistogram(DefaultHistogramBounds)
+		h.Observe(v)
+
+		return h, nil
+	case int64:
+		h := NewHistogram(DefaultHistogramBounds)
+		h.Observe(float64(v))
+
+		return h, nil
+	default:
+		return nil, ErrWrongMetricValue
+	}
+}
+
 // Validate осуществляет валидацию метрики.
 func (mtrc *Metrics) Validate() error {
 	if mtrc.ID == "" {
 		return ErrWrongMetricName
 	}
 
+	if err := mtrc.Labels.Validate(); err != nil {
+		return err
+	}
+
 	switch mtrc.MType {
 	case CounterMetric:
 		if mtrc.Delta == nil {
@@ -126,6 +315,12 @@ func (mtrc *Metrics) Validate() error {
 		if mtrc.Value == nil {
 			return ErrWrongMetricValue
 		}
+	case HistogramMetric:
+		if mtrc.Histogram == nil {
+			return ErrWrongMetricValue
+		}
+
+		return mtrc.Histogram.Validate()
 	default:
 		return ErrWrongMetricType
 	}
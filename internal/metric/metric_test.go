@@ -0,0 +1,66 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg      string
+		expected Labels
+		wantErr  bool
+	}{
+		{
+			name:     "Empty string",
+			arg:      "",
+			expected: nil,
+		},
+		{
+			name:     "Correct labels",
+			arg:      "host=a, env = prod",
+			expected: Labels{"host": "a", "env": "prod"},
+		},
+		{
+			name:    "Missing value separator",
+			arg:     "host",
+			wantErr: true,
+		},
+		{
+			name:    "Empty label name",
+			arg:     "=a",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			labels, err := ParseLabels(test.arg)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongMetricLabels)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, labels)
+		})
+	}
+}
+
+func TestLabels(t *testing.T) {
+	labels := Labels{"host": "a", "env": "prod"}
+
+	assert.True(t, labels.Equal(Labels{"env": "prod", "host": "a"}))
+	assert.False(t, labels.Equal(Labels{"host": "a"}))
+	assert.True(t, Labels(nil).Equal(Labels{}))
+
+	assert.True(t, labels.Match(Labels{"host": "a"}))
+	assert.True(t, labels.Match(nil))
+	assert.False(t, labels.Match(Labels{"host": "b"}))
+
+	assert.Equal(t, `{env="prod",host="a"}`, labels.String())
+	assert.Equal(t, "", Labels(nil).String())
+}
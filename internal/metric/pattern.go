@@ -0,0 +1,40 @@
+package metric
+
+import "errors"
+
+// ErrWrongNamePattern возвращается, если шаблон имени метрики пуст.
+var ErrWrongNamePattern = errors.New("wrong metric name pattern")
+
+// MatchName проверяет соответствие имени метрики name шаблону pattern.
+// Символ '*' в шаблоне соответствует любой последовательности символов (в том числе пустой),
+// символ '?' - любому одиночному символу; остальные символы сравниваются как есть.
+func MatchName(pattern, name string) bool {
+	var (
+		p, n       = []rune(pattern), []rune(name)
+		pi, ni     int
+		star, back = -1, 0
+	)
+
+	for ni < len(n) {
+		switch {
+		case pi < len(p) && p[pi] == '*':
+			star, back = pi, ni
+			pi++
+		case pi < len(p) && (p[pi] == '?' || p[pi] == n[ni]):
+			pi++
+			ni++
+		case star >= 0:
+			// Символ '*' поглощает ещё один символ имени
+			back++
+			pi, ni = star+1, back
+		default:
+			return false
+		}
+	}
+
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+
+	return pi == len(p)
+}
@@ -0,0 +1,39 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchName(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{pattern: "Alloc", name: "Alloc", expected: true},
+		{pattern: "Alloc", name: "TotalAlloc", expected: false},
+		{pattern: "cpu_*", name: "cpu_user", expected: true},
+		{pattern: "cpu_*", name: "cpu_", expected: true},
+		{pattern: "cpu_*", name: "disk_used", expected: false},
+		{pattern: "*Alloc", name: "TotalAlloc", expected: true},
+		{pattern: "*_used_*", name: "disk_used_bytes", expected: true},
+		{pattern: "*_used_*", name: "disk_free_bytes", expected: false},
+		{pattern: "CPUutilization?", name: "CPUutilization1", expected: true},
+		{pattern: "CPUutilization?", name: "CPUutilization12", expected: false},
+		{pattern: "*", name: "", expected: true},
+		{pattern: "a*b*c", name: "abxbc", expected: true},
+		{pattern: "a*b*c", name: "abxbd", expected: false},
+		{pattern: "*", name: "*x", expected: true},
+		{pattern: "a*", name: "a*b", expected: true},
+		{pattern: "*b", name: "*ab", expected: true},
+		{pattern: "a*c", name: "a*b", expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern+" "+test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, MatchName(test.pattern, test.name))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package metric
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// MaxRangePoints - максимальное количество шагов в запросе значений метрики за интервал времени.
+const MaxRangePoints = 11000
+
+// ErrWrongRangeQuery возвращается, если запрос значений метрики за интервал времени некорректен.
+var ErrWrongRangeQuery = errors.New("wrong range query")
+
+// Aggregation - функция агрегации значений метрики, принятых на одном шаге интервала.
+type Aggregation string
+
+const (
+	AggregationAvg  Aggregation = "avg"  // Среднее значение
+	AggregationMin  Aggregation = "min"  // Минимальное значение
+	AggregationMax  Aggregation = "max"  // Максимальное значение
+	AggregationSum  Aggregation = "sum"  // Сумма значений
+	AggregationRate Aggregation = "rate" // Сумма приращений counter в секунду
+	AggregationLast Aggregation = "last" // Последнее значение
+)
+
+// IsValid проверяет, является ли функция агрегации допустимой.
+func (a Aggregation) IsValid() bool {
+	switch a {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationSum, AggregationRate, AggregationLast:
+		return true
+	}
+
+	return false
+}
+
+// Apply вычисляет значение функции агрегации для непустого набора значений values,
+// упорядоченного по времени получения и принятого на шаге длительностью step.
+func (a Aggregation) Apply(values []float64, step time.Duration) float64 {
+	switch a {
+	case AggregationMin, AggregationMax:
+		res := values[0]
+
+		for _, v := range values[1:] {
+			if a == AggregationMin {
+				res = math.Min(res, v)
+			} else {
+				res = math.Max(res, v)
+			}
+		}
+
+		return res
+
+	case AggregationLast:
+		return values[len(values)-1]
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	switch a {
+	case AggregationAvg:
+		return sum / float64(len(values))
+	case AggregationRate:
+		return sum / step.Seconds()
+	default:
+		return sum
+	}
+}
+
+// RangeQuery описывает запрос значений метрики, принятых в интервале времени [From, To],
+// агрегированных на шагах длительностью Step.
+//
+// Шаги отсчитываются от From; значение шага относится к моменту его начала.
+// Для counter агрегируются принятые приращения, для gauge - принятые значения.
+type RangeQuery struct {
+	MType       MetricType    // Тип метрики (counter или gauge)
+	Name        string        // Имя метрики
+	Labels      Labels        // Набор меток метрики
+	From        time.Time     // Начало интервала
+	To          time.Time     // Окончание интервала
+	Step        time.Duration // Длительность шага
+	Aggregation Aggregation   // Функция агрегации значений на шаге
+}
+
+// Validate проверяет корректность запроса.
+func (q *RangeQuery) Validate() error {
+	if q.MType != CounterMetric && q.MType != GaugeMetric {
+		return fmt.Errorf("%w: only counter and gauge metrics are supported", ErrWrongRangeQuery)
+	}
+
+	if q.Name == "" {
+		return fmt.Errorf("%w: %s", ErrWrongRangeQuery, ErrWrongMetricName)
+	}
+
+	if err := q.Labels.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrWrongRangeQuery, err)
+	}
+
+	if q.To.Before(q.From) {
+		return fmt.Errorf("%w: invalid time range", ErrWrongRangeQuery)
+	}
+
+	if q.Step <= 0 {
+		return fmt.Errorf("%w: non-positive step", ErrWrongRangeQuery)
+	}
+
+	if q.To.Sub(q.From)/q.Step >= MaxRangePoints {
+		return fmt.Errorf("%w: too many points, increase step", ErrWrongRangeQuery)
+	}
+
+	if !q.Aggregation.IsValid() {
+		return fmt.Errorf("%w: unknown aggregation %q", ErrWrongRangeQuery, q.Aggregation)
+	}
+
+	if q.Aggregation == AggregationRate && q.MType != CounterMetric {
+		return fmt.Errorf("%w: rate is supported only for counters", ErrWrongRangeQuery)
+	}
+
+	return nil
+}
+
+// Point описывает агрегированное значение метрики на шаге интервала.
+type Point struct {
+	Timestamp time.Time `json:"timestamp"` // Начало шага
+	Value     float64   `json:"value"`     // Агрегированное значение
+}
@@ -0,0 +1,98 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregationApply(t *testing.T) {
+	values := []float64{4, 1, 7, 2}
+
+	tests := []struct {
+		aggregation Aggregation
+		expected    float64
+	}{
+		{aggregation: AggregationAvg, expected: 3.5},
+		{aggregation: AggregationMin, expected: 1},
+		{aggregation: AggregationMax, expected: 7},
+		{aggregation: AggregationSum, expected: 14},
+		{aggregation: AggregationRate, expected: 0.7},
+		{aggregation: AggregationLast, expected: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.aggregation), func(t *testing.T) {
+			assert.InDelta(t, test.expected, test.aggregation.Apply(values, 20*time.Second), 1e-9)
+		})
+	}
+}
+
+func TestRangeQueryValidate(t *testing.T) {
+	to := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	valid := RangeQuery{
+		MType: CounterMetric, Name: "PollCount", From: to.Add(-time.Hour), To: to,
+		Step: time.Minute, Aggregation: AggregationRate,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(q *RangeQuery)
+		wantErr bool
+	}{
+		{
+			name:   "Correct query",
+			modify: func(q *RangeQuery) {},
+		},
+		{
+			name:    "Histogram",
+			modify:  func(q *RangeQuery) { q.MType = HistogramMetric },
+			wantErr: true,
+		},
+		{
+			name:    "Empty name",
+			modify:  func(q *RangeQuery) { q.Name = "" },
+			wantErr: true,
+		},
+		{
+			name:    "Invalid time range",
+			modify:  func(q *RangeQuery) { q.From = q.To.Add(time.Second) },
+			wantErr: true,
+		},
+		{
+			name:    "Zero step",
+			modify:  func(q *RangeQuery) { q.Step = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Too many points",
+			modify:  func(q *RangeQuery) { q.Step = time.Millisecond },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown aggregation",
+			modify:  func(q *RangeQuery) { q.Aggregation = "median" },
+			wantErr: true,
+		},
+		{
+			name:    "Rate of gauge",
+			modify:  func(q *RangeQuery) { q.MType = GaugeMetric },
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := valid
+			test.modify(&q)
+
+			err := q.Validate()
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongRangeQuery)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
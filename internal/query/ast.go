@@ -0,0 +1,153 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr - узел синтаксического дерева выражения.
+type Expr interface {
+	// String возвращает каноническую запись выражения.
+	String() string
+}
+
+// NumberLiteral - числовая константа.
+type NumberLiteral struct {
+	Value float64
+}
+
+// String возвращает каноническую запись выражения.
+func (n *NumberLiteral) String() string {
+	return strconv.FormatFloat(n.Value, 'g', -1, 64)
+}
+
+// LabelMatcher - условие на значение метки в селекторе.
+type LabelMatcher struct {
+	Name     string // Имя метки
+	Value    string // Значение метки
+	Negative bool   // Признак условия "!=" вместо "="
+}
+
+// String возвращает каноническую запись условия.
+func (m *LabelMatcher) String() string {
+	op := "="
+	if m.Negative {
+		op = "!="
+	}
+
+	return m.Name + op + strconv.Quote(m.Value)
+}
+
+// Matches проверяет, удовлетворяет ли значение метки value условию.
+// Отсутствующая метка считается меткой с пустым значением.
+func (m *LabelMatcher) Matches(value string) bool {
+	return (value == m.Value) != m.Negative
+}
+
+// VectorSelector - выборка метрик counter и gauge по имени и условиям на метки.
+//
+// Если задана длительность Range, селектор выбирает значения метрик, принятые за Range
+// до момента вычисления, и допустим только в качестве аргумента функций.
+type VectorSelector struct {
+	Name     string         // Имя метрики
+	Matchers []LabelMatcher // Условия на метки
+	Range    time.Duration  // Длительность интервала выборки
+}
+
+// String возвращает каноническую запись выражения.
+func (s *VectorSelector) String() string {
+	var b strings.Builder
+
+	b.WriteString(s.Name)
+
+	if len(s.Matchers) > 0 {
+		matchers := make([]string, 0, len(s.Matchers))
+		for i := range s.Matchers {
+			matchers = append(matchers, s.Matchers[i].String())
+		}
+
+		b.WriteString("{" + strings.Join(matchers, ",") + "}")
+	}
+
+	if s.Range > 0 {
+		b.WriteString("[" + formatDuration(s.Range) + "]")
+	}
+
+	return b.String()
+}
+
+// formatDuration возвращает запись длительности d без нулевых младших единиц: 5m вместо 5m0s.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+
+	return s
+}
+
+// Call - вызов функции.
+type Call struct {
+	Func string // Имя функции
+	Arg  Expr   // Аргумент функции
+}
+
+// String возвращает каноническую запись выражения.
+func (c *Call) String() string {
+	return c.Func + "(" + c.Arg.String() + ")"
+}
+
+// AggregateExpr - агрегация значений вектора по группам с одинаковыми значениями меток By.
+type AggregateExpr struct {
+	Op   string   // Оператор агрегации
+	By   []string // Имена меток, по которым выполняется группировка
+	Expr Expr     // Агрегируемое выражение
+}
+
+// String возвращает каноническую запись выражения.
+func (a *AggregateExpr) String() string {
+	if len(a.By) == 0 {
+		return a.Op + "(" + a.Expr.String() + ")"
+	}
+
+	return a.Op + " by (" + strings.Join(a.By, ",") + ") (" + a.Expr.String() + ")"
+}
+
+// UnaryExpr - смена знака выражения.
+type UnaryExpr struct {
+	Expr Expr
+}
+
+// String возвращает каноническую запись выражения.
+func (u *UnaryExpr) String() string {
+	return "-" + u.Expr.String()
+}
+
+// BinaryExpr - арифметическая операция или сравнение.
+type BinaryExpr struct {
+	Op  string // Оператор
+	LHS Expr   // Левый операнд
+	RHS Expr   // Правый операнд
+}
+
+// String возвращает каноническую запись выражения. Бинарные выражения всегда заключаются в скобки,
+// что делает порядок вычисления явным.
+func (b *BinaryExpr) String() string {
+	return "(" + b.LHS.String() + " " + b.Op + " " + b.RHS.String() + ")"
+}
+
+// isComparison проверяет, является ли оператор op оператором сравнения.
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", ">", "<", ">=", "<=":
+		return true
+	}
+
+	return false
+}
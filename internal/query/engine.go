@@ -0,0 +1,428 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+)
+
+// ErrEvaluation возвращается, если корректное синтаксически выражение не может быть вычислено.
+var ErrEvaluation = errors.New("evaluation error")
+
+// Engine вычисляет выражения по данным хранилища.
+type Engine struct {
+	storage storage.Storage
+}
+
+// NewEngine создаёт новый вычислитель выражений над хранилищем s.
+func NewEngine(s storage.Storage) *Engine {
+	return &Engine{storage: s}
+}
+
+// Query разбирает выражение input и вычисляет его на момент времени ts.
+func (e *Engine) Query(ctx context.Context, input string, ts time.Time) (Value, error) {
+	expr, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.Eval(ctx, expr, ts)
+}
+
+// Eval вычисляет выражение expr на момент времени ts.
+//
+// Селекторы без интервала возвращают текущие значения метрик: накопленное значение для counter
+// и последнее значение для gauge. Значения вектора-результата упорядочены по имени и набору меток;
+// значения, не являющиеся конечными числами (например, результат деления на ноль), в него не включаются.
+func (e *Engine) Eval(ctx context.Context, expr Expr, ts time.Time) (Value, error) {
+	v, err := e.eval(ctx, expr, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	switch res := v.(type) {
+	case Scalar:
+		if math.IsNaN(float64(res)) || math.IsInf(float64(res), 0) {
+			return nil, fmt.Errorf("%w: result is not a finite number", ErrEvaluation)
+		}
+
+	case Vector:
+		finite := res[:0]
+
+		for _, s := range res {
+			if !math.IsNaN(s.Value) && !math.IsInf(s.Value, 0) {
+				finite = append(finite, s)
+			}
+		}
+
+		finite.sort()
+
+		return finite, nil
+	}
+
+	return v, nil
+}
+
+// eval рекурсивно вычисляет выражение expr.
+func (e *Engine) eval(ctx context.Context, expr Expr, ts time.Time) (Value, error) {
+	switch ex := expr.(type) {
+	case *NumberLiteral:
+		return Scalar(ex.Value), nil
+
+	case *VectorSelector:
+		return e.evalSelector(ctx, ex)
+
+	case *Call:
+		return e.evalCall(ctx, ex, ts)
+
+	case *AggregateExpr:
+		return e.evalAggregate(ctx, ex, ts)
+
+	case *UnaryExpr:
+		v, err := e.eval(ctx, ex.Expr, ts)
+		if err != nil {
+			return nil, err
+		}
+
+		return negate(v), nil
+
+	case *BinaryExpr:
+		lhs, err := e.eval(ctx, ex.LHS, ts)
+		if err != nil {
+			return nil, err
+		}
+
+		rhs, err := e.eval(ctx, ex.RHS, ts)
+		if err != nil {
+			return nil, err
+		}
+
+		return evalBinary(ex.Op, lhs, rhs)
+	}
+
+	return nil, fmt.Errorf("%w: unsupported expression %s", ErrEvaluation, expr)
+}
+
+// selectMetrics возвращает метрики counter и gauge, соответствующие селектору sel.
+//
+// Из хранилища запрашиваются метрики, содержащие метки из условий "=" с непустым значением;
+// остальные условия проверяются по полученным метрикам.
+func (e *Engine) selectMetrics(ctx context.Context, sel *VectorSelector) ([]metric.Metrics, error) {
+	labels := make(metric.Labels)
+
+	for _, m := range sel.Matchers {
+		if !m.Negative && m.Value != "" {
+			labels[m.Name] = m.Value
+		}
+	}
+
+	mtrcs, err := e.storage.GetByLabels(ctx, labels)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]metric.Metrics, 0)
+
+	for _, mtrc := range mtrcs {
+		if mtrc.ID != sel.Name || mtrc.MType == metric.HistogramMetric {
+			continue
+		}
+
+		matched := true
+
+		for i := range sel.Matchers {
+			if !sel.Matchers[i].Matches(mtrc.Labels[sel.Matchers[i].Name]) {
+				matched = false
+
+				break
+			}
+		}
+
+		if matched {
+			res = append(res, mtrc)
+		}
+	}
+
+	return res, nil
+}
+
+// evalSelector возвращает текущие значения метрик, соответствующих селектору sel.
+func (e *Engine) evalSelector(ctx context.Context, sel *VectorSelector) (Value, error) {
+	mtrcs, err := e.selectMetrics(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(Vector, 0, len(mtrcs))
+
+	for _, mtrc := range mtrcs {
+		s := Sample{Name: mtrc.ID, Labels: mtrc.Labels.Copy()}
+
+		switch {
+		case mtrc.MType == metric.CounterMetric && mtrc.Delta != nil:
+			s.Value = float64(*mtrc.Delta)
+		case mtrc.MType == metric.GaugeMetric && mtrc.Value != nil:
+			s.Value = *mtrc.Value
+		default:
+			continue
+		}
+
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
+// evalCall вычисляет вызов функции.
+//
+// rate(x[d]) возвращает для каждой метрики counter, выбранной селектором, сумму приращений,
+// принятых за интервал (ts-d, ts], в пересчёте на секунду. Метрики gauge функцией пропускаются.
+func (e *Engine) evalCall(ctx context.Context, call *Call, ts time.Time) (Value, error) {
+	sel, ok := call.Arg.(*VectorSelector)
+	if !ok || sel.Range == 0 || call.Func != "rate" {
+		return nil, fmt.Errorf("%w: unsupported call %s", ErrEvaluation, call)
+	}
+
+	mtrcs, err := e.selectMetrics(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(Vector, 0, len(mtrcs))
+
+	for _, mtrc := range mtrcs {
+		if mtrc.MType != metric.CounterMetric {
+			continue
+		}
+
+		samples, err := e.storage.GetHistory(ctx, mtrc.MType, mtrc.ID, mtrc.Labels, ts.Add(-sel.Range), ts)
+		if err != nil {
+			return nil, err
+		}
+
+		var sum float64
+
+		for _, sample := range samples {
+			if sample.Delta != nil && sample.Timestamp.After(ts.Add(-sel.Range)) {
+				sum += float64(*sample.Delta)
+			}
+		}
+
+		res = append(res, Sample{Labels: mtrc.Labels.Copy(), Value: sum / sel.Range.Seconds()})
+	}
+
+	return res, nil
+}
+
+// evalAggregate вычисляет агрегацию значений вектора по группам.
+func (e *Engine) evalAggregate(ctx context.Context, agg *AggregateExpr, ts time.Time) (Value, error) {
+	v, err := e.eval(ctx, agg.Expr, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	vec, ok := v.(Vector)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s() expects a vector argument", ErrEvaluation, agg.Op)
+	}
+
+	type group struct {
+		labels metric.Labels
+		values []float64
+	}
+
+	var (
+		groups = make(map[string]*group)
+		order  = make([]string, 0)
+	)
+
+	for _, s := range vec {
+		var labels metric.Labels
+
+		for _, name := range agg.By {
+			if value, ok := s.Labels[name]; ok {
+				if labels == nil {
+					labels = make(metric.Labels)
+				}
+
+				labels[name] = value
+			}
+		}
+
+		key := labels.String()
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: labels}
+			groups[key] = g
+			order = append(order, key)
+		}
+
+		g.values = append(g.values, s.Value)
+	}
+
+	res := make(Vector, 0, len(groups))
+	for _, key := range order {
+		res = append(res, Sample{Labels: groups[key].labels, Value: aggregate(agg.Op, groups[key].values)})
+	}
+
+	return res, nil
+}
+
+// aggregate вычисляет значение оператора агрегации op для непустого набора значений values.
+func aggregate(op string, values []float64) float64 {
+	switch op {
+	case "count":
+		return float64(len(values))
+	case "min":
+		return metric.AggregationMin.Apply(values, 0)
+	case "max":
+		return metric.AggregationMax.Apply(values, 0)
+	case "avg":
+		return metric.AggregationAvg.Apply(values, 0)
+	default:
+		return metric.AggregationSum.Apply(values, 0)
+	}
+}
+
+// negate возвращает результат смены знака значения v.
+func negate(v Value) Value {
+	if s, ok := v.(Scalar); ok {
+		return -s
+	}
+
+	vec := v.(Vector)
+
+	res := make(Vector, 0, len(vec))
+	for _, s := range vec {
+		res = append(res, Sample{Labels: s.Labels, Value: -s.Value})
+	}
+
+	return res
+}
+
+// evalBinary вычисляет бинарную операцию op над операндами lhs и rhs.
+//
+// Арифметическая операция над векторами выполняется для пар значений с одинаковыми наборами меток;
+// значения без пары отбрасываются. Сравнение с участием вектора оставляет в векторе только значения,
+// для которых оно истинно; сравнение двух чисел возвращает 1 или 0.
+func evalBinary(op string, lhs, rhs Value) (Value, error) {
+	ls, lok := lhs.(Scalar)
+	rs, rok := rhs.(Scalar)
+
+	switch {
+	case lok && rok:
+		return Scalar(apply(op, float64(ls), float64(rs))), nil
+
+	case rok:
+		return vectorScalar(op, lhs.(Vector), func(v float64) float64 { return apply(op, v, float64(rs)) }), nil
+
+	case lok:
+		return vectorScalar(op, rhs.(Vector), func(v float64) float64 { return apply(op, float64(ls), v) }), nil
+	}
+
+	return vectorVector(op, lhs.(Vector), rhs.(Vector))
+}
+
+// vectorScalar применяет операцию op к каждому значению вектора vec,
+// где fn вычисляет операцию для значения вектора и числового операнда.
+func vectorScalar(op string, vec Vector, fn func(float64) float64) Vector {
+	res := make(Vector, 0, len(vec))
+
+	for _, s := range vec {
+		v := fn(s.Value)
+
+		if !isComparison(op) {
+			res = append(res, Sample{Labels: s.Labels, Value: v})
+		} else if v == 1 {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// vectorVector применяет операцию op к парам значений векторов lhs и rhs с одинаковыми наборами меток.
+func vectorVector(op string, lhs, rhs Vector) (Vector, error) {
+	right := make(map[string]Sample, len(rhs))
+
+	for _, s := range rhs {
+		key := s.Labels.String()
+		if _, ok := right[key]; ok {
+			return nil, fmt.Errorf("%w: duplicate series %s on the right side of %q", ErrEvaluation, s.id(), op)
+		}
+
+		right[key] = s
+	}
+
+	var (
+		res  = make(Vector, 0, len(lhs))
+		seen = make(map[string]bool, len(lhs))
+	)
+
+	for _, s := range lhs {
+		key := s.Labels.String()
+		if seen[key] {
+			return nil, fmt.Errorf("%w: duplicate series %s on the left side of %q", ErrEvaluation, s.id(), op)
+		}
+
+		seen[key] = true
+
+		r, ok := right[key]
+		if !ok {
+			continue
+		}
+
+		v := apply(op, s.Value, r.Value)
+
+		if !isComparison(op) {
+			res = append(res, Sample{Labels: s.Labels, Value: v})
+		} else if v == 1 {
+			res = append(res, s)
+		}
+	}
+
+	return res, nil
+}
+
+// apply вычисляет операцию op над числами a и b. Результат сравнения - 1 или 0.
+func apply(op string, a, b float64) float64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	case "/":
+		return a / b
+	}
+
+	var res bool
+
+	switch op {
+	case "==":
+		res = a == b
+	case "!=":
+		res = a != b
+	case ">":
+		res = a > b
+	case "<":
+		res = a < b
+	case ">=":
+		res = a >= b
+	case "<=":
+		res = a <= b
+	}
+
+	if res {
+		return 1
+	}
+
+	return 0
+}
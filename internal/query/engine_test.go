@@ -0,0 +1,130 @@
+package query
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T) *Engine {
+	t.Helper()
+
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	gauges := []struct {
+		name   string
+		labels metric.Labels
+		value  float64
+	}{
+		{name: "HeapAlloc", labels: metric.Labels{"host": "a"}, value: 50},
+		{name: "HeapAlloc", labels: metric.Labels{"host": "b"}, value: 30},
+		{name: "HeapSys", labels: metric.Labels{"host": "a"}, value: 100},
+		{name: "HeapSys", labels: metric.Labels{"host": "b"}, value: 120},
+		{name: "HeapSys", labels: metric.Labels{"host": "c"}, value: 0},
+		{name: "Ready", value: 1},
+	}
+
+	for _, g := range gauges {
+		value := g.value
+		require.NoError(t, repo.Update(context.Background(), &metric.Metrics{
+			ID: g.name, MType: metric.GaugeMetric, Value: &value, Labels: g.labels,
+		}))
+	}
+
+	counters := []struct {
+		name   string
+		labels metric.Labels
+		deltas []int64
+	}{
+		{labels: metric.Labels{"host": "a", "code": "200"}, deltas: []int64{10, 20, 30}},
+		{labels: metric.Labels{"host": "a", "code": "500"}, deltas: []int64{6}},
+		{labels: metric.Labels{"host": "b", "code": "200"}, deltas: []int64{12, 30}},
+		{name: "Ready", deltas: []int64{2}},
+	}
+
+	for _, c := range counters {
+		if c.name == "" {
+			c.name = "requests"
+		}
+
+		for _, delta := range c.deltas {
+			delta := delta
+			require.NoError(t, repo.Update(context.Background(), &metric.Metrics{
+				ID: c.name, MType: metric.CounterMetric, Delta: &delta, Labels: c.labels,
+			}))
+		}
+	}
+
+	return NewEngine(storage.NewMetricsStorage(repo, 10*time.Second))
+}
+
+func TestEngine(t *testing.T) {
+	engine := newTestEngine(t)
+	ts := time.Now()
+
+	queries := []string{
+		`HeapAlloc`,
+		`HeapAlloc{host="a"}`,
+		`HeapAlloc{host!="a"}`,
+		`HeapAlloc{env=""}`,
+		`HeapAlloc{host=""}`,
+		`Unknown`,
+		`requests`,
+		`HeapAlloc / HeapSys`,
+		`HeapAlloc / HeapSys * 100`,
+		`HeapSys / 0`,
+		`HeapSys > 50`,
+		`100 > HeapSys`,
+		`HeapAlloc < HeapSys / 3`,
+		`-HeapAlloc`,
+		`1 + 2 * 3`,
+		`2 > 1`,
+		`1 / 0`,
+		`Ready * 10`,
+		`Ready / Ready`,
+		`sum(HeapAlloc)`,
+		`avg(HeapSys)`,
+		`min(HeapSys)`,
+		`max(HeapSys)`,
+		`count(HeapSys)`,
+		`sum by (host) (requests)`,
+		`sum(requests) by (code)`,
+		`sum by (host, code) (requests)`,
+		`count(HeapSys > 50)`,
+		`rate(requests[1m])`,
+		`rate(requests{code="200"}[1m]) * 60`,
+		`sum by (host) (rate(requests[1m])) * 60`,
+		`rate(HeapAlloc[1m])`,
+		`sum(1)`,
+		`requests + HeapAlloc`,
+		`sum by (host) (requests) / sum by (host) (HeapSys)`,
+	}
+
+	var b strings.Builder
+
+	for _, q := range queries {
+		b.WriteString(q + "\n")
+
+		v, err := engine.Query(context.Background(), q, ts)
+		if err != nil {
+			b.WriteString("  error: " + err.Error() + "\n")
+
+			continue
+		}
+
+		b.WriteString("  " + string(v.Type()) + "\n")
+
+		if s := v.String(); s != "" {
+			b.WriteString("  " + strings.ReplaceAll(s, "\n", "\n  ") + "\n")
+		}
+	}
+
+	checkGolden(t, "engine.golden", b.String())
+}
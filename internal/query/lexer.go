@@ -0,0 +1,178 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind - вид лексемы выражения.
+type tokenKind int
+
+const (
+	tokenEOF      tokenKind = iota // Конец выражения
+	tokenIdent                     // Идентификатор: имя метрики, метки, функции или оператора агрегации
+	tokenNumber                    // Число
+	tokenString                    // Строка в двойных кавычках
+	tokenDuration                  // Длительность в квадратных скобках
+	tokenLParen                    // (
+	tokenRParen                    // )
+	tokenLBrace                    // {
+	tokenRBrace                    // }
+	tokenComma                     // ,
+	tokenAssign                    // =
+	tokenOperator                  // Бинарный оператор
+)
+
+// token описывает лексему выражения.
+type token struct {
+	kind tokenKind // Вид лексемы
+	text string    // Текст лексемы; для строк - значение без кавычек
+	pos  int       // Позиция лексемы в выражении (в байтах)
+}
+
+// operators - бинарные операторы в порядке, при котором более длинные операторы проверяются первыми.
+var operators = []string{"==", "!=", ">=", "<=", ">", "<", "+", "-", "*", "/"}
+
+// lex разбивает выражение на лексемы. Последней лексемой всегда является tokenEOF.
+func lex(input string) ([]token, error) {
+	var (
+		tokens = make([]token, 0)
+		pos    = 0
+	)
+
+	for pos < len(input) {
+		r, size := utf8.DecodeRuneInString(input[pos:])
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+
+			continue
+
+		case isIdentStart(r):
+			end := pos + size
+			for end < len(input) {
+				r, size = utf8.DecodeRuneInString(input[end:])
+				if !isIdentStart(r) && !unicode.IsDigit(r) && r != '.' {
+					break
+				}
+
+				end += size
+			}
+
+			tokens = append(tokens, token{kind: tokenIdent, text: input[pos:end], pos: pos})
+			pos = end
+
+			continue
+
+		case unicode.IsDigit(r) || r == '.':
+			end := pos
+			for end < len(input) && (isNumberChar(input[end]) ||
+				(input[end] == '+' || input[end] == '-') && (input[end-1] == 'e' || input[end-1] == 'E')) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: input[pos:end], pos: pos})
+			pos = end
+
+			continue
+
+		case r == '"':
+			end, value, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: value, pos: pos})
+			pos = end
+
+			continue
+
+		case r == '[':
+			end := strings.IndexByte(input[pos:], ']')
+			if end < 0 {
+				return nil, syntaxError(pos, "unclosed duration")
+			}
+
+			tokens = append(tokens, token{kind: tokenDuration, text: input[pos+1 : pos+end], pos: pos})
+			pos += end + 1
+
+			continue
+		}
+
+		if kind, ok := punctuation[r]; ok {
+			tokens = append(tokens, token{kind: kind, text: string(r), pos: pos})
+			pos += size
+
+			continue
+		}
+
+		op := matchOperator(input[pos:])
+
+		switch {
+		case op != "":
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			pos += len(op)
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenAssign, text: "=", pos: pos})
+			pos += size
+		default:
+			return nil, syntaxError(pos, fmt.Sprintf("unexpected character %q", r))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// punctuation - односимвольные лексемы, не являющиеся операторами.
+var punctuation = map[rune]tokenKind{
+	'(': tokenLParen,
+	')': tokenRParen,
+	'{': tokenLBrace,
+	'}': tokenRBrace,
+	',': tokenComma,
+}
+
+// lexString разбирает строку в двойных кавычках, начинающуюся в позиции pos.
+// Возвращает позицию, следующую за строкой, и значение строки.
+func lexString(input string, pos int) (int, string, error) {
+	for end := pos + 1; end < len(input); end++ {
+		switch input[end] {
+		case '\\':
+			end++
+		case '"':
+			value, err := strconv.Unquote(input[pos : end+1])
+			if err != nil {
+				return 0, "", syntaxError(pos, "invalid string")
+			}
+
+			return end + 1, value, nil
+		}
+	}
+
+	return 0, "", syntaxError(pos, "unclosed string")
+}
+
+// matchOperator возвращает бинарный оператор, с которого начинается s, или пустую строку.
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+// isIdentStart проверяет, может ли идентификатор начинаться с символа r.
+func isIdentStart(r rune) bool {
+	return r == '_' || r == ':' || unicode.IsLetter(r)
+}
+
+// isNumberChar проверяет, может ли символ c входить в запись числа.
+func isNumberChar(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E'
+}
@@ -0,0 +1,383 @@
+// Package query содержит язык выражений над метриками: разбор выражений и их вычисление по данным хранилища.
+//
+// Выражение строится из следующих элементов:
+//   - селектор: HeapAlloc, http_requests{host="a",env!="dev"};
+//   - селектор с интервалом (только в качестве аргумента функции): PollCount[5m];
+//   - функция rate(селектор[интервал]) - приращение counter за интервал в секунду;
+//   - агрегация sum, avg, min, max, count с необязательной группировкой:
+//     sum(x), sum by (host) (x), sum(x) by (host);
+//   - арифметика +, -, *, / и сравнения ==, !=, >, <, >=, <= между векторами и числами.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrSyntax возвращается, если выражение содержит синтаксическую ошибку.
+var ErrSyntax = errors.New("syntax error")
+
+// aggregateOps - допустимые операторы агрегации.
+var aggregateOps = map[string]bool{
+	"sum":   true,
+	"avg":   true,
+	"min":   true,
+	"max":   true,
+	"count": true,
+}
+
+// functions - допустимые функции.
+var functions = map[string]bool{
+	"rate": true,
+}
+
+// precedence - приоритеты бинарных операторов: чем больше значение, тем раньше выполняется операция.
+var precedence = map[string]int{
+	"==": 1, "!=": 1, ">": 1, "<": 1, ">=": 1, "<=": 1,
+	"+": 2, "-": 2,
+	"*": 3, "/": 3,
+}
+
+// parser - синтаксический анализатор выражения методом рекурсивного спуска.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse разбирает выражение и возвращает его синтаксическое дерево.
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+
+	expr, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, syntaxError(tok.pos, fmt.Sprintf("unexpected %q", tok.text))
+	}
+
+	if err := validateRanges(expr); err != nil {
+		return nil, err
+	}
+
+	return expr, nil
+}
+
+// syntaxError возвращает ошибку разбора в позиции pos.
+func syntaxError(pos int, msg string) error {
+	return fmt.Errorf("%w at position %d: %s", ErrSyntax, pos, msg)
+}
+
+// peek возвращает текущую лексему.
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next возвращает текущую лексему и переходит к следующей.
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+
+	return tok
+}
+
+// expect возвращает текущую лексему, если она имеет вид kind, и переходит к следующей.
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		if tok.kind == tokenEOF {
+			return tok, syntaxError(tok.pos, "unexpected end of expression, expected "+what)
+		}
+
+		return tok, syntaxError(tok.pos, fmt.Sprintf("unexpected %q, expected %s", tok.text, what))
+	}
+
+	return tok, nil
+}
+
+// parseExpr разбирает бинарное выражение, операторы которого имеют приоритет не ниже minPrec.
+// Все бинарные операторы левоассоциативны.
+func (p *parser) parseExpr(minPrec int) (Expr, error) {
+	lhs, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+
+		prec, ok := precedence[tok.text]
+		if tok.kind != tokenOperator || !ok || prec < minPrec {
+			return lhs, nil
+		}
+
+		p.next()
+
+		rhs, err := p.parseExpr(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		lhs = &BinaryExpr{Op: tok.text, LHS: lhs, RHS: rhs}
+	}
+}
+
+// parseUnary разбирает выражение с необязательной сменой знака.
+func (p *parser) parseUnary() (Expr, error) {
+	if tok := p.peek(); tok.kind == tokenOperator && (tok.text == "-" || tok.text == "+") {
+		p.next()
+
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		if tok.text == "+" {
+			return expr, nil
+		}
+
+		if n, ok := expr.(*NumberLiteral); ok {
+			return &NumberLiteral{Value: -n.Value}, nil
+		}
+
+		return &UnaryExpr{Expr: expr}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// parsePrimary разбирает число, выражение в скобках, селектор, вызов функции или агрегацию.
+func (p *parser) parsePrimary() (Expr, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, syntaxError(tok.pos, fmt.Sprintf("invalid number %q", tok.text))
+		}
+
+		return &NumberLiteral{Value: v}, nil
+
+	case tokenLParen:
+		expr, err := p.parseExpr(1)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expect(tokenRParen, `")"`); err != nil {
+			return nil, err
+		}
+
+		return expr, nil
+
+	case tokenIdent:
+		next := p.peek()
+
+		if aggregateOps[tok.text] && (next.kind == tokenLParen || next.kind == tokenIdent && next.text == "by") {
+			return p.parseAggregate(tok.text)
+		}
+
+		if functions[tok.text] && next.kind == tokenLParen {
+			return p.parseCall(tok.text)
+		}
+
+		return p.parseSelector(tok.text)
+
+	case tokenEOF:
+		return nil, syntaxError(tok.pos, "unexpected end of expression")
+	}
+
+	return nil, syntaxError(tok.pos, fmt.Sprintf("unexpected %q", tok.text))
+}
+
+// parseSelector разбирает селектор метрики с именем name.
+// Использование селекторов с интервалом вне аргументов функций проверяется в validateRanges.
+func (p *parser) parseSelector(name string) (Expr, error) {
+	sel := &VectorSelector{Name: name}
+
+	if p.peek().kind == tokenLBrace {
+		p.next()
+
+		for p.peek().kind != tokenRBrace {
+			m, err := p.parseMatcher()
+			if err != nil {
+				return nil, err
+			}
+
+			sel.Matchers = append(sel.Matchers, m)
+
+			if p.peek().kind != tokenComma {
+				break
+			}
+
+			p.next()
+		}
+
+		if _, err := p.expect(tokenRBrace, `"}"`); err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.kind == tokenDuration {
+		p.next()
+
+		d, err := time.ParseDuration(tok.text)
+		if err != nil || d <= 0 {
+			return nil, syntaxError(tok.pos, fmt.Sprintf("invalid duration %q", tok.text))
+		}
+
+		sel.Range = d
+	}
+
+	return sel, nil
+}
+
+// parseMatcher разбирает условие на значение метки вида name="value" или name!="value".
+func (p *parser) parseMatcher() (LabelMatcher, error) {
+	name, err := p.expect(tokenIdent, "label name")
+	if err != nil {
+		return LabelMatcher{}, err
+	}
+
+	m := LabelMatcher{Name: name.text}
+
+	switch op := p.next(); {
+	case op.kind == tokenAssign:
+	case op.kind == tokenOperator && op.text == "!=":
+		m.Negative = true
+	default:
+		return LabelMatcher{}, syntaxError(op.pos, fmt.Sprintf("unexpected %q, expected \"=\" or \"!=\"", op.text))
+	}
+
+	value, err := p.expect(tokenString, "label value")
+	if err != nil {
+		return LabelMatcher{}, err
+	}
+
+	m.Value = value.text
+
+	return m, nil
+}
+
+// parseCall разбирает вызов функции fn. Аргументом функции должен быть селектор с интервалом.
+func (p *parser) parseCall(fn string) (Expr, error) {
+	p.next()
+
+	start := p.peek()
+
+	arg, err := p.parseExpr(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if sel, ok := arg.(*VectorSelector); !ok || sel.Range == 0 {
+		return nil, syntaxError(start.pos, fmt.Sprintf("%s() expects a range selector, e.g. metric[5m]", fn))
+	}
+
+	if _, err := p.expect(tokenRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	return &Call{Func: fn, Arg: arg}, nil
+}
+
+// parseAggregate разбирает агрегацию op в одной из форм: op(x), op by (labels) (x), op(x) by (labels).
+func (p *parser) parseAggregate(op string) (Expr, error) {
+	var (
+		agg = &AggregateExpr{Op: op}
+		err error
+	)
+
+	if p.peek().kind == tokenIdent {
+		p.next()
+
+		if agg.By, err = p.parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err = p.expect(tokenLParen, `"("`); err != nil {
+		return nil, err
+	}
+
+	if agg.Expr, err = p.parseExpr(1); err != nil {
+		return nil, err
+	}
+
+	if _, err = p.expect(tokenRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); agg.By == nil && tok.kind == tokenIdent && tok.text == "by" {
+		p.next()
+
+		if agg.By, err = p.parseGrouping(); err != nil {
+			return nil, err
+		}
+	}
+
+	return agg, nil
+}
+
+// parseGrouping разбирает список имён меток группировки вида (label1, label2).
+func (p *parser) parseGrouping() ([]string, error) {
+	if _, err := p.expect(tokenLParen, `"("`); err != nil {
+		return nil, err
+	}
+
+	labels := make([]string, 0)
+
+	for p.peek().kind != tokenRParen {
+		name, err := p.expect(tokenIdent, "label name")
+		if err != nil {
+			return nil, err
+		}
+
+		labels = append(labels, name.text)
+
+		if p.peek().kind != tokenComma {
+			break
+		}
+
+		p.next()
+	}
+
+	if _, err := p.expect(tokenRParen, `")"`); err != nil {
+		return nil, err
+	}
+
+	return labels, nil
+}
+
+// validateRanges проверяет, что селекторы с интервалом используются только в качестве аргументов функций.
+func validateRanges(expr Expr) error {
+	switch e := expr.(type) {
+	case *VectorSelector:
+		if e.Range > 0 {
+			return fmt.Errorf("%w: range selector %s must be used as a function argument", ErrSyntax, e)
+		}
+	case *AggregateExpr:
+		return validateRanges(e.Expr)
+	case *UnaryExpr:
+		return validateRanges(e.Expr)
+	case *BinaryExpr:
+		if err := validateRanges(e.LHS); err != nil {
+			return err
+		}
+
+		return validateRanges(e.RHS)
+	}
+
+	return nil
+}
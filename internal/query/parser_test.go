@@ -0,0 +1,84 @@
+package query
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// checkGolden сравнивает got с содержимым файла testdata/name.
+// С флагом -update файл перезаписывается значением got.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		require.NoError(t, os.WriteFile(path, []byte(got), 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), got)
+}
+
+func TestParse(t *testing.T) {
+	queries := []string{
+		`HeapAlloc`,
+		`http.requests{host="a", env!="dev"}`,
+		`requests{path="/a\"b"}`,
+		`HeapAlloc / HeapSys`,
+		`1 + 2 * 3 - 4 / 2`,
+		`(1 + 2) * 3`,
+		`-HeapAlloc`,
+		`- -2`,
+		`1e3 + .5`,
+		`rate(PollCount[5m])`,
+		`rate(requests{code="500"}[1m30s]) * 60`,
+		`sum(HeapAlloc)`,
+		`sum by (host) (HeapAlloc)`,
+		`avg(HeapAlloc) by (host, env)`,
+		`count(HeapAlloc > 100)`,
+		`HeapAlloc / HeapSys > 0.5`,
+		`a + b == c - d`,
+		`sum by (host) (rate(requests[5m])) / count by (host) (requests)`,
+		`sum + count`,
+		``,
+		`HeapAlloc +`,
+		`HeapAlloc{host="a"`,
+		`HeapAlloc{host=a}`,
+		`HeapAlloc{host~"a"}`,
+		`HeapAlloc[5m]`,
+		`rate(HeapAlloc)`,
+		`rate(PollCount[5x])`,
+		`sum by host (HeapAlloc)`,
+		`(HeapAlloc`,
+		`HeapAlloc HeapSys`,
+		`"text"`,
+		`HeapAlloc % 2`,
+	}
+
+	var b strings.Builder
+
+	for _, q := range queries {
+		b.WriteString(q + "\n")
+
+		expr, err := Parse(q)
+		if err != nil {
+			b.WriteString("  error: " + err.Error() + "\n")
+
+			continue
+		}
+
+		b.WriteString("  " + expr.String() + "\n")
+	}
+
+	checkGolden(t, "parse.golden", b.String())
+}
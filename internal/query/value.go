@@ -0,0 +1,91 @@
+package query
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+// ValueType - тип результата вычисления выражения.
+type ValueType string
+
+const (
+	ValueScalar ValueType = "scalar" // Число
+	ValueVector ValueType = "vector" // Набор значений метрик
+)
+
+// Value - результат вычисления выражения.
+type Value interface {
+	// Type возвращает тип результата.
+	Type() ValueType
+	// String возвращает текстовое представление результата.
+	String() string
+}
+
+// Scalar - числовой результат вычисления выражения.
+type Scalar float64
+
+// Type возвращает тип результата.
+func (s Scalar) Type() ValueType {
+	return ValueScalar
+}
+
+// String возвращает текстовое представление результата.
+func (s Scalar) String() string {
+	return strconv.FormatFloat(float64(s), 'g', -1, 64)
+}
+
+// Sample - значение метрики в векторе.
+//
+// Имя метрики сохраняется только у значений, выбранных селектором без изменений
+// (в том числе отфильтрованных сравнением). Результаты функций, агрегаций и арифметических операций имени не имеют.
+type Sample struct {
+	Name   string        `json:"name,omitempty"`   // Имя метрики
+	Labels metric.Labels `json:"labels,omitempty"` // Набор меток
+	Value  float64       `json:"value"`            // Значение
+}
+
+// String возвращает текстовое представление значения вида name{labels} value.
+func (s *Sample) String() string {
+	return s.id() + " " + strconv.FormatFloat(s.Value, 'g', -1, 64)
+}
+
+// id возвращает идентификатор значения вида name{labels}. Для значения без имени и меток возвращается {}.
+func (s *Sample) id() string {
+	if id := s.Name + s.Labels.String(); id != "" {
+		return id
+	}
+
+	return "{}"
+}
+
+// Vector - результат вычисления выражения в виде набора значений метрик.
+type Vector []Sample
+
+// Type возвращает тип результата.
+func (v Vector) Type() ValueType {
+	return ValueVector
+}
+
+// String возвращает текстовое представление результата: по одному значению в строке.
+func (v Vector) String() string {
+	lines := make([]string, 0, len(v))
+	for i := range v {
+		lines = append(lines, v[i].String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// sort упорядочивает значения вектора по имени метрики и набору меток.
+func (v Vector) sort() {
+	sort.Slice(v, func(i, j int) bool {
+		if v[i].Name != v[j].Name {
+			return v[i].Name < v[j].Name
+		}
+
+		return v[i].Labels.String() < v[j].Labels.String()
+	})
+}
@@ -0,0 +1,300 @@
+// Package alerting содержит подсистему оповещений сервера: периодическую проверку правил
+// оповещения по данным хранилища и отслеживание состояния оповещений.
+package alerting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/query"
+	"github.com/KryukovO/metricscollector/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// resolvedRetention - время, в течение которого разрешённое оповещение возвращается в списке оповещений.
+const resolvedRetention = 15 * time.Minute
+
+var (
+	// ErrStorageIsNil возвращается NewManager, если передано неинициализированное хранилище.
+	ErrStorageIsNil = errors.New("storage is nil")
+	// ErrWrongInterval возвращается NewManager, если интервал проверки правил не положителен.
+	ErrWrongInterval = errors.New("non-positive evaluation interval")
+)
+
+// AlertState - состояние оповещения.
+type AlertState string
+
+const (
+	StatePending  AlertState = "pending"  // Условие выполняется меньше заданной в правиле длительности
+	StateFiring   AlertState = "firing"   // Оповещение сработало
+	StateResolved AlertState = "resolved" // Условие сработавшего оповещения перестало выполняться
+)
+
+// Alert описывает оповещение по одному значению выражения правила.
+type Alert struct {
+	Rule        string        `json:"rule"`                  // Имя правила
+	Severity    Severity      `json:"severity"`              // Важность оповещения
+	Description string        `json:"description,omitempty"` // Описание оповещения
+	Metric      string        `json:"metric,omitempty"`      // Имя метрики
+	Labels      metric.Labels `json:"labels,omitempty"`      // Набор меток значения
+	Value       float64       `json:"value"`                 // Значение при последней проверке
+	State       AlertState    `json:"state"`                 // Состояние оповещения
+	ActiveAt    time.Time     `json:"active_at"`             // Время начала выполнения условия
+	FiredAt     *time.Time    `json:"fired_at,omitempty"`    // Время срабатывания оповещения
+	ResolvedAt  *time.Time    `json:"resolved_at,omitempty"` // Время прекращения выполнения условия
+	Receivers   []string      `json:"-"`                     // Получатели уведомлений (см. Rule.Receivers)
+}
+
+// Notifier - получатель оповещений для отправки уведомлений.
+type Notifier interface {
+	// Notify вызывается после каждой проверки правил, выполненной в момент ts,
+	// и получает оповещения в состояниях firing и resolved.
+	Notify(ts time.Time, alerts []Alert)
+}
+
+// key возвращает идентификатор оповещения.
+func (a *Alert) key() string {
+	return a.Rule + "/" + a.series()
+}
+
+// series возвращает идентификатор значения выражения правила вида name{labels}.
+func (a *Alert) series() string {
+	if series := a.Metric + a.Labels.String(); series != "" {
+		return series
+	}
+
+	return "{}"
+}
+
+// Manager периодически проверяет правила оповещения и хранит активные оповещения.
+//
+// Оповещение по значению выражения правила находится в состоянии pending, пока условие выполняется
+// меньше длительности For правила, затем переходит в состояние firing. Когда условие перестаёт выполняться,
+// сработавшее оповещение переходит в состояние resolved, а оповещение в состоянии pending удаляется.
+// Если условие снова выполняется, по значению создаётся новое оповещение в состоянии pending.
+type Manager struct {
+	engine   *query.Engine
+	rules    []Rule
+	notifier Notifier
+	interval time.Duration
+	l        *log.Logger
+
+	mtx    sync.RWMutex
+	alerts map[string]*Alert
+
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewManager создаёт подсистему оповещений, проверяющую правила rules по данным хранилища s
+// с интервалом interval. Если передан notifier, после каждой проверки ему передаются
+// сработавшие и разрешённые оповещения.
+func NewManager(
+	s storage.Storage, rules []Rule, notifier Notifier, interval time.Duration, l *log.Logger,
+) (*Manager, error) {
+	if s == nil {
+		return nil, ErrStorageIsNil
+	}
+
+	if interval <= 0 {
+		return nil, ErrWrongInterval
+	}
+
+	names := make(map[string]bool, len(rules))
+	compiled := make([]Rule, len(rules))
+
+	for i := range rules {
+		compiled[i] = rules[i]
+
+		if err := compiled[i].compile(); err != nil {
+			return nil, err
+		}
+
+		if names[compiled[i].Name] {
+			return nil, fmt.Errorf("%w: duplicate rule name %q", ErrWrongRule, compiled[i].Name)
+		}
+
+		names[compiled[i].Name] = true
+	}
+
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	return &Manager{
+		engine:   query.NewEngine(s),
+		rules:    compiled,
+		notifier: notifier,
+		interval: interval,
+		l:        lg,
+		alerts:   make(map[string]*Alert),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Run проверяет правила оповещения с заданным интервалом до вызова Shutdown.
+func (m *Manager) Run() error {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return nil
+		default:
+		}
+
+		m.Evaluate(context.Background(), time.Now())
+
+		select {
+		case <-m.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown останавливает проверку правил, ожидая завершения текущей проверки.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Evaluate выполняет проверку всех правил оповещения на момент времени ts, обновляет состояние оповещений
+// и передаёт сработавшие и разрешённые оповещения получателю уведомлений.
+// Если выражение правила не удалось вычислить, состояние его оповещений не изменяется.
+func (m *Manager) Evaluate(ctx context.Context, ts time.Time) {
+	defer m.notify(ts)
+
+	for i := range m.rules {
+		rule := &m.rules[i]
+
+		res, err := m.engine.Eval(ctx, rule.expr, ts)
+		if err != nil {
+			m.l.Errorf("Alerting rule %q evaluation error: %s", rule.Name, err.Error())
+
+			continue
+		}
+
+		vec, ok := res.(query.Vector)
+		if !ok {
+			m.l.Errorf("Alerting rule %q evaluation error: expression must return a vector", rule.Name)
+
+			continue
+		}
+
+		m.update(rule, vec, ts)
+	}
+}
+
+// update обновляет состояние оповещений правила rule по значениям vec, для которых выполняется его условие.
+func (m *Manager) update(rule *Rule, vec query.Vector, ts time.Time) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	active := make(map[string]bool, len(vec))
+
+	for _, s := range vec {
+		alert := &Alert{Rule: rule.Name, Metric: s.Name, Labels: s.Labels, Receivers: rule.Receivers}
+		key := alert.key()
+		active[key] = true
+
+		if existing, ok := m.alerts[key]; ok && existing.State != StateResolved {
+			alert = existing
+		} else {
+			alert.Severity = rule.Severity
+			alert.Description = rule.Description
+			alert.State = StatePending
+			alert.ActiveAt = ts
+			m.alerts[key] = alert
+		}
+
+		alert.Value = s.Value
+
+		if alert.State == StatePending && ts.Sub(alert.ActiveAt) >= rule.For.Duration {
+			firedAt := ts
+			alert.State = StateFiring
+			alert.FiredAt = &firedAt
+
+			m.l.Warnf("Alert %q is firing for %s", rule.Name, alert.series())
+		}
+	}
+
+	for key, alert := range m.alerts {
+		if alert.Rule != rule.Name || active[key] {
+			continue
+		}
+
+		switch alert.State {
+		case StateFiring:
+			resolvedAt := ts
+			alert.State = StateResolved
+			alert.ResolvedAt = &resolvedAt
+
+			m.l.Infof("Alert %q is resolved for %s", rule.Name, alert.series())
+
+		case StateResolved:
+			if ts.Sub(*alert.ResolvedAt) >= resolvedRetention {
+				delete(m.alerts, key)
+			}
+
+		default:
+			delete(m.alerts, key)
+		}
+	}
+}
+
+// Alerts возвращает активные оповещения (в состояниях pending и firing), а также оповещения,
+// разрешённые в течение последних resolvedRetention. Оповещения упорядочены по имени правила,
+// имени метрики и набору меток.
+func (m *Manager) Alerts() []Alert {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	res := make([]Alert, 0, len(m.alerts))
+	for _, alert := range m.alerts {
+		res = append(res, *alert)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].key() < res[j].key()
+	})
+
+	return res
+}
+
+// notify передаёт получателю уведомлений оповещения в состояниях firing и resolved.
+func (m *Manager) notify(ts time.Time) {
+	if m.notifier == nil {
+		return
+	}
+
+	alerts := m.Alerts()
+
+	res := alerts[:0]
+
+	for i := range alerts {
+		if alerts[i].State != StatePending {
+			res = append(res, alerts[i])
+		}
+	}
+
+	m.notifier.Notify(ts, res)
+}
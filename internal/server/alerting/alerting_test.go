@@ -0,0 +1,233 @@
+package alerting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorage(t *testing.T) *storage.MetricsStorage {
+	t.Helper()
+
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	return storage.NewMetricsStorage(repo, 10*time.Second)
+}
+
+func setGauge(t *testing.T, s storage.Storage, name string, labels metric.Labels, value float64) {
+	t.Helper()
+
+	require.NoError(t, s.Update(context.Background(), &metric.Metrics{
+		ID: name, MType: metric.GaugeMetric, Value: &value, Labels: labels,
+	}))
+}
+
+func TestNewManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		wantErr bool
+	}{
+		{
+			name: "Correct rules",
+			rules: []Rule{
+				{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Threshold: 1e8},
+				{Name: "HighHeapUsage", Expr: "HeapAlloc / HeapSys", Op: ">=", Threshold: 0.9, Severity: SeverityCritical},
+			},
+		},
+		{
+			name:    "Empty name",
+			rules:   []Rule{{Expr: "FreeMemory", Op: "<"}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong expression",
+			rules:   []Rule{{Name: "LowFreeMemory", Expr: "FreeMemory +", Op: "<"}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong operator",
+			rules:   []Rule{{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "+"}},
+			wantErr: true,
+		},
+		{
+			name:    "Wrong severity",
+			rules:   []Rule{{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Severity: "fatal"}},
+			wantErr: true,
+		},
+		{
+			name: "Negative duration",
+			rules: []Rule{
+				{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", For: utils.Duration{Duration: -time.Second}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Duplicate name",
+			rules: []Rule{
+				{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<"},
+				{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<="},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m, err := NewManager(newTestStorage(t), test.rules, nil, time.Second, nil)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongRule)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, SeverityWarning, m.rules[0].Severity)
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s := newTestStorage(t)
+
+	rules := []Rule{
+		{
+			Name:        "LowFreeMemory",
+			Expr:        "FreeMemory",
+			Op:          "<",
+			Threshold:   100,
+			For:         utils.Duration{Duration: time.Minute},
+			Severity:    SeverityCritical,
+			Description: "Free memory is too low",
+		},
+	}
+
+	m, err := NewManager(s, rules, nil, time.Second, nil)
+	require.NoError(t, err)
+
+	var (
+		hostA = metric.Labels{"host": "a"}
+		hostB = metric.Labels{"host": "b"}
+		start = time.Now()
+	)
+
+	setGauge(t, s, "FreeMemory", hostA, 50)
+	setGauge(t, s, "FreeMemory", hostB, 500)
+
+	states := func() map[string]AlertState {
+		res := make(map[string]AlertState)
+		for _, alert := range m.Alerts() {
+			res[alert.Labels["host"]] = alert.State
+		}
+
+		return res
+	}
+
+	m.Evaluate(context.Background(), start)
+	assert.Equal(t, map[string]AlertState{"a": StatePending}, states())
+
+	m.Evaluate(context.Background(), start.Add(30*time.Second))
+	assert.Equal(t, map[string]AlertState{"a": StatePending}, states())
+
+	setGauge(t, s, "FreeMemory", hostB, 10)
+
+	m.Evaluate(context.Background(), start.Add(time.Minute))
+	assert.Equal(t, map[string]AlertState{"a": StateFiring, "b": StatePending}, states())
+
+	alerts := m.Alerts()
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "LowFreeMemory", alerts[0].Rule)
+	assert.Equal(t, "FreeMemory", alerts[0].Metric)
+	assert.Equal(t, SeverityCritical, alerts[0].Severity)
+	assert.Equal(t, "Free memory is too low", alerts[0].Description)
+	assert.Equal(t, float64(50), alerts[0].Value)
+	assert.Equal(t, start, alerts[0].ActiveAt)
+	require.NotNil(t, alerts[0].FiredAt)
+	assert.Equal(t, start.Add(time.Minute), *alerts[0].FiredAt)
+
+	setGauge(t, s, "FreeMemory", hostA, 500)
+	setGauge(t, s, "FreeMemory", hostB, 500)
+
+	m.Evaluate(context.Background(), start.Add(2*time.Minute))
+	assert.Equal(t, map[string]AlertState{"a": StateResolved}, states())
+
+	m.Evaluate(context.Background(), start.Add(2*time.Minute+resolvedRetention))
+	assert.Empty(t, m.Alerts())
+
+	setGauge(t, s, "FreeMemory", hostA, 50)
+
+	m.Evaluate(context.Background(), start.Add(3*time.Minute))
+	assert.Equal(t, map[string]AlertState{"a": StatePending}, states())
+}
+
+func TestRunShutdown(t *testing.T) {
+	s := newTestStorage(t)
+	setGauge(t, s, "FreeMemory", nil, 50)
+
+	m, err := NewManager(s, []Rule{{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Threshold: 100}},
+		nil, 10*time.Millisecond, nil)
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- m.Run() }()
+
+	require.Eventually(t, func() bool { return len(m.Alerts()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, StateFiring, m.Alerts()[0].State)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, m.Shutdown(ctx))
+	require.NoError(t, <-errCh)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "rules.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{
+		"rules": [{
+			"name": "LowFreeMemory", "expr": "FreeMemory", "op": "<", "threshold": 1e8,
+			"for": "5m", "severity": "critical", "description": "Free memory is too low", "receivers": ["ops"]
+		}],
+		"receivers": [{"name": "ops", "url": "http://localhost:9093/alerts", "key": "secret"}]
+	}`), 0o600))
+
+	cfg, err := LoadConfig(valid)
+	require.NoError(t, err)
+	assert.Equal(t, &Config{
+		Rules: []Rule{{
+			Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Threshold: 1e8,
+			For: utils.Duration{Duration: 5 * time.Minute}, Severity: SeverityCritical,
+			Description: "Free memory is too low", Receivers: []string{"ops"},
+		}},
+		Receivers: []Receiver{{Name: "ops", URL: "http://localhost:9093/alerts", Key: "secret"}},
+	}, cfg)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"rules": [{"name": 1}]}`), 0o600))
+
+	_, err = LoadConfig(invalid)
+	assert.ErrorIs(t, err, ErrWrongRule)
+
+	unknownReceiver := filepath.Join(dir, "unknown.json")
+	require.NoError(t, os.WriteFile(unknownReceiver, []byte(`{"rules": [
+		{"name": "LowFreeMemory", "expr": "FreeMemory", "op": "<", "receivers": ["ops"]}
+	]}`), 0o600))
+
+	_, err = LoadConfig(unknownReceiver)
+	assert.ErrorIs(t, err, ErrWrongRule)
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/KryukovO/metricscollector/internal/query"
+	"github.com/KryukovO/metricscollector/internal/utils"
+)
+
+// ErrWrongRule возвращается, если правило оповещения некорректно.
+var ErrWrongRule = errors.New("wrong alerting rule")
+
+// Severity - важность оповещения.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// IsValid проверяет, является ли важность оповещения допустимой.
+func (s Severity) IsValid() bool {
+	return s == SeverityInfo || s == SeverityWarning || s == SeverityCritical
+}
+
+// Rule описывает правило оповещения.
+//
+// Условие правила выполняется для каждого значения выражения Expr, для которого истинно сравнение
+// "значение Op Threshold". Оповещение срабатывает, если условие выполняется непрерывно в течение For.
+type Rule struct {
+	// Name - Имя правила
+	Name string `json:"name"`
+	// Expr - Выражение, значения которого проверяются (см. пакет query), например "FreeMemory"
+	// или "HeapAlloc / HeapSys"
+	Expr string `json:"expr"`
+	// Op - Оператор сравнения: ==, !=, >, <, >= или <=
+	Op string `json:"op"`
+	// Threshold - Пороговое значение
+	Threshold float64 `json:"threshold"`
+	// For - Длительность выполнения условия, после которой оповещение срабатывает
+	For utils.Duration `json:"for"`
+	// Severity - Важность оповещения (по умолчанию warning)
+	Severity Severity `json:"severity"`
+	// Description - Описание оповещения
+	Description string `json:"description"`
+	// Receivers - Имена получателей уведомлений об оповещениях правила.
+	// Если не указаны, уведомления отправляются всем получателям
+	Receivers []string `json:"receivers"`
+
+	expr query.Expr // Выражение условия правила
+}
+
+// compile проверяет корректность правила и строит выражение его условия.
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrWrongRule)
+	}
+
+	expr, err := query.Parse(r.Expr)
+	if err != nil {
+		return fmt.Errorf("%w %q: %s", ErrWrongRule, r.Name, err)
+	}
+
+	if !isComparison(r.Op) {
+		return fmt.Errorf("%w %q: unknown comparison operator %q", ErrWrongRule, r.Name, r.Op)
+	}
+
+	if r.For.Duration < 0 {
+		return fmt.Errorf("%w %q: negative duration", ErrWrongRule, r.Name)
+	}
+
+	if r.Severity == "" {
+		r.Severity = SeverityWarning
+	}
+
+	if !r.Severity.IsValid() {
+		return fmt.Errorf("%w %q: unknown severity %q", ErrWrongRule, r.Name, r.Severity)
+	}
+
+	r.expr = &query.BinaryExpr{Op: r.Op, LHS: expr, RHS: &query.NumberLiteral{Value: r.Threshold}}
+
+	return nil
+}
+
+// isComparison проверяет, является ли оператор op оператором сравнения.
+func isComparison(op string) bool {
+	switch op {
+	case "==", "!=", ">", "<", ">=", "<=":
+		return true
+	}
+
+	return false
+}
+
+// Config описывает содержимое файла правил оповещения.
+type Config struct {
+	Rules     []Rule     `json:"rules"`     // Правила оповещения
+	Receivers []Receiver `json:"receivers"` // Получатели уведомлений об оповещениях
+}
+
+// LoadConfig загружает правила оповещения и получателей уведомлений из JSON-файла
+// вида {"rules": [...], "receivers": [...]}.
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+
+	if err := json.Unmarshal(content, cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrWrongRule, err)
+	}
+
+	receivers := make(map[string]bool, len(cfg.Receivers))
+	for i := range cfg.Receivers {
+		receivers[cfg.Receivers[i].Name] = true
+	}
+
+	for i := range cfg.Rules {
+		for _, name := range cfg.Rules[i].Receivers {
+			if !receivers[name] {
+				return nil, fmt.Errorf("%w %q: unknown receiver %q", ErrWrongRule, cfg.Rules[i].Name, name)
+			}
+		}
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,434 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Параметры получателя уведомлений по умолчанию.
+const (
+	defaultRepeatInterval = 4 * time.Hour    // Интервал повторной отправки сработавшего оповещения
+	defaultMaxAttempts    = 3                // Количество попыток отправки уведомления
+	defaultRetryDelay     = time.Second      // Задержка перед первой повторной попыткой
+	defaultTimeout        = 10 * time.Second // Таймаут запроса
+)
+
+var (
+	// ErrWrongReceiver возвращается, если получатель уведомлений некорректен.
+	ErrWrongReceiver = errors.New("wrong alert receiver")
+	// ErrUnexpectedStatus возвращается, если получатель ответил на уведомление кодом, отличным от 2xx.
+	ErrUnexpectedStatus = errors.New("unexpected response status")
+)
+
+// Receiver описывает получателя уведомлений об оповещениях, принимающего их HTTP-запросом POST (webhook).
+//
+// Уведомления об оповещениях, накопленные к моменту отправки, группируются в один запрос.
+// Если задан ключ Key, тело запроса подписывается HMAC-SHA256 (см. utils.HashSHA256),
+// а подпись в шестнадцатеричном виде передаётся в заголовке HashSHA256.
+type Receiver struct {
+	// Name - Имя получателя
+	Name string `json:"name"`
+	// URL - Адрес, на который отправляются уведомления
+	URL string `json:"url"`
+	// Key - Ключ подписи тела запроса
+	Key string `json:"key"`
+	// MinInterval - Минимальный интервал между запросами к получателю
+	MinInterval utils.Duration `json:"min_interval"`
+	// RepeatInterval - Интервал повторной отправки уведомления о сработавшем оповещении (по умолчанию 4h)
+	RepeatInterval utils.Duration `json:"repeat_interval"`
+	// MaxAttempts - Количество попыток отправки уведомления (по умолчанию 3)
+	MaxAttempts uint `json:"max_attempts"`
+	// RetryDelay - Задержка перед первой повторной попыткой; удваивается с каждой попыткой (по умолчанию 1s)
+	RetryDelay utils.Duration `json:"retry_delay"`
+	// Timeout - Таймаут запроса (по умолчанию 10s)
+	Timeout utils.Duration `json:"timeout"`
+}
+
+// validate проверяет корректность получателя и заполняет незаданные параметры значениями по умолчанию.
+func (r *Receiver) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrWrongReceiver)
+	}
+
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w %q: invalid URL %q", ErrWrongReceiver, r.Name, r.URL)
+	}
+
+	if r.MinInterval.Duration < 0 || r.RepeatInterval.Duration < 0 ||
+		r.RetryDelay.Duration < 0 || r.Timeout.Duration < 0 {
+		return fmt.Errorf("%w %q: negative duration", ErrWrongReceiver, r.Name)
+	}
+
+	if r.RepeatInterval.Duration == 0 {
+		r.RepeatInterval.Duration = defaultRepeatInterval
+	}
+
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = defaultMaxAttempts
+	}
+
+	if r.RetryDelay.Duration == 0 {
+		r.RetryDelay.Duration = defaultRetryDelay
+	}
+
+	if r.Timeout.Duration == 0 {
+		r.Timeout.Duration = defaultTimeout
+	}
+
+	return nil
+}
+
+// Notification описывает тело запроса с уведомлением об оповещениях.
+type Notification struct {
+	Receiver string     `json:"receiver"` // Имя получателя
+	Status   AlertState `json:"status"`   // firing, если среди оповещений есть сработавшие, иначе resolved
+	Alerts   []Alert    `json:"alerts"`   // Оповещения
+}
+
+// sentAlert описывает последнее уведомление, поставленное в очередь отправки по оповещению.
+type sentAlert struct {
+	state    AlertState // Состояние оповещения
+	activeAt time.Time  // Время начала выполнения условия оповещения
+	at       time.Time  // Время постановки в очередь
+}
+
+// webhookReceiver отправляет уведомления одному получателю.
+type webhookReceiver struct {
+	cfg    Receiver
+	client *http.Client
+	l      *log.Logger
+
+	mtx     sync.Mutex
+	pending map[string]Alert     // Оповещения, ожидающие отправки
+	sent    map[string]sentAlert // Последние уведомления по оповещениям
+	wake    chan struct{}
+}
+
+// WebhookNotifier отправляет уведомления о сработавших и разрешённых оповещениях получателям (webhook).
+//
+// Уведомление о сработавшем оповещении отправляется при срабатывании и затем повторяется
+// с интервалом RepeatInterval получателя, пока оповещение не будет разрешено;
+// уведомление о разрешении отправляется однократно. Запросы к одному получателю выполняются
+// не чаще MinInterval; уведомления, накопленные за это время, отправляются одним запросом.
+// Уведомления, которые не удалось отправить за MaxAttempts попыток, повторно в очередь не ставятся.
+type WebhookNotifier struct {
+	receivers []*webhookReceiver
+	l         *log.Logger
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewWebhookNotifier создаёт отправителя уведомлений получателям receivers.
+func NewWebhookNotifier(receivers []Receiver, l *log.Logger) (*WebhookNotifier, error) {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	n := &WebhookNotifier{
+		receivers: make([]*webhookReceiver, 0, len(receivers)),
+		l:         lg,
+		ctx:       ctx,
+		cancel:    cancel,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	names := make(map[string]bool, len(receivers))
+
+	for _, cfg := range receivers {
+		if err := cfg.validate(); err != nil {
+			cancel()
+
+			return nil, err
+		}
+
+		if names[cfg.Name] {
+			cancel()
+
+			return nil, fmt.Errorf("%w: duplicate receiver name %q", ErrWrongReceiver, cfg.Name)
+		}
+
+		names[cfg.Name] = true
+
+		n.receivers = append(n.receivers, &webhookReceiver{
+			cfg:     cfg,
+			client:  &http.Client{Timeout: cfg.Timeout.Duration},
+			l:       lg,
+			pending: make(map[string]Alert),
+			sent:    make(map[string]sentAlert),
+			wake:    make(chan struct{}, 1),
+		})
+	}
+
+	return n, nil
+}
+
+// Run выполняет отправку уведомлений до вызова Shutdown.
+func (n *WebhookNotifier) Run() error {
+	defer close(n.done)
+
+	var wg sync.WaitGroup
+
+	for _, r := range n.receivers {
+		wg.Add(1)
+
+		go func(r *webhookReceiver) {
+			defer wg.Done()
+
+			r.run(n.ctx, n.stop)
+		}(r)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// Shutdown останавливает отправку уведомлений, предварительно отправив накопленные уведомления.
+// Если ctx завершается раньше, отправка прерывается.
+func (n *WebhookNotifier) Shutdown(ctx context.Context) error {
+	n.stopOnce.Do(func() { close(n.stop) })
+
+	select {
+	case <-n.done:
+		n.cancel()
+
+		return nil
+	case <-ctx.Done():
+		n.cancel()
+
+		return ctx.Err()
+	}
+}
+
+// Notify ставит в очередь отправки уведомления по оповещениям alerts, полученным при проверке правил в момент ts.
+func (n *WebhookNotifier) Notify(ts time.Time, alerts []Alert) {
+	for _, r := range n.receivers {
+		r.enqueue(ts, alerts)
+	}
+}
+
+// enqueue ставит в очередь отправки получателю уведомления по оповещениям alerts,
+// адресованным ему, о которых он ещё не был уведомлён или уведомление о которых необходимо повторить.
+func (r *webhookReceiver) enqueue(ts time.Time, alerts []Alert) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	seen := make(map[string]bool, len(alerts))
+	queued := false
+
+	for i := range alerts {
+		if !r.accepts(&alerts[i]) {
+			continue
+		}
+
+		key := alerts[i].key()
+		seen[key] = true
+
+		last, ok := r.sent[key]
+		if ok && !last.activeAt.Equal(alerts[i].ActiveAt) {
+			ok = false
+		}
+
+		switch alerts[i].State {
+		case StateFiring:
+			if ok && last.state == StateFiring && ts.Sub(last.at) < r.cfg.RepeatInterval.Duration {
+				continue
+			}
+
+		case StateResolved:
+			if !ok || last.state != StateFiring {
+				continue
+			}
+
+		default:
+			continue
+		}
+
+		r.sent[key] = sentAlert{state: alerts[i].State, activeAt: alerts[i].ActiveAt, at: ts}
+		r.pending[key] = alerts[i]
+		queued = true
+	}
+
+	for key := range r.sent {
+		if !seen[key] {
+			delete(r.sent, key)
+		}
+	}
+
+	if queued {
+		select {
+		case r.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// accepts проверяет, адресовано ли оповещение alert получателю.
+func (r *webhookReceiver) accepts(alert *Alert) bool {
+	if len(alert.Receivers) == 0 {
+		return true
+	}
+
+	for _, name := range alert.Receivers {
+		if name == r.cfg.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// run отправляет накопленные уведомления не чаще MinInterval до закрытия stop,
+// после чего отправляет оставшиеся уведомления.
+func (r *webhookReceiver) run(ctx context.Context, stop <-chan struct{}) {
+	var last time.Time
+
+	for {
+		select {
+		case <-r.wake:
+		case <-stop:
+			r.flush(ctx)
+
+			return
+		}
+
+		if wait := time.Until(last.Add(r.cfg.MinInterval.Duration)); !last.IsZero() && wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				r.flush(ctx)
+
+				return
+			}
+		}
+
+		r.flush(ctx)
+
+		last = time.Now()
+	}
+}
+
+// flush отправляет накопленные уведомления одним запросом.
+func (r *webhookReceiver) flush(ctx context.Context) {
+	r.mtx.Lock()
+	pending := r.pending
+	r.pending = make(map[string]Alert)
+	r.mtx.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	notification := Notification{
+		Receiver: r.cfg.Name,
+		Status:   StateResolved,
+		Alerts:   make([]Alert, 0, len(pending)),
+	}
+
+	for _, alert := range pending {
+		if alert.State == StateFiring {
+			notification.Status = StateFiring
+		}
+
+		notification.Alerts = append(notification.Alerts, alert)
+	}
+
+	sort.Slice(notification.Alerts, func(i, j int) bool {
+		return notification.Alerts[i].key() < notification.Alerts[j].key()
+	})
+
+	if err := r.send(ctx, &notification); err != nil {
+		r.l.Errorf("Can't send alert notification to receiver %q: %s", r.cfg.Name, err.Error())
+	}
+}
+
+// send отправляет уведомление, повторяя попытки после сетевых ошибок и ответов с кодами 429 и 5xx.
+func (r *webhookReceiver) send(ctx context.Context, notification *Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	delay := r.cfg.RetryDelay.Duration
+
+	for attempt := uint(1); ; attempt++ {
+		retryable, err := r.post(ctx, body)
+		if err == nil || !retryable || attempt >= r.cfg.MaxAttempts {
+			return err
+		}
+
+		r.l.Debugf("Alert notification to receiver %q failed (attempt %d): %s", r.cfg.Name, attempt, err.Error())
+
+		if waitErr := utils.Wait(ctx, delay); waitErr != nil {
+			return err
+		}
+
+		delay *= 2
+	}
+}
+
+// post выполняет запрос с телом body. Возвращает ошибку и признак того, что попытку следует повторить.
+func (r *webhookReceiver) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if r.cfg.Key != "" {
+		hash, err := utils.HashSHA256(body, []byte(r.cfg.Key))
+		if err != nil {
+			return false, err
+		}
+
+		req.Header.Set("HashSHA256", hex.EncodeToString(hash))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	_, err = io.Copy(io.Discard, resp.Body)
+
+	defer resp.Body.Close()
+
+	if err != nil {
+		return true, err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+
+		return retryable, fmt.Errorf("%w: %s", ErrUnexpectedStatus, resp.Status)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,318 @@
+package alerting
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newWebhookServer запускает тестового получателя уведомлений, который проверяет подпись запросов ключом key
+// (при пустом ключе - отсутствие подписи) и передаёт полученные уведомления в канал.
+// Ответ на запрос с номером n (начиная с 1) - statuses(n).
+func newWebhookServer(t *testing.T, key string, statuses func(n int32) int) (*httptest.Server, <-chan Notification) {
+	t.Helper()
+
+	var (
+		calls         int32
+		notifications = make(chan Notification, 10)
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		if key == "" {
+			assert.Empty(t, r.Header.Get("HashSHA256"))
+		} else {
+			hash, err := utils.HashSHA256(body, []byte(key))
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Equal(t, hex.EncodeToString(hash), r.Header.Get("HashSHA256"))
+		}
+
+		status := statuses(atomic.AddInt32(&calls, 1))
+		w.WriteHeader(status)
+
+		if status == http.StatusOK {
+			var notification Notification
+			if assert.NoError(t, json.Unmarshal(body, &notification)) {
+				notifications <- notification
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, notifications
+}
+
+// receive возвращает очередное уведомление, полученное тестовым получателем.
+func receive(t *testing.T, notifications <-chan Notification) Notification {
+	t.Helper()
+
+	select {
+	case notification := <-notifications:
+		return notification
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "notification was not received")
+	}
+
+	return Notification{}
+}
+
+// startNotifier запускает отправку уведомлений и останавливает её по завершении теста.
+func startNotifier(t *testing.T, n *WebhookNotifier) {
+	t.Helper()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- n.Run() }()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		assert.NoError(t, n.Shutdown(ctx))
+		assert.NoError(t, <-errCh)
+	})
+}
+
+// summary возвращает состояния оповещений уведомления по значениям метки host.
+func summary(notification Notification) map[string]AlertState {
+	res := make(map[string]AlertState, len(notification.Alerts))
+	for _, alert := range notification.Alerts {
+		res[alert.Labels["host"]] = alert.State
+	}
+
+	return res
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv, notifications := newWebhookServer(t, "secret", func(int32) int { return http.StatusOK })
+
+	notifier, err := NewWebhookNotifier([]Receiver{
+		{Name: "ops", URL: srv.URL, Key: "secret", RepeatInterval: utils.Duration{Duration: time.Hour}},
+		{Name: "dev", URL: "http://localhost:1/unused"},
+	}, nil)
+	require.NoError(t, err)
+	startNotifier(t, notifier)
+
+	s := newTestStorage(t)
+
+	m, err := NewManager(s, []Rule{
+		{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Threshold: 100, Receivers: []string{"ops"}},
+	}, notifier, time.Second, nil)
+	require.NoError(t, err)
+
+	start := time.Now()
+
+	setGauge(t, s, "FreeMemory", metric.Labels{"host": "a"}, 50)
+	setGauge(t, s, "FreeMemory", metric.Labels{"host": "b"}, 10)
+
+	m.Evaluate(context.Background(), start)
+
+	notification := receive(t, notifications)
+	assert.Equal(t, "ops", notification.Receiver)
+	assert.Equal(t, StateFiring, notification.Status)
+	assert.Equal(t, map[string]AlertState{"a": StateFiring, "b": StateFiring}, summary(notification))
+	assert.Equal(t, float64(50), notification.Alerts[0].Value)
+
+	// До истечения RepeatInterval уведомление не повторяется
+	m.Evaluate(context.Background(), start.Add(time.Minute))
+
+	m.Evaluate(context.Background(), start.Add(time.Hour))
+
+	notification = receive(t, notifications)
+	assert.Equal(t, map[string]AlertState{"a": StateFiring, "b": StateFiring}, summary(notification))
+
+	setGauge(t, s, "FreeMemory", metric.Labels{"host": "a"}, 500)
+
+	m.Evaluate(context.Background(), start.Add(time.Hour+time.Minute))
+
+	notification = receive(t, notifications)
+	assert.Equal(t, StateResolved, notification.Status)
+	assert.Equal(t, map[string]AlertState{"a": StateResolved}, summary(notification))
+
+	// Уведомления о разрешении не повторяются; получателю dev оповещения правила не адресованы
+	m.Evaluate(context.Background(), start.Add(time.Hour+2*time.Minute))
+
+	select {
+	case notification = <-notifications:
+		assert.Fail(t, "unexpected notification", "%+v", notification)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	assert.Empty(t, notifier.receivers[1].sent)
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  func(n int32) int
+		delivered bool
+	}{
+		{
+			name: "Retry after server errors",
+			statuses: func(n int32) int {
+				if n < 3 {
+					return http.StatusServiceUnavailable
+				}
+
+				return http.StatusOK
+			},
+			delivered: true,
+		},
+		{
+			name: "Attempts exhausted",
+			statuses: func(int32) int {
+				return http.StatusTooManyRequests
+			},
+		},
+		{
+			name: "Client error is not retried",
+			statuses: func(n int32) int {
+				if n == 1 {
+					return http.StatusBadRequest
+				}
+
+				return http.StatusOK
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv, notifications := newWebhookServer(t, "", test.statuses)
+
+			notifier, err := NewWebhookNotifier([]Receiver{{
+				Name: "ops", URL: srv.URL, MaxAttempts: 3, RetryDelay: utils.Duration{Duration: 10 * time.Millisecond},
+			}}, nil)
+			require.NoError(t, err)
+
+			alert := Alert{Rule: "LowFreeMemory", State: StateFiring, ActiveAt: time.Now()}
+
+			err = notifier.receivers[0].send(context.Background(), &Notification{
+				Receiver: "ops", Status: StateFiring, Alerts: []Alert{alert},
+			})
+
+			if !test.delivered {
+				assert.ErrorIs(t, err, ErrUnexpectedStatus)
+				assert.Empty(t, notifications)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"LowFreeMemory"}, []string{receive(t, notifications).Alerts[0].Rule})
+		})
+	}
+}
+
+func TestWebhookRateLimit(t *testing.T) {
+	srv, notifications := newWebhookServer(t, "", func(int32) int { return http.StatusOK })
+
+	notifier, err := NewWebhookNotifier([]Receiver{
+		{Name: "ops", URL: srv.URL, MinInterval: utils.Duration{Duration: time.Hour}},
+	}, nil)
+	require.NoError(t, err)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- notifier.Run() }()
+
+	var (
+		ts     = time.Now()
+		alertA = Alert{Rule: "LowFreeMemory", Labels: metric.Labels{"host": "a"}, State: StateFiring, ActiveAt: ts}
+		alertB = Alert{Rule: "LowFreeMemory", Labels: metric.Labels{"host": "b"}, State: StateFiring, ActiveAt: ts}
+	)
+
+	notifier.Notify(ts, []Alert{alertA})
+	assert.Equal(t, map[string]AlertState{"a": StateFiring}, summary(receive(t, notifications)))
+
+	// Уведомления, накопленные до истечения MinInterval, группируются и отправляются при остановке
+	notifier.Notify(ts, []Alert{alertA, alertB})
+
+	alertA.State = StateResolved
+	notifier.Notify(ts, []Alert{alertA, alertB})
+
+	select {
+	case notification := <-notifications:
+		assert.Fail(t, "unexpected notification", "%+v", notification)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, notifier.Shutdown(ctx))
+	require.NoError(t, <-errCh)
+
+	notification := receive(t, notifications)
+	assert.Equal(t, StateFiring, notification.Status)
+	assert.Equal(t, map[string]AlertState{"a": StateResolved, "b": StateFiring}, summary(notification))
+}
+
+func TestNewWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name      string
+		receivers []Receiver
+		wantErr   bool
+	}{
+		{
+			name:      "Correct receivers",
+			receivers: []Receiver{{Name: "ops", URL: "https://example.com/hook"}, {Name: "dev", URL: "http://dev:8080"}},
+		},
+		{
+			name:      "Empty name",
+			receivers: []Receiver{{URL: "https://example.com/hook"}},
+			wantErr:   true,
+		},
+		{
+			name:      "Wrong URL",
+			receivers: []Receiver{{Name: "ops", URL: "example.com/hook"}},
+			wantErr:   true,
+		},
+		{
+			name: "Negative interval",
+			receivers: []Receiver{
+				{Name: "ops", URL: "https://example.com/hook", MinInterval: utils.Duration{Duration: -time.Second}},
+			},
+			wantErr: true,
+		},
+		{
+			name:      "Duplicate name",
+			receivers: []Receiver{{Name: "ops", URL: "https://example.com/a"}, {Name: "ops", URL: "https://example.com/b"}},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := NewWebhookNotifier(test.receivers, nil)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongReceiver)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, defaultRepeatInterval, n.receivers[0].cfg.RepeatInterval.Duration)
+			assert.Equal(t, uint(defaultMaxAttempts), n.receivers[0].cfg.MaxAttempts)
+		})
+	}
+}
@@ -26,6 +26,14 @@ const (
 	key             = ""                     // Ключ аутентификации по умолчанию
 	cryptoKey       = ""                     // Путь до файла с приватным ключом
 	trastesSNet     = ""                     // Подсеть доверенных адресов
+	statsdAddress   = ""                     // Адрес UDP-эндпоинта приёмника метрик StatsD (host:port) по умолчанию
+	statsdFlush     = 10 * time.Second       // Интервал записи метрик StatsD в хранилище по умолчанию
+	graphiteAddress = ""                     // Адрес TCP-эндпоинта приёмника метрик Graphite (host:port) по умолчанию
+	adminToken      = ""                     // Токен доступа к операциям администрирования по умолчанию
+	alertRules      = ""                     // Путь до файла с правилами оповещения по умолчанию
+	alertInterval   = 15 * time.Second       // Интервал проверки правил оповещения по умолчанию
+	historyAge      = 24 * time.Hour         // Время хранения истории значений метрик по умолчанию
+	historySamples  = 10000                  // Количество хранимых значений одной метрики по умолчанию
 
 	storeTimeout    = 5 * time.Second  // Таймаут выполнения операций с хранилищем по умолчанию
 	shutdownTimeout = 10 * time.Second // Таймаут для graceful shutdown сервера по умолчанию
@@ -56,6 +64,26 @@ type Config struct {
 	CryptoKey string `env:"CRYPTO_KEY" json:"crypto_key"`
 	// TrustedSNet - Подсеть доверенных адресов
 	TrustedSNet string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
+	// StatsDAddress - Адрес UDP-эндпоинта приёмника метрик StatsD (host:port).
+	// Если не указан, приёмник не запускается
+	StatsDAddress string `env:"STATSD_ADDRESS" json:"statsd_address"`
+	// StatsDFlushInterval - Интервал записи агрегированных метрик StatsD в хранилище
+	StatsDFlushInterval utils.Duration `env:"STATSD_FLUSH_INTERVAL" json:"statsd_flush_interval"`
+	// GraphiteAddress - Адрес TCP-эндпоинта приёмника метрик в формате Graphite plaintext (host:port).
+	// Если не указан, приёмник не запускается
+	GraphiteAddress string `env:"GRAPHITE_ADDRESS" json:"graphite_address"`
+	// AdminToken - Токен доступа к операциям администрирования (удаление и сброс метрик).
+	// Если не указан, операции администрирования запрещены
+	AdminToken string `env:"ADMIN_TOKEN" json:"-"`
+	// AlertRules - Путь до файла с правилами оповещения.
+	// Если не указан, подсистема оповещений не запускается
+	AlertRules string `env:"ALERT_RULES" json:"alert_rules"`
+	// AlertInterval - Интервал проверки правил оповещения
+	AlertInterval utils.Duration `env:"ALERT_INTERVAL" json:"alert_interval"`
+	// HistoryMaxAge - Время хранения истории значений метрик в памяти сервера (0 - без ограничения)
+	HistoryMaxAge utils.Duration `env:"HISTORY_MAX_AGE" json:"history_max_age"`
+	// HistoryMaxSamples - Количество хранимых в памяти сервера значений одной метрики (0 - без ограничения)
+	HistoryMaxSamples int `env:"HISTORY_MAX_SAMPLES" json:"history_max_samples"`
 
 	// StoreTimeout -Таймаут выполнения операций с хранилищем
 	StoreTimeout utils.Duration `json:"-"`
@@ -87,6 +115,14 @@ func NewConfig() (*Config, error) {
 	flag.StringVar(&cfg.Key, "k", key, "Server key")
 	flag.StringVar(&cfg.CryptoKey, "crypto-key", cryptoKey, "Path to file with private cryptographic key")
 	flag.StringVar(&cfg.TrustedSNet, "t", trastesSNet, "Trusted subnet")
+	flag.StringVar(&cfg.StatsDAddress, "statsd", statsdAddress, "StatsD UDP endpoint address")
+	flag.DurationVar(&cfg.StatsDFlushInterval.Duration, "statsd-flush", statsdFlush, "StatsD flush interval")
+	flag.StringVar(&cfg.GraphiteAddress, "graphite", graphiteAddress, "Graphite plaintext TCP endpoint address")
+	flag.StringVar(&cfg.AdminToken, "admin-token", adminToken, "Admin operations access token")
+	flag.StringVar(&cfg.AlertRules, "alert-rules", alertRules, "Alerting rules file path")
+	flag.DurationVar(&cfg.AlertInterval.Duration, "alert-interval", alertInterval, "Alerting rules evaluation interval")
+	flag.DurationVar(&cfg.HistoryMaxAge.Duration, "history-max-age", historyAge, "In-memory metric history retention")
+	flag.IntVar(&cfg.HistoryMaxSamples, "history-max-samples", historySamples, "In-memory metric history samples limit")
 
 	flag.DurationVar(&cfg.StoreTimeout.Duration, "timeout", storeTimeout, "Storage connection timeout")
 	flag.DurationVar(&cfg.ShutdownTimeout.Duration, "shutdown", shutdownTimeout, "Graceful shutdown timeout")
@@ -174,5 +210,33 @@ func (cfg *Config) parseFile(path string) error {
 		cfg.TrustedSNet = fileConf.TrustedSNet
 	}
 
+	if !utils.IsFlagPassed("statsd") {
+		cfg.StatsDAddress = fileConf.StatsDAddress
+	}
+
+	if !utils.IsFlagPassed("statsd-flush") && fileConf.StatsDFlushInterval.Duration != 0 {
+		cfg.StatsDFlushInterval = fileConf.StatsDFlushInterval
+	}
+
+	if !utils.IsFlagPassed("graphite") {
+		cfg.GraphiteAddress = fileConf.GraphiteAddress
+	}
+
+	if !utils.IsFlagPassed("alert-rules") {
+		cfg.AlertRules = fileConf.AlertRules
+	}
+
+	if !utils.IsFlagPassed("alert-interval") && fileConf.AlertInterval.Duration != 0 {
+		cfg.AlertInterval = fileConf.AlertInterval
+	}
+
+	if !utils.IsFlagPassed("history-max-age") && fileConf.HistoryMaxAge.Duration != 0 {
+		cfg.HistoryMaxAge = fileConf.HistoryMaxAge
+	}
+
+	if !utils.IsFlagPassed("history-max-samples") && fileConf.HistoryMaxSamples != 0 {
+		cfg.HistoryMaxSamples = fileConf.HistoryMaxSamples
+	}
+
 	return nil
 }
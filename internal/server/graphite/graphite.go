@@ -0,0 +1,269 @@
+// Package graphite содержит приёмник метрик в формате Graphite plaintext по протоколу TCP.
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxBatchSize - максимальное количество метрик, записываемых в хранилище за раз.
+const maxBatchSize = 1000
+
+var (
+	// ErrStorageIsNil возвращается NewListener, если передано неинициализированное хранилище.
+	ErrStorageIsNil = errors.New("storage is nil")
+	// ErrListenerClosed возвращается Serve после вызова Shutdown.
+	ErrListenerClosed = errors.New("graphite listener closed")
+	// ErrWrongLine возвращается, если строка не соответствует формату Graphite plaintext.
+	ErrWrongLine = errors.New("wrong Graphite line")
+)
+
+// Listener принимает метрики в формате Graphite plaintext ("path value timestamp")
+// и записывает каждый путь в хранилище как метрику типа gauge.
+// Поддерживаются теги в формате "path;tag1=value1;tag2=value2", которые записываются как метки.
+type Listener struct {
+	storage storage.Storage
+	l       *log.Logger
+
+	mtx      sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewListener создаёт приёмник метрик Graphite, записывающий метрики в хранилище s.
+func NewListener(s storage.Storage, l *log.Logger) (*Listener, error) {
+	if s == nil {
+		return nil, ErrStorageIsNil
+	}
+
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	return &Listener{
+		storage: s,
+		conns:   make(map[net.Conn]struct{}),
+		l:       lg,
+	}, nil
+}
+
+// Serve принимает соединения из ln и обрабатывает их до вызова Shutdown.
+// После вызова Shutdown возвращает ErrListenerClosed.
+func (lsn *Listener) Serve(ln net.Listener) error {
+	lsn.mtx.Lock()
+	if lsn.closed {
+		lsn.mtx.Unlock()
+		_ = ln.Close()
+
+		return ErrListenerClosed
+	}
+
+	lsn.listener = ln
+	lsn.mtx.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			lsn.mtx.Lock()
+			closed := lsn.closed
+			lsn.mtx.Unlock()
+
+			if closed {
+				return ErrListenerClosed
+			}
+
+			return err
+		}
+
+		if !lsn.track(conn) {
+			_ = conn.Close()
+
+			return ErrListenerClosed
+		}
+
+		go func() {
+			defer lsn.untrack(conn)
+
+			lsn.handleConn(conn)
+		}()
+	}
+}
+
+// Shutdown прекращает приём новых соединений, прерывает ожидание данных в активных соединениях
+// и ожидает записи уже полученных метрик в хранилище.
+// Если ctx отменяется раньше, активные соединения закрываются принудительно и возвращается ошибка ctx.
+func (lsn *Listener) Shutdown(ctx context.Context) error {
+	lsn.mtx.Lock()
+	lsn.closed = true
+
+	var err error
+	if lsn.listener != nil {
+		err = lsn.listener.Close()
+	}
+
+	for conn := range lsn.conns {
+		_ = conn.SetReadDeadline(time.Now())
+	}
+
+	lsn.mtx.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		lsn.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+
+	case <-ctx.Done():
+		lsn.mtx.Lock()
+		for conn := range lsn.conns {
+			_ = conn.Close()
+		}
+		lsn.mtx.Unlock()
+
+		<-done
+
+		return ctx.Err()
+	}
+}
+
+// track регистрирует активное соединение. Возвращает false, если приёмник остановлен.
+func (lsn *Listener) track(conn net.Conn) bool {
+	lsn.mtx.Lock()
+	defer lsn.mtx.Unlock()
+
+	if lsn.closed {
+		return false
+	}
+
+	lsn.conns[conn] = struct{}{}
+	lsn.wg.Add(1)
+
+	return true
+}
+
+// untrack закрывает соединение и удаляет его из активных.
+func (lsn *Listener) untrack(conn net.Conn) {
+	_ = conn.Close()
+
+	lsn.mtx.Lock()
+	delete(lsn.conns, conn)
+	lsn.mtx.Unlock()
+
+	lsn.wg.Done()
+}
+
+// handleConn читает строки из соединения до его закрытия клиентом.
+// Метрики записываются в хранилище, когда прочитаны все поступившие данные
+// или накоплено maxBatchSize метрик. Некорректные строки пропускаются.
+func (lsn *Listener) handleConn(conn net.Conn) {
+	var (
+		reader = bufio.NewReader(conn)
+		batch  = make([]metric.Metrics, 0)
+		remote = conn.RemoteAddr()
+	)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		// Строка, оборванная из-за ошибки чтения, может быть неполной
+		if (err == nil || errors.Is(err, io.EOF)) && strings.TrimSpace(line) != "" {
+			mtrc, parseErr := parseLine(line)
+			if parseErr != nil {
+				lsn.l.Debugf("Graphite (%s): %s", remote, parseErr)
+			} else {
+				batch = append(batch, mtrc)
+			}
+		}
+
+		if err != nil || reader.Buffered() == 0 || len(batch) >= maxBatchSize {
+			lsn.save(remote, batch)
+			batch = batch[:0]
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrDeadlineExceeded) {
+			return
+		}
+
+		if err != nil {
+			lsn.l.Errorf("Graphite (%s): error reading connection: %s", remote, err)
+
+			return
+		}
+	}
+}
+
+// save записывает набор метрик в хранилище.
+func (lsn *Listener) save(remote net.Addr, mtrcs []metric.Metrics) {
+	if len(mtrcs) == 0 {
+		return
+	}
+
+	if err := lsn.storage.UpdateMany(context.Background(), mtrcs); err != nil {
+		lsn.l.Errorf("Graphite (%s): error when saving %d metrics: %s", remote, len(mtrcs), err)
+	}
+}
+
+// parseLine разбирает строку вида "path[;tag=value...] value [timestamp]".
+// Метка времени -1 или её отсутствие означает время получения; метка времени не сохраняется.
+func parseLine(line string) (metric.Metrics, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return metric.Metrics{}, fmt.Errorf("%w: %q", ErrWrongLine, strings.TrimSpace(line))
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return metric.Metrics{}, fmt.Errorf("%w: wrong value %q", ErrWrongLine, fields[1])
+	}
+
+	if len(fields) == 3 {
+		if _, err = strconv.ParseFloat(fields[2], 64); err != nil {
+			return metric.Metrics{}, fmt.Errorf("%w: wrong timestamp %q", ErrWrongLine, fields[2])
+		}
+	}
+
+	parts := strings.Split(fields[0], ";")
+	if parts[0] == "" {
+		return metric.Metrics{}, fmt.Errorf("%w: empty path", ErrWrongLine)
+	}
+
+	mtrc := metric.Metrics{ID: parts[0], MType: metric.GaugeMetric, Value: &value}
+
+	for _, tag := range parts[1:] {
+		name, tagValue, ok := strings.Cut(tag, "=")
+		if !ok || name == "" || tagValue == "" {
+			return metric.Metrics{}, fmt.Errorf("%w: wrong tag %q", ErrWrongLine, tag)
+		}
+
+		if mtrc.Labels == nil {
+			mtrc.Labels = make(metric.Labels)
+		}
+
+		mtrc.Labels[name] = tagValue
+	}
+
+	return mtrc, nil
+}
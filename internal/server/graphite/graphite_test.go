@@ -0,0 +1,136 @@
+package graphite
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	value := 42.5
+
+	tests := []struct {
+		name     string
+		line     string
+		expected metric.Metrics
+		wantErr  bool
+	}{
+		{
+			name:     "Path with timestamp",
+			line:     "servers.web01.cpu 42.5 1700000000\n",
+			expected: metric.Metrics{ID: "servers.web01.cpu", MType: metric.GaugeMetric, Value: &value},
+		},
+		{
+			name:     "Path without timestamp",
+			line:     "servers.web01.cpu 42.5",
+			expected: metric.Metrics{ID: "servers.web01.cpu", MType: metric.GaugeMetric, Value: &value},
+		},
+		{
+			name: "Tagged path",
+			line: "cpu;host=web01;dc=eu 42.5 -1",
+			expected: metric.Metrics{
+				ID: "cpu", MType: metric.GaugeMetric, Value: &value, Labels: metric.Labels{"host": "web01", "dc": "eu"},
+			},
+		},
+		{
+			name:    "Missing value",
+			line:    "servers.web01.cpu",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong value",
+			line:    "servers.web01.cpu high 1700000000",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong timestamp",
+			line:    "servers.web01.cpu 42.5 now",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong tag",
+			line:    "cpu;host 42.5",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mtrc, err := parseLine(test.line)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongLine)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, mtrc)
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	_, err = NewListener(nil, nil)
+	assert.ErrorIs(t, err, ErrStorageIsNil)
+
+	lsn, err := NewListener(storage.NewMetricsStorage(repo, time.Second), nil)
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- lsn.Serve(ln)
+	}()
+
+	// Клиент, закрывающий соединение после отправки
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("jobs.backup.duration 12.5 1700000000\nwrong\njobs.backup.size 1024"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.Eventually(t, func() bool {
+		v, err := repo.GetValue(context.Background(), metric.GaugeMetric, "jobs.backup.size", nil)
+
+		return err == nil && *v.Value == 1024
+	}, time.Second, 10*time.Millisecond)
+
+	// Клиент, удерживающий соединение, не препятствует остановке приёмника
+	conn, err = net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("jobs.backup.duration 20 1700000060\n"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		v, err := repo.GetValue(context.Background(), metric.GaugeMetric, "jobs.backup.duration", nil)
+
+		return err == nil && *v.Value == 20
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, lsn.Shutdown(ctx))
+	assert.ErrorIs(t, <-done, ErrListenerClosed)
+
+	values, err := repo.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, values, 2)
+}
@@ -0,0 +1,71 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AlertingServer описывает gRPC-сервер, предоставляющий интерфейс получения оповещений.
+type AlertingServer struct {
+	pb.UnimplementedAlertingServer
+
+	alerts *alerting.Manager
+	l      *log.Logger
+}
+
+// NewAlertingServer возвращает новый экземляр AlertingServer.
+// Если подсистема оповещений alerts не передана, список оповещений всегда пуст.
+func NewAlertingServer(alerts *alerting.Manager, l *log.Logger) *AlertingServer {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	return &AlertingServer{
+		alerts: alerts,
+		l:      lg,
+	}
+}
+
+// Alerts возвращает активные и недавно разрешённые оповещения.
+func (s *AlertingServer) Alerts(_ context.Context, _ *emptypb.Empty) (*pb.AlertsResponse, error) {
+	resp := &pb.AlertsResponse{}
+
+	if s.alerts == nil {
+		return resp, nil
+	}
+
+	alerts := s.alerts.Alerts()
+	resp.Alerts = make([]*pb.Alert, 0, len(alerts))
+
+	for i := range alerts {
+		alert := &pb.Alert{
+			Rule:        alerts[i].Rule,
+			Severity:    string(alerts[i].Severity),
+			Description: alerts[i].Description,
+			Metric:      alerts[i].Metric,
+			Labels:      alerts[i].Labels,
+			Value:       alerts[i].Value,
+			State:       string(alerts[i].State),
+			ActiveAt:    timestamppb.New(alerts[i].ActiveAt),
+		}
+
+		if alerts[i].FiredAt != nil {
+			alert.FiredAt = timestamppb.New(*alerts[i].FiredAt)
+		}
+
+		if alerts[i].ResolvedAt != nil {
+			alert.ResolvedAt = timestamppb.New(*alerts[i].ResolvedAt)
+		}
+
+		resp.Alerts = append(resp.Alerts, alert)
+	}
+
+	return resp, nil
+}
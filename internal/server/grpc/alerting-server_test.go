@@ -0,0 +1,53 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlerts(t *testing.T) {
+	resp, err := NewAlertingServer(nil, nil).Alerts(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetAlerts())
+
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	stor := storage.NewMetricsStorage(repo, time.Second)
+
+	value := 50.0
+	require.NoError(t, stor.Update(context.Background(), &metric.Metrics{
+		ID: "FreeMemory", MType: metric.GaugeMetric, Value: &value, Labels: metric.Labels{"host": "a"},
+	}))
+
+	alerts, err := alerting.NewManager(
+		stor, []alerting.Rule{{Name: "LowFreeMemory", Expr: "FreeMemory", Op: "<", Threshold: 100}}, nil, time.Second, nil,
+	)
+	require.NoError(t, err)
+
+	ts := time.Now()
+	alerts.Evaluate(context.Background(), ts)
+
+	resp, err = NewAlertingServer(alerts, nil).Alerts(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, resp.GetAlerts(), 1)
+
+	alert := resp.GetAlerts()[0]
+	assert.Equal(t, "LowFreeMemory", alert.GetRule())
+	assert.Equal(t, "warning", alert.GetSeverity())
+	assert.Equal(t, "FreeMemory", alert.GetMetric())
+	assert.Equal(t, map[string]string{"host": "a"}, alert.GetLabels())
+	assert.Equal(t, value, alert.GetValue())
+	assert.Equal(t, "firing", alert.GetState())
+	assert.True(t, ts.Equal(alert.GetActiveAt().AsTime()))
+	assert.True(t, ts.Equal(alert.GetFiredAt().AsTime()))
+	assert.Nil(t, alert.GetResolvedAt())
+}
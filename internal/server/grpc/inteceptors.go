@@ -5,6 +5,8 @@ import (
 	"net"
 	"time"
 
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/utils"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
@@ -13,14 +15,23 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// adminMethods - методы gRPC, доступные только по токену администратора.
+var adminMethods = map[string]struct{}{
+	pb.Storage_Delete_FullMethodName:          {},
+	pb.Storage_DeleteByPattern_FullMethodName: {},
+	pb.Storage_Reset_FullMethodName:           {},
+}
+
 // Manager предназначен для управления interceptors.
 type Manager struct {
 	trustedSNet *net.IPNet
+	adminToken  string
 	l           *log.Logger
 }
 
 // NewManager создаёт новый объект Manager.
-func NewManager(trustedSNet *net.IPNet, l *log.Logger) *Manager {
+// Методы администрирования доступны только по токену adminToken; если он пуст, они запрещены.
+func NewManager(trustedSNet *net.IPNet, adminToken string, l *log.Logger) *Manager {
 	lg := log.StandardLogger()
 	if l != nil {
 		lg = l
@@ -28,6 +39,7 @@ func NewManager(trustedSNet *net.IPNet, l *log.Logger) *Manager {
 
 	return &Manager{
 		trustedSNet: trustedSNet,
+		adminToken:  adminToken,
 		l:           lg,
 	}
 }
@@ -69,10 +81,115 @@ func (itc *Manager) IPValidationInterceptor(
 	ctx context.Context, req interface{},
 	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
 ) (interface{}, error) {
-	if itc.trustedSNet == nil {
+	if err := itc.validateIP(ctx); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+// AdminInterceptor - выполняет проверку токена администратора в метаданных authorization ("Bearer <token>")
+// для методов администрирования.
+func (itc *Manager) AdminInterceptor(
+	ctx context.Context, req interface{},
+	info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if _, ok := adminMethods[info.FullMethod]; !ok {
 		return handler(ctx, req)
 	}
 
+	if itc.adminToken == "" {
+		return nil, status.Error(codes.PermissionDenied, "admin operations are disabled")
+	}
+
+	var authorization string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		values := md.Get("authorization")
+		if len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+
+	if !utils.ValidBearerToken(authorization, itc.adminToken) {
+		return nil, status.Error(codes.Unauthenticated, "invalid admin token")
+	}
+
+	return handler(ctx, req)
+}
+
+// LoggingStreamInterceptor - выполняет логгирование входящего потокового gRPC запроса.
+func (itc *Manager) LoggingStreamInterceptor(
+	srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	uuid := uuid.New()
+
+	uuidCtx := metadata.AppendToOutgoingContext(ss.Context(), "uuid", uuid.String())
+
+	itc.l.Infof("[%s] received gRPC stream: %s", uuid, info.FullMethod)
+
+	ts := time.Now()
+	err := handler(srv, &serverStream{ServerStream: ss, ctx: uuidCtx})
+
+	if err != nil {
+		st, _ := status.FromError(err)
+
+		itc.l.Printf(
+			"[%s] stream status: %d; duration: %s",
+			uuid, st.Code(), time.Since(ts),
+		)
+	} else {
+		itc.l.Printf(
+			"[%s] stream status: OK; duration: %s",
+			uuid, time.Since(ts),
+		)
+	}
+
+	return err
+}
+
+// serverStream - потоковый gRPC запрос с контекстом, заменённым interceptor.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context возвращает контекст потокового запроса.
+func (ss *serverStream) Context() context.Context {
+	return ss.ctx
+}
+
+// requestUUID возвращает идентификатор запроса, добавленный в контекст ctx interceptor логгирования.
+func requestUUID(ctx context.Context) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get("uuid"); len(values) > 0 {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// IPValidationStreamInterceptor - выполняет проверку IP отправителя потокового запроса
+// на соответствие доверенной подсети.
+func (itc *Manager) IPValidationStreamInterceptor(
+	srv interface{}, ss grpc.ServerStream,
+	info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	if err := itc.validateIP(ss.Context()); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// validateIP проверяет IP отправителя из метаданных запроса на соответствие доверенной подсети.
+func (itc *Manager) validateIP(ctx context.Context) error {
+	if itc.trustedSNet == nil {
+		return nil
+	}
+
 	var ipStr string
 
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
@@ -84,12 +201,12 @@ func (itc *Manager) IPValidationInterceptor(
 
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
-		return nil, status.Error(codes.PermissionDenied, "access is denied by IP")
+		return status.Error(codes.PermissionDenied, "access is denied by IP")
 	}
 
 	if !itc.trustedSNet.Contains(ip) {
-		return nil, status.Error(codes.PermissionDenied, "access is denied by IP")
+		return status.Error(codes.PermissionDenied, "access is denied by IP")
 	}
 
-	return handler(ctx, req)
+	return nil
 }
@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminInterceptor(t *testing.T) {
+	const token = "secret"
+
+	tests := []struct {
+		name          string
+		adminToken    string
+		method        string
+		authorization string
+		code          codes.Code
+	}{
+		{
+			name:       "Regular method",
+			adminToken: token,
+			method:     pb.Storage_Metric_FullMethodName,
+			code:       codes.OK,
+		},
+		{
+			name:          "Admin method with token",
+			adminToken:    token,
+			method:        pb.Storage_Delete_FullMethodName,
+			authorization: "Bearer " + token,
+			code:          codes.OK,
+		},
+		{
+			name:          "Admin method with wrong token",
+			adminToken:    token,
+			method:        pb.Storage_DeleteByPattern_FullMethodName,
+			authorization: "Bearer wrong",
+			code:          codes.Unauthenticated,
+		},
+		{
+			name:       "Admin method without token",
+			adminToken: token,
+			method:     pb.Storage_Reset_FullMethodName,
+			code:       codes.Unauthenticated,
+		},
+		{
+			name:          "Admin operations disabled",
+			method:        pb.Storage_Reset_FullMethodName,
+			authorization: "Bearer ",
+			code:          codes.PermissionDenied,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itc := NewManager(nil, test.adminToken, nil)
+
+			ctx := context.Background()
+			if test.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", test.authorization))
+			}
+
+			called := false
+			handler := func(context.Context, interface{}) (interface{}, error) {
+				called = true
+
+				return struct{}{}, nil
+			}
+
+			_, err := itc.AdminInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.method}, handler)
+			assert.Equal(t, test.code, status.Code(err))
+			assert.Equal(t, test.code == codes.OK, called)
+		})
+	}
+}
+
+// testServerStream - потоковый gRPC запрос с заданным контекстом.
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (ss *testServerStream) Context() context.Context {
+	return ss.ctx
+}
+
+func TestLoggingStreamInterceptor(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		l   = log.New()
+	)
+
+	l.SetOutput(&buf)
+
+	itc := NewManager(nil, "", l)
+
+	var uuid string
+
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		uuid = requestUUID(stream.Context())
+
+		return nil
+	}
+
+	err := itc.LoggingStreamInterceptor(
+		nil, &testServerStream{ctx: context.Background()},
+		&grpc.StreamServerInfo{FullMethod: pb.Storage_StreamUpdates_FullMethodName}, handler,
+	)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, uuid)
+	assert.Contains(t, buf.String(), "["+uuid+"] received gRPC stream")
+}
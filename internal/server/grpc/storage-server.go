@@ -5,13 +5,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 
 	pb "github.com/KryukovO/metricscollector/api/serverpb"
 	"github.com/KryukovO/metricscollector/internal/metric"
 	"github.com/KryukovO/metricscollector/internal/storage"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -46,7 +49,7 @@ func NewStorageServer(s storage.Storage, l *log.Logger) (*StorageServer, error)
 
 // Update выполняет обновление единственной метрики.
 func (s *StorageServer) Update(ctx context.Context, req *pb.UpdateRequest) (*emptypb.Empty, error) {
-	uuid := ctx.Value("uuid")
+	uuid := requestUUID(ctx)
 
 	var (
 		val interface{}
@@ -57,7 +60,9 @@ func (s *StorageServer) Update(ctx context.Context, req *pb.UpdateRequest) (*emp
 	case pb.MetricType_COUNTER:
 		val = req.GetMetric().GetDelta()
 	case pb.MetricType_GAUGE:
-		val = float64(req.GetMetric().GetValue())
+		val = gaugeFromGRPC(req.GetMetric())
+	case pb.MetricType_HISTOGRAM:
+		val = metric.HistogramFromGRPC(req.GetMetric().GetHistogram())
 	default:
 		s.l.Debugf("[%s] %s", uuid, metric.ErrWrongMetricType)
 
@@ -78,8 +83,10 @@ func (s *StorageServer) Update(ctx context.Context, req *pb.UpdateRequest) (*emp
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	mtrc.Labels = labelsFromGRPC(req.GetMetric().GetLabels())
+
 	err = s.storage.Update(ctx, &mtrc)
-	if errors.Is(err, metric.ErrWrongMetricName) ||
+	if errors.Is(err, metric.ErrWrongMetricName) || errors.Is(err, metric.ErrWrongMetricLabels) ||
 		errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
 		s.l.Debugf("[%s] %s", uuid, err.Error())
 
@@ -97,24 +104,67 @@ func (s *StorageServer) Update(ctx context.Context, req *pb.UpdateRequest) (*emp
 
 // UpdateMany выполняет обновления набора метрик.
 func (s *StorageServer) UpdateMany(ctx context.Context, req *pb.UpdateManyRequest) (*emptypb.Empty, error) {
-	uuid := ctx.Value("uuid")
+	if err := s.updateMany(ctx, metadataValue(ctx, metric.BatchIDHeader), req.GetMetrics()); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// StreamUpdates принимает поток наборов метрик для обновления и подтверждает обработку каждого набора.
+// Ошибка обработки набора передаётся в подтверждении и не прерывает поток.
+func (s *StorageServer) StreamUpdates(stream pb.Storage_StreamUpdatesServer) error {
+	ctx := stream.Context()
+
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		resp := &pb.StreamUpdateResponse{Seq: req.GetSeq()}
+
+		if err = s.updateMany(ctx, req.GetBatchId(), req.GetMetrics()); err != nil {
+			st, _ := status.FromError(err)
+
+			resp.Code = uint32(st.Code())
+			resp.Message = st.Message()
+		}
+
+		if err = stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// updateMany выполняет обновление набора метрик из запроса gRPC.
+// Повторно отправленный агентом набор с тем же идентификатором batchID не применяется.
+// Возвращаемая ошибка содержит статус gRPC.
+func (s *StorageServer) updateMany(ctx context.Context, batchID string, descrs []*pb.MetricDescr) error {
+	uuid := requestUUID(ctx)
 
 	var (
 		val     interface{}
-		metrics = make([]metric.Metrics, 0, len(req.GetMetrics()))
+		metrics = make([]metric.Metrics, 0, len(descrs))
 		err     error
 	)
 
-	for _, mtrc := range req.GetMetrics() {
+	for _, mtrc := range descrs {
 		switch mtrc.GetType() {
 		case pb.MetricType_COUNTER:
 			val = mtrc.GetDelta()
 		case pb.MetricType_GAUGE:
-			val = float64(mtrc.GetValue())
+			val = gaugeFromGRPC(mtrc)
+		case pb.MetricType_HISTOGRAM:
+			val = metric.HistogramFromGRPC(mtrc.GetHistogram())
 		default:
 			s.l.Debugf("[%s] %s", uuid, metric.ErrWrongMetricType)
 
-			return nil, status.Error(codes.InvalidArgument, metric.ErrWrongMetricType.Error())
+			return status.Error(codes.InvalidArgument, metric.ErrWrongMetricType.Error())
 		}
 
 		m, err := metric.NewMetrics(mtrc.GetId(), metric.MapGRPCToMetricType[mtrc.GetType()], val)
@@ -122,41 +172,64 @@ func (s *StorageServer) UpdateMany(ctx context.Context, req *pb.UpdateManyReques
 			errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
 			s.l.Debugf("[%s] %s", uuid, err.Error())
 
-			return nil, status.Error(codes.InvalidArgument, err.Error())
+			return status.Error(codes.InvalidArgument, err.Error())
 		}
 
 		if err != nil {
 			s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
-			return nil, status.Error(codes.Internal, err.Error())
+			return status.Error(codes.Internal, err.Error())
 		}
 
+		m.Labels = labelsFromGRPC(mtrc.GetLabels())
+
 		metrics = append(metrics, m)
 	}
 
-	err = s.storage.UpdateMany(ctx, metrics)
-	if errors.Is(err, metric.ErrWrongMetricName) ||
+	applied, err := s.storage.UpdateBatch(ctx, metric.Batch{ID: batchID, Metrics: metrics})
+	if errors.Is(err, metric.ErrWrongMetricName) || errors.Is(err, metric.ErrWrongMetricLabels) ||
 		errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
 		s.l.Debugf("[%s] %s", uuid, err.Error())
 
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return status.Error(codes.InvalidArgument, err.Error())
 	}
 
 	if err != nil {
 		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
-		return nil, status.Error(codes.Internal, err.Error())
+		return status.Error(codes.Internal, err.Error())
 	}
 
-	return &emptypb.Empty{}, nil
+	if !applied {
+		s.l.Debugf("[%s] batch %s has already been applied", uuid, batchID)
+	}
+
+	return nil
+}
+
+// metadataValue возвращает первое значение ключа key из метаданных входящего запроса.
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
 }
 
 // Metric возвращает описание метрики из хранилища.
 func (s *StorageServer) Metric(ctx context.Context, req *pb.MetricRequest) (*pb.MetricResponse, error) {
-	uuid := ctx.Value("uuid")
+	uuid := requestUUID(ctx)
 
-	v, err := s.storage.GetValue(ctx, metric.MetricType(req.GetType()), req.GetId())
-	if errors.Is(err, metric.ErrWrongMetricType) {
+	v, err := s.storage.GetValue(
+		ctx, metric.MapGRPCToMetricType[req.GetType()], req.GetId(), labelsFromGRPC(req.GetLabels()),
+	)
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricLabels) {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
@@ -172,28 +245,40 @@ func (s *StorageServer) Metric(ctx context.Context, req *pb.MetricRequest) (*pb.
 		return nil, status.Error(codes.NotFound, msg)
 	}
 
-	resp := &pb.MetricResponse{
-		Metric: &pb.MetricDescr{
-			Id:   v.ID,
-			Type: metric.MapMetricTypeToGRPC[v.MType],
-		},
+	return &pb.MetricResponse{Metric: metricToGRPC(v)}, nil
+}
+
+// AllMetrics описание всех метрик из хранилища.
+func (s *StorageServer) AllMetrics(ctx context.Context, _ *emptypb.Empty) (*pb.AllMetricsResponse, error) {
+	uuid := requestUUID(ctx)
+
+	values, err := s.storage.GetAll(ctx)
+	if err != nil {
+		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	switch {
-	case v.Delta != nil:
-		resp.Metric.Delta = *v.Delta
-	case v.Value != nil:
-		resp.Metric.Value = float32(*v.Value)
+	resp := &pb.AllMetricsResponse{
+		Metrics: make([]*pb.MetricDescr, 0, len(values)),
+	}
+
+	for i := range values {
+		resp.Metrics = append(resp.GetMetrics(), metricToGRPC(&values[i]))
 	}
 
 	return resp, nil
 }
 
-// AllMetrics описание всех метрик из хранилища.
-func (s *StorageServer) AllMetrics(ctx context.Context, _ *emptypb.Empty) (*pb.AllMetricsResponse, error) {
-	uuid := ctx.Value("uuid")
+// MetricsByLabels возвращает описание метрик, набор меток которых содержит все переданные метки.
+func (s *StorageServer) MetricsByLabels(ctx context.Context, req *pb.LabelsRequest) (*pb.AllMetricsResponse, error) {
+	uuid := requestUUID(ctx)
+
+	values, err := s.storage.GetByLabels(ctx, labelsFromGRPC(req.GetLabels()))
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-	values, err := s.storage.GetAll(ctx)
 	if err != nil {
 		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
@@ -204,21 +289,162 @@ func (s *StorageServer) AllMetrics(ctx context.Context, _ *emptypb.Empty) (*pb.A
 		Metrics: make([]*pb.MetricDescr, 0, len(values)),
 	}
 
-	for _, val := range values {
-		mtrc := &pb.MetricDescr{
-			Id:   val.ID,
-			Type: metric.MapMetricTypeToGRPC[val.MType],
-		}
+	for i := range values {
+		resp.Metrics = append(resp.GetMetrics(), metricToGRPC(&values[i]))
+	}
 
-		switch {
-		case val.Delta != nil:
-			mtrc.Delta = *val.Delta
-		case val.Value != nil:
-			mtrc.Value = float32(*val.Value)
-		}
+	return resp, nil
+}
+
+// QueryRange возвращает значения метрики за интервал времени, агрегированные по шагам.
+// Начало и окончание интервала обязательны.
+func (s *StorageServer) QueryRange(ctx context.Context, req *pb.RangeRequest) (*pb.RangeResponse, error) {
+	uuid := requestUUID(ctx)
 
-		resp.Metrics = append(resp.GetMetrics(), mtrc)
+	if req.GetFrom() == nil || req.GetTo() == nil {
+		return nil, status.Error(codes.InvalidArgument, "time range is required")
+	}
+
+	points, err := s.storage.GetRange(ctx, metric.RangeQuery{
+		MType:       metric.MapGRPCToMetricType[req.GetType()],
+		Name:        req.GetId(),
+		Labels:      labelsFromGRPC(req.GetLabels()),
+		From:        req.GetFrom().AsTime(),
+		To:          req.GetTo().AsTime(),
+		Step:        req.GetStep().AsDuration(),
+		Aggregation: metric.Aggregation(req.GetAggregation()),
+	})
+	if errors.Is(err, metric.ErrWrongRangeQuery) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err != nil {
+		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	resp := &pb.RangeResponse{
+		Points: make([]*pb.Point, 0, len(points)),
+	}
+
+	for _, p := range points {
+		resp.Points = append(resp.GetPoints(), &pb.Point{Timestamp: timestamppb.New(p.Timestamp), Value: p.Value})
 	}
 
 	return resp, nil
 }
+
+// Delete удаляет метрику вместе с её историей.
+func (s *StorageServer) Delete(ctx context.Context, req *pb.MetricRequest) (*emptypb.Empty, error) {
+	uuid := requestUUID(ctx)
+
+	deleted, err := s.storage.Delete(
+		ctx, metric.MapGRPCToMetricType[req.GetType()], req.GetId(), labelsFromGRPC(req.GetLabels()),
+	)
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricLabels) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err != nil {
+		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if !deleted {
+		msg := fmt.Sprintf("metric with name '%s' not found", req.GetId())
+
+		return nil, status.Error(codes.NotFound, msg)
+	}
+
+	s.l.Infof("[%s] metric '%s' of type '%s' deleted", uuid, req.GetId(), metric.MapGRPCToMetricType[req.GetType()])
+
+	return &emptypb.Empty{}, nil
+}
+
+// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону.
+func (s *StorageServer) DeleteByPattern(ctx context.Context, req *pb.PatternRequest) (*pb.DeleteResponse, error) {
+	uuid := requestUUID(ctx)
+
+	deleted, err := s.storage.DeleteByPattern(ctx, req.GetPattern())
+	if errors.Is(err, metric.ErrWrongNamePattern) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err != nil {
+		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	s.l.Infof("[%s] %d metrics matching '%s' deleted", uuid, deleted, req.GetPattern())
+
+	return &pb.DeleteResponse{Deleted: deleted}, nil
+}
+
+// Reset обнуляет значение метрики типа counter.
+func (s *StorageServer) Reset(ctx context.Context, req *pb.ResetRequest) (*emptypb.Empty, error) {
+	uuid := requestUUID(ctx)
+
+	reset, err := s.storage.Reset(ctx, req.GetId(), labelsFromGRPC(req.GetLabels()))
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err != nil {
+		s.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	if !reset {
+		msg := fmt.Sprintf("counter with name '%s' not found", req.GetId())
+
+		return nil, status.Error(codes.NotFound, msg)
+	}
+
+	s.l.Infof("[%s] counter '%s' reset", uuid, req.GetId())
+
+	return &emptypb.Empty{}, nil
+}
+
+// metricToGRPC преобразует метрику в её описание для ответа gRPC.
+func metricToGRPC(v *metric.Metrics) *pb.MetricDescr {
+	mtrc := &pb.MetricDescr{
+		Id:     v.ID,
+		Type:   metric.MapMetricTypeToGRPC[v.MType],
+		Labels: v.Labels,
+	}
+
+	switch {
+	case v.Delta != nil:
+		mtrc.Delta = *v.Delta
+	case v.Value != nil:
+		mtrc.Value = float32(*v.Value)
+		mtrc.ValueDouble = v.Value
+	case v.Histogram != nil:
+		mtrc.Histogram = metric.HistogramToGRPC(v.Histogram)
+	}
+
+	return mtrc
+}
+
+// gaugeFromGRPC возвращает значение метрики типа gauge из её описания в запросе gRPC.
+// Значение value_double имеет приоритет над устаревшим полем value одинарной точности.
+func gaugeFromGRPC(descr *pb.MetricDescr) float64 {
+	if descr.ValueDouble != nil {
+		return descr.GetValueDouble()
+	}
+
+	return float64(descr.GetValue())
+}
+
+// labelsFromGRPC преобразует набор меток из запроса gRPC в metric.Labels.
+func labelsFromGRPC(labels map[string]string) metric.Labels {
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return labels
+}
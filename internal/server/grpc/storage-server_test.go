@@ -0,0 +1,46 @@
+package grpc
+
+import (
+	"testing"
+
+	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGaugeFromGRPC(t *testing.T) {
+	value := 1234567890.123456
+
+	tests := []struct {
+		name     string
+		descr    *pb.MetricDescr
+		expected float64
+	}{
+		{
+			name:     "Legacy float value",
+			descr:    &pb.MetricDescr{Value: 1.5},
+			expected: 1.5,
+		},
+		{
+			name:     "Double value",
+			descr:    &pb.MetricDescr{Value: float32(value), ValueDouble: &value},
+			expected: value,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, gaugeFromGRPC(test.descr))
+		})
+	}
+}
+
+func TestMetricToGRPC(t *testing.T) {
+	value := 1234567890.123456
+
+	descr := metricToGRPC(&metric.Metrics{ID: "TotalAlloc", MType: metric.GaugeMetric, Value: &value})
+
+	assert.Equal(t, pb.MetricType_GAUGE, descr.GetType())
+	assert.Equal(t, value, descr.GetValueDouble())
+	assert.Equal(t, float32(value), descr.GetValue())
+}
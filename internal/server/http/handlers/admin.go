@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/utils"
+
+	"github.com/labstack/echo"
+)
+
+// adminOnly возвращает обработчик, выполняющий next только для запросов с токеном администратора
+// в заголовке Authorization ("Bearer <token>").
+// Если токен администратора не задан, возвращается 403 Forbidden, если токен запроса неверен - 401 Unauthorized.
+func (c *StorageController) adminOnly(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(e echo.Context) error {
+		uuid := e.Get("uuid")
+
+		if c.adminToken == "" {
+			c.l.Debugf("[%s] admin operations are disabled", uuid)
+
+			return e.NoContent(http.StatusForbidden)
+		}
+
+		if !utils.ValidBearerToken(e.Request().Header.Get(echo.HeaderAuthorization), c.adminToken) {
+			c.l.Infof("[%s] admin operation is denied: invalid token", uuid)
+
+			return e.NoContent(http.StatusUnauthorized)
+		}
+
+		return next(e)
+	}
+}
+
+// deleteHandler представляет собой обработчик запроса на удаление единственной метрики вместе с её историей.
+// Параметры метрики передаются через URL, метки - через параметр запроса labels.
+func (c *StorageController) deleteHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	labels, err := queryLabels(e)
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	deleted, err := c.storage.Delete(
+		e.Request().Context(), metric.MetricType(e.Param("mtype")), e.Param("mname"), labels,
+	)
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricLabels) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	if !deleted {
+		return e.NoContent(http.StatusNotFound)
+	}
+
+	c.l.Infof("[%s] metric '%s' of type '%s' deleted", uuid, e.Param("mname"), e.Param("mtype"))
+
+	return e.NoContent(http.StatusOK)
+}
+
+// deleteByPatternHandler представляет собой обработчик запроса на удаление всех метрик,
+// имена которых соответствуют шаблону из URL (см. metric.MatchName), вместе с их историей.
+// Количество удалённых метрик возвращается в теле ответа.
+func (c *StorageController) deleteByPatternHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	pattern := e.Param("pattern")
+
+	deleted, err := c.storage.DeleteByPattern(e.Request().Context(), pattern)
+	if errors.Is(err, metric.ErrWrongNamePattern) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	c.l.Infof("[%s] %d metrics matching '%s' deleted", uuid, deleted, pattern)
+
+	return e.String(http.StatusOK, strconv.FormatInt(deleted, 10))
+}
+
+// resetHandler представляет собой обработчик запроса на обнуление метрики типа counter.
+// Имя метрики передаётся через URL, метки - через параметр запроса labels.
+func (c *StorageController) resetHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	labels, err := queryLabels(e)
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	reset, err := c.storage.Reset(e.Request().Context(), e.Param("mname"), labels)
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	if !reset {
+		return e.NoContent(http.StatusNotFound)
+	}
+
+	c.l.Infof("[%s] counter '%s' reset", uuid, e.Param("mname"))
+
+	return e.NoContent(http.StatusOK)
+}
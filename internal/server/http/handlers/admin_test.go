@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandlers(t *testing.T) {
+	const token = "secret"
+
+	type handler func(c *StorageController) echo.HandlerFunc
+
+	var (
+		deleteOne = func(c *StorageController) echo.HandlerFunc { return c.adminOnly(c.deleteHandler) }
+		deleteAll = func(c *StorageController) echo.HandlerFunc { return c.adminOnly(c.deleteByPatternHandler) }
+		reset     = func(c *StorageController) echo.HandlerFunc { return c.adminOnly(c.resetHandler) }
+	)
+
+	tests := []struct {
+		name          string
+		adminToken    string
+		authorization string
+		method        string
+		url           string
+		params        []string
+		handler       handler
+		status        int
+		body          string
+		remaining     []string
+		reset         bool // признак обнуления счётчика PollCount
+	}{
+		{
+			name:          "Delete metric",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodDelete,
+			url:           "/value/gauge/RandomValue",
+			params:        []string{"mtype", "mname"},
+			handler:       deleteOne,
+			status:        http.StatusOK,
+			remaining:     []string{"PollCount"},
+		},
+		{
+			name:          "Delete unknown metric",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodDelete,
+			url:           "/value/counter/RandomValue",
+			params:        []string{"mtype", "mname"},
+			handler:       deleteOne,
+			status:        http.StatusNotFound,
+			remaining:     []string{"PollCount", "RandomValue"},
+		},
+		{
+			name:          "Delete metric of wrong type",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodDelete,
+			url:           "/value/unknown/RandomValue",
+			params:        []string{"mtype", "mname"},
+			handler:       deleteOne,
+			status:        http.StatusBadRequest,
+			remaining:     []string{"PollCount", "RandomValue"},
+		},
+		{
+			name:          "Delete by pattern",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodDelete,
+			url:           "/values/*Count",
+			params:        []string{"pattern"},
+			handler:       deleteAll,
+			status:        http.StatusOK,
+			body:          "1",
+			remaining:     []string{"RandomValue"},
+		},
+		{
+			name:          "Reset counter",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodPost,
+			url:           "/reset/PollCount",
+			params:        []string{"mname"},
+			handler:       reset,
+			status:        http.StatusOK,
+			remaining:     []string{"PollCount", "RandomValue"},
+			reset:         true,
+		},
+		{
+			name:          "Reset gauge",
+			adminToken:    token,
+			authorization: "Bearer " + token,
+			method:        http.MethodPost,
+			url:           "/reset/RandomValue",
+			params:        []string{"mname"},
+			handler:       reset,
+			status:        http.StatusNotFound,
+			remaining:     []string{"PollCount", "RandomValue"},
+		},
+		{
+			name:       "Missing token",
+			adminToken: token,
+			method:     http.MethodDelete,
+			url:        "/values/*",
+			params:     []string{"pattern"},
+			handler:    deleteAll,
+			status:     http.StatusUnauthorized,
+			remaining:  []string{"PollCount", "RandomValue"},
+		},
+		{
+			name:          "Wrong token",
+			adminToken:    token,
+			authorization: "Bearer wrong",
+			method:        http.MethodDelete,
+			url:           "/values/*",
+			params:        []string{"pattern"},
+			handler:       deleteAll,
+			status:        http.StatusUnauthorized,
+			remaining:     []string{"PollCount", "RandomValue"},
+		},
+		{
+			name:          "Admin operations disabled",
+			authorization: "Bearer ",
+			method:        http.MethodDelete,
+			url:           "/values/*",
+			params:        []string{"pattern"},
+			handler:       deleteAll,
+			status:        http.StatusForbidden,
+			remaining:     []string{"PollCount", "RandomValue"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, test.method, test.url, nil, test.params)
+			require.NoError(t, err)
+
+			if test.authorization != "" {
+				ctx.Request().Header.Set(echo.HeaderAuthorization, test.authorization)
+			}
+
+			repo, err := newTestRepo(false)
+			require.NoError(t, err)
+
+			c, err := NewStorageController(
+				storage.NewMetricsStorage(repo, 10*time.Second), test.adminToken, logrus.StandardLogger(),
+			)
+			require.NoError(t, err)
+			require.NoError(t, test.handler(c)(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.status, res.StatusCode)
+
+			if test.body != "" {
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.Equal(t, test.body, string(body))
+			}
+
+			values, err := repo.GetAll(context.Background())
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(values))
+			for _, v := range values {
+				names = append(names, v.ID)
+
+				if v.ID == "PollCount" && test.reset {
+					assert.EqualValues(t, 0, *v.Delta)
+				}
+			}
+
+			assert.ElementsMatch(t, test.remaining, names)
+		})
+	}
+}
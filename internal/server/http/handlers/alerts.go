@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
+
+	"github.com/labstack/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// AlertController представляет собой контроллер оповещений.
+type AlertController struct {
+	alerts *alerting.Manager
+	l      *log.Logger
+}
+
+// NewAlertController создаёт новый контроллер оповещений.
+// Если подсистема оповещений alerts не передана, список оповещений всегда пуст.
+func NewAlertController(alerts *alerting.Manager, l *log.Logger) *AlertController {
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	return &AlertController{alerts: alerts, l: lg}
+}
+
+// MapAlertHandlers выполняет маппинг маршрутов и обработчиков оповещений в маршрутизатор echo.
+func MapAlertHandlers(router *echo.Router, c *AlertController) error {
+	if router == nil {
+		return ErrRouterIsNil
+	}
+
+	if c == nil {
+		return ErrControllerIsNil
+	}
+
+	router.Add(http.MethodGet, "/api/v1/alerts", c.alertsHandler)
+
+	return nil
+}
+
+// alertsResponse описывает ответ на запрос списка оповещений.
+type alertsResponse struct {
+	Alerts []alerting.Alert `json:"alerts"` // Оповещения
+}
+
+// alertsHandler представляет собой обработчик запроса активных и недавно разрешённых оповещений.
+// Результат возвращается в формате JSON.
+func (c *AlertController) alertsHandler(e echo.Context) error {
+	resp := &alertsResponse{Alerts: make([]alerting.Alert, 0)}
+
+	if c.alerts != nil {
+		resp.Alerts = c.alerts.Alerts()
+	}
+
+	return e.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertsHandler(t *testing.T) {
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	alerts, err := alerting.NewManager(
+		storage.NewMetricsStorage(repo, 10*time.Second),
+		[]alerting.Rule{{Name: "HighRandomValue", Expr: "RandomValue", Op: ">", Threshold: 100}},
+		nil, time.Second, nil,
+	)
+	require.NoError(t, err)
+
+	alerts.Evaluate(context.Background(), time.Now())
+
+	tests := []struct {
+		name     string
+		alerts   *alerting.Manager
+		expected []string
+	}{
+		{
+			name:     "Alerting disabled",
+			expected: []string{},
+		},
+		{
+			name:     "Firing alert",
+			alerts:   alerts,
+			expected: []string{"HighRandomValue"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := NewAlertController(test.alerts, nil)
+
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, http.MethodGet, "/api/v1/alerts", nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, c.alertsHandler(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, http.StatusOK, res.StatusCode)
+
+			var resp alertsResponse
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+			rules := make([]string, 0, len(resp.Alerts))
+			for _, alert := range resp.Alerts {
+				rules = append(rules, alert.Rule)
+				assert.Equal(t, alerting.StateFiring, alert.State)
+			}
+
+			assert.Equal(t, test.expected, rules)
+		})
+	}
+}
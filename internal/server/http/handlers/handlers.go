@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net"
 
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
 	"github.com/KryukovO/metricscollector/internal/server/http/middleware"
 	"github.com/KryukovO/metricscollector/internal/storage"
 	"github.com/labstack/echo"
@@ -20,11 +21,11 @@ var (
 )
 
 // SetHandlers инициирует маппинг маршрутов и обработчиков в инстанс echo,
-// а также выстраивает цепочку middleware.
+// а также выстраивает цепочку middleware. Подсистема оповещений alerts может быть не передана.
 func SetHandlers(
-	e *echo.Echo, s storage.Storage,
+	e *echo.Echo, s storage.Storage, alerts *alerting.Manager,
 	key []byte, privateKey *rsa.PrivateKey, trustedSNet *net.IPNet,
-	l *log.Logger,
+	adminToken string, l *log.Logger,
 ) error {
 	if e == nil {
 		return ErrServerIsNil
@@ -34,12 +35,15 @@ func SetHandlers(
 		return ErrStorageIsNil
 	}
 
-	ctrl, err := NewStorageController(s, l)
+	ctrl, err := NewStorageController(s, adminToken, l)
 	if err != nil {
 		return err
 	}
 
 	mw := middleware.NewManager(key, privateKey, trustedSNet, l)
+	// Telegraf и экспортёры OTLP/HTTP не поддерживают шифрование тела запроса;
+	// запросы защищаются подписью и проверкой IP
+	mw.SkipDecryption("/write", "/v1/metrics")
 	e.Use(
 		mw.LoggingMiddleware,
 		mw.IPValidationMiddleware,
@@ -48,5 +52,9 @@ func SetHandlers(
 		mw.RSAMiddleware,
 	)
 
-	return MapStorageHandlers(e.Router(), ctrl)
+	if err := MapStorageHandlers(e.Router(), ctrl); err != nil {
+		return err
+	}
+
+	return MapAlertHandlers(e.Router(), NewAlertController(alerts, l))
 }
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHandlersEncryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	repo, err := memstorage.NewMemStorage(
+		context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil,
+	)
+	require.NoError(t, err)
+
+	stor := storage.NewMetricsStorage(repo, 0)
+	defer stor.Close()
+
+	e := echo.New()
+	require.NoError(t, SetHandlers(e, stor, nil, nil, privateKey, nil, "", nil))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		cType  string
+		status int
+	}{
+		{name: "Prometheus exposition", method: http.MethodGet, target: "/metrics", status: http.StatusOK},
+		{name: "Metrics listing", method: http.MethodGet, target: "/api/v1/metrics", status: http.StatusOK},
+		{
+			name:   "Range query",
+			method: http.MethodGet,
+			target: "/api/v1/query_range?type=gauge&name=Alloc",
+			status: http.StatusOK,
+		},
+		{name: "Expression query", method: http.MethodGet, target: "/api/v1/query?query=1", status: http.StatusOK},
+		{name: "Alerts listing", method: http.MethodGet, target: "/api/v1/alerts", status: http.StatusOK},
+		{
+			name:   "InfluxDB line protocol write",
+			method: http.MethodPost,
+			target: "/write",
+			body:   "cpu,host=a usage=0.5",
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "OTLP/HTTP metrics export",
+			method: http.MethodPost,
+			target: "/v1/metrics",
+			body:   `{"resourceMetrics":[]}`,
+			cType:  otlpJSONContentType,
+			status: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.cType != "" {
+				req.Header.Set(echo.HeaderContentType, test.cType)
+			}
+			rec := httptest.NewRecorder()
+
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.status, rec.Code, "Request must not be decrypted")
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	"github.com/labstack/echo"
+)
+
+// ErrWrongInfluxLine возвращается, если строка не соответствует формату InfluxDB line protocol.
+var ErrWrongInfluxLine = errors.New("wrong line protocol")
+
+// influxLineError описывает ошибку разбора строки в ответе на запрос записи в формате InfluxDB.
+type influxLineError struct {
+	Line  int    `json:"line"`  // Номер строки, начиная с 1
+	Error string `json:"error"` // Описание ошибки
+}
+
+// influxWriteResponse описывает ответ на запрос записи в формате InfluxDB с ошибками разбора строк.
+type influxWriteResponse struct {
+	Written int               `json:"written"` // Количество записанных метрик
+	Errors  []influxLineError `json:"errors"`  // Ошибки разбора строк
+}
+
+// writeHandler представляет собой обработчик запроса на запись метрик в формате InfluxDB line protocol.
+// Метрики получают имена вида measurement_field; поля с числовыми (в том числе целыми: 1i, 1u)
+// и логическими значениями записываются как gauge, теги записываются как метки. Целые поля
+// не записываются как counter: Telegraf передаёт в них абсолютные значения, а не приращения.
+// Строковые поля и метки времени не сохраняются.
+//
+// Тело запроса не расшифровывается, даже если на сервере задан приватный ключ.
+//
+// Строки, содержащие ошибки, пропускаются, остальные записываются в хранилище.
+// Если все строки разобраны, возвращается 204 No Content, иначе - 400 Bad Request
+// с описанием ошибок по строкам в теле ответа.
+func (c *StorageController) writeHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	var (
+		mtrcs   = make([]metric.Metrics, 0)
+		lineErr = make([]influxLineError, 0)
+		scanner = bufio.NewScanner(bytes.NewReader(body))
+	)
+
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), len(body)+1)
+
+	for n := 1; scanner.Scan(); n++ {
+		lineMtrcs, err := parseInfluxLine(scanner.Text())
+		if err != nil {
+			lineErr = append(lineErr, influxLineError{Line: n, Error: err.Error()})
+
+			continue
+		}
+
+		mtrcs = append(mtrcs, lineMtrcs...)
+	}
+
+	if len(mtrcs) > 0 {
+		if err = c.storage.UpdateMany(e.Request().Context(), mtrcs); err != nil {
+			c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+			return e.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	if len(lineErr) > 0 {
+		c.l.Debugf("[%s] %d lines of line protocol were not parsed", uuid, len(lineErr))
+
+		return e.JSON(http.StatusBadRequest, &influxWriteResponse{Written: len(mtrcs), Errors: lineErr})
+	}
+
+	return e.NoContent(http.StatusNoContent)
+}
+
+// parseInfluxLine разбирает строку вида "measurement[,tag=value...] field=value[,field=value...] [timestamp]".
+// Для пустых строк и комментариев возвращается пустой набор метрик.
+func parseInfluxLine(line string) ([]metric.Metrics, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	sections := splitInflux(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return nil, fmt.Errorf("%w: expected measurement, fields and optional timestamp", ErrWrongInfluxLine)
+	}
+
+	if len(sections) == 3 {
+		if _, err := strconv.ParseInt(sections[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: wrong timestamp %q", ErrWrongInfluxLine, sections[2])
+		}
+	}
+
+	key := splitInflux(sections[0], ',', false)
+
+	measurement := unescapeInflux(key[0])
+	if measurement == "" {
+		return nil, fmt.Errorf("%w: empty measurement", ErrWrongInfluxLine)
+	}
+
+	var labels metric.Labels
+
+	for _, tag := range key[1:] {
+		name, value, ok := cutInflux(tag)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w: wrong tag %q", ErrWrongInfluxLine, tag)
+		}
+
+		if labels == nil {
+			labels = make(metric.Labels)
+		}
+
+		labels[name] = value
+	}
+
+	mtrcs := make([]metric.Metrics, 0)
+
+	for _, field := range splitInflux(sections[1], ',', true) {
+		name, value, ok := cutInflux(field)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("%w: wrong field %q", ErrWrongInfluxLine, field)
+		}
+
+		mtrc, ok, err := influxFieldValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", name, err)
+		}
+
+		if !ok {
+			continue
+		}
+
+		mtrc.ID = measurement + "_" + name
+		mtrc.Labels = labels
+		mtrcs = append(mtrcs, mtrc)
+	}
+
+	return mtrcs, nil
+}
+
+// influxFieldValue преобразует значение поля в метрику без имени.
+// Для строковых значений возвращается false.
+func influxFieldValue(value string) (metric.Metrics, bool, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		if len(value) < 2 || !strings.HasSuffix(value, `"`) {
+			return metric.Metrics{}, false, fmt.Errorf("%w: unterminated string %s", ErrWrongInfluxLine, value)
+		}
+
+		return metric.Metrics{}, false, nil
+
+	case strings.HasSuffix(value, "i"):
+		i, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+		if err != nil {
+			return metric.Metrics{}, false, fmt.Errorf("%w: wrong integer %s", ErrWrongInfluxLine, value)
+		}
+
+		v := float64(i)
+
+		return metric.Metrics{MType: metric.GaugeMetric, Value: &v}, true, nil
+
+	case strings.HasSuffix(value, "u"):
+		u, err := strconv.ParseUint(value[:len(value)-1], 10, 64)
+		if err != nil {
+			return metric.Metrics{}, false, fmt.Errorf("%w: wrong integer %s", ErrWrongInfluxLine, value)
+		}
+
+		v := float64(u)
+
+		return metric.Metrics{MType: metric.GaugeMetric, Value: &v}, true, nil
+	}
+
+	var v float64
+
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		v = 1
+	case "f", "F", "false", "False", "FALSE":
+		v = 0
+	default:
+		var err error
+
+		v, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return metric.Metrics{}, false, fmt.Errorf("%w: wrong float %s", ErrWrongInfluxLine, value)
+		}
+	}
+
+	return metric.Metrics{MType: metric.GaugeMetric, Value: &v}, true, nil
+}
+
+// splitInflux разделяет s по символу sep, не экранированному обратной косой чертой.
+// Если quoted, символы внутри строк в двойных кавычках не считаются разделителями.
+// Пустые части, возникающие при повторении sep, пропускаются.
+func splitInflux(s string, sep byte, quoted bool) []string {
+	var (
+		res      = make([]string, 0)
+		start    int
+		inQuotes bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '"' && quoted:
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			if i > start {
+				res = append(res, s[start:i])
+			}
+
+			start = i + 1
+		}
+	}
+
+	if start < len(s) {
+		res = append(res, s[start:])
+	}
+
+	if len(res) == 0 {
+		res = append(res, "")
+	}
+
+	return res
+}
+
+// cutInflux разделяет пару вида key=value по первому неэкранированному '=' и снимает экранирование с ключа.
+// Значения тегов также освобождаются от экранирования; значения полей (строки) не изменяются,
+// поскольку кавычки в них обрабатываются отдельно.
+func cutInflux(pair string) (string, string, bool) {
+	for i := 0; i < len(pair); i++ {
+		switch pair[i] {
+		case '\\':
+			i++
+		case '=':
+			value := pair[i+1:]
+			if !strings.HasPrefix(value, `"`) {
+				value = unescapeInflux(value)
+			}
+
+			return unescapeInflux(pair[:i]), value, true
+		}
+	}
+
+	return "", "", false
+}
+
+// unescapeInflux снимает экранирование символов ',', '=', ' ' и '\' обратной косой чертой.
+func unescapeInflux(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	builder := strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(`,= \`, s[i+1]) >= 0 {
+			i++
+		}
+
+		builder.WriteByte(s[i])
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInfluxLine(t *testing.T) {
+	var (
+		value   = 0.64
+		ints    = 42.0
+		one     = 1.0
+		maxUint = float64(math.MaxUint64)
+	)
+
+	tests := []struct {
+		name     string
+		line     string
+		expected []metric.Metrics
+		wantErr  bool
+	}{
+		{
+			name: "Fields of all types",
+			line: `cpu,host=server01,region=us-west usage=0.64,procs=42i,running=t,state="idle" 1434055562000000000`,
+			expected: []metric.Metrics{
+				{
+					ID: "cpu_usage", MType: metric.GaugeMetric, Value: &value,
+					Labels: metric.Labels{"host": "server01", "region": "us-west"},
+				},
+				{
+					ID: "cpu_procs", MType: metric.GaugeMetric, Value: &ints,
+					Labels: metric.Labels{"host": "server01", "region": "us-west"},
+				},
+				{
+					ID: "cpu_running", MType: metric.GaugeMetric, Value: &one,
+					Labels: metric.Labels{"host": "server01", "region": "us-west"},
+				},
+			},
+		},
+		{
+			name: "Escaped characters",
+			line: `disk\ io,path=C:\\,dev=sd\ a bytes\ read=42u,msg="a b,c=d"`,
+			expected: []metric.Metrics{
+				{
+					ID: "disk io_bytes read", MType: metric.GaugeMetric, Value: &ints,
+					Labels: metric.Labels{"path": `C:\`, "dev": "sd a"},
+				},
+			},
+		},
+		{
+			name: "Comment",
+			line: "# comment",
+		},
+		{
+			name:    "Missing fields",
+			line:    "cpu,host=server01",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong tag",
+			line:    "cpu,host usage=1",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong integer",
+			line:    "cpu procs=4.2i",
+			wantErr: true,
+		},
+		{
+			name: "Large unsigned integer",
+			line: "net bytes=18446744073709551615u",
+			expected: []metric.Metrics{
+				{ID: "net_bytes", MType: metric.GaugeMetric, Value: &maxUint},
+			},
+		},
+		{
+			name:    "Negative unsigned integer",
+			line:    "net bytes=-1u",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong float",
+			line:    "cpu usage=high",
+			wantErr: true,
+		},
+		{
+			name:    "Wrong timestamp",
+			line:    "cpu usage=1 now",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mtrcs, err := parseInfluxLine(test.line)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrWrongInfluxLine)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.ElementsMatch(t, test.expected, mtrcs)
+		})
+	}
+}
+
+func TestWriteHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		errors  []int
+		written int
+	}{
+		{
+			name:    "Correct body",
+			body:    "cpu,host=a usage=0.5,procs=3i\nmem used=1024i 1434055562000000000\n",
+			status:  http.StatusNoContent,
+			written: 3,
+		},
+		{
+			name:    "Lines with errors",
+			body:    "cpu,host=a usage=0.5\ncpu usage\nmem used=1024i\nmem used=1.5i",
+			status:  http.StatusBadRequest,
+			errors:  []int{2, 4},
+			written: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, http.MethodPost, "/write", strings.NewReader(test.body), nil)
+			require.NoError(t, err)
+
+			repo, err := newTestRepo(true)
+			require.NoError(t, err)
+
+			s := StorageController{
+				storage: storage.NewMetricsStorage(repo, 10*time.Second),
+				l:       logrus.StandardLogger(),
+			}
+			require.NoError(t, s.writeHandler(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, test.status, res.StatusCode)
+
+			if len(test.errors) > 0 {
+				var resp influxWriteResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+				lines := make([]int, 0, len(resp.Errors))
+				for _, lineErr := range resp.Errors {
+					lines = append(lines, lineErr.Line)
+				}
+
+				assert.Equal(t, test.errors, lines)
+				assert.Equal(t, test.written, resp.Written)
+			}
+
+			values, err := repo.GetAll(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, values, test.written)
+
+			v, err := repo.GetValue(context.Background(), metric.GaugeMetric, "cpu_usage", metric.Labels{"host": "a"})
+			require.NoError(t, err)
+			assert.Equal(t, 0.5, *v.Value)
+		})
+	}
+}
+
+func TestWriteHandlerIntegerFields(t *testing.T) {
+	repo, err := newTestRepo(true)
+	require.NoError(t, err)
+
+	s := StorageController{
+		storage: storage.NewMetricsStorage(repo, 10*time.Second),
+		l:       logrus.StandardLogger(),
+	}
+
+	// Telegraf передаёт в целых полях абсолютные значения, повторная запись не должна их суммировать
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		ctx, err := newEchoContext(rec, http.MethodPost, "/write", strings.NewReader("mem,host=a used=1024i"), nil)
+		require.NoError(t, err)
+		require.NoError(t, s.writeHandler(ctx))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	v, err := repo.GetValue(context.Background(), metric.GaugeMetric, "mem_used", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	require.NotNil(t, v.Value)
+	assert.Equal(t, 1024.0, *v.Value)
+}
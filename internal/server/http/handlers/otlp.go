@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+
+	"github.com/labstack/echo"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Типы содержимого запросов OTLP/HTTP.
+const (
+	otlpProtobufContentType = "application/x-protobuf"
+	otlpJSONContentType     = "application/json"
+)
+
+// otlpRejectedMessage - описание причины отклонения точек данных в ответе OTLP/HTTP.
+const otlpRejectedMessage = "unsupported data points: only gauges, sums and delta histograms are accepted"
+
+// otlpResponse описывает ответ OTLP/HTTP (ExportMetricsServiceResponse) в формате JSON.
+type otlpResponse struct {
+	PartialSuccess *otlpPartialSuccess `json:"partialSuccess,omitempty"`
+}
+
+// otlpPartialSuccess описывает точки данных, отклонённые при обработке запроса OTLP/HTTP.
+type otlpPartialSuccess struct {
+	RejectedDataPoints string `json:"rejectedDataPoints"` // int64 в формате OTLP/JSON передаётся строкой
+	ErrorMessage       string `json:"errorMessage"`
+}
+
+// otlpMetricsHandler представляет собой обработчик запроса OTLP/HTTP на запись метрик (POST /v1/metrics).
+// Тело запроса (ExportMetricsServiceRequest) передаётся в формате protobuf или JSON.
+//
+// Монотонные суммы с временной агрегацией delta записываются как counter, остальные суммы и gauge -
+// как gauge, гистограммы с временной агрегацией delta - как histogram. Атрибуты ресурса и точки данных
+// записываются как метки; атрибуты точки данных имеют приоритет. Точки данных других видов
+// отклоняются, их количество возвращается в поле partialSuccess ответа.
+//
+// Тело запроса не расшифровывается, даже если на сервере задан приватный ключ.
+func (c *StorageController) otlpMetricsHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	contentType, _, err := mime.ParseMediaType(e.Request().Header.Get(echo.HeaderContentType))
+	if err != nil || (contentType != otlpProtobufContentType && contentType != otlpJSONContentType) {
+		c.l.Debugf("[%s] unsupported OTLP content type: '%s'", uuid, e.Request().Header.Get(echo.HeaderContentType))
+
+		return e.NoContent(http.StatusUnsupportedMediaType)
+	}
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	// ExportMetricsServiceRequest совпадает с MetricsData по составу полей
+	var data metricspb.MetricsData
+
+	if contentType == otlpJSONContentType {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, &data)
+	} else {
+		err = proto.Unmarshal(body, &data)
+	}
+
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	mtrcs, rejected := otlpToMetrics(&data)
+
+	if len(mtrcs) > 0 {
+		err = c.storage.UpdateMany(e.Request().Context(), mtrcs)
+		if errors.Is(err, metric.ErrWrongMetricName) || errors.Is(err, metric.ErrWrongMetricType) ||
+			errors.Is(err, metric.ErrWrongMetricValue) || errors.Is(err, metric.ErrWrongMetricLabels) {
+			c.l.Debugf("[%s] %s", uuid, err.Error())
+
+			return e.NoContent(http.StatusBadRequest)
+		}
+
+		if err != nil {
+			c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+			return e.NoContent(http.StatusInternalServerError)
+		}
+	}
+
+	if contentType == otlpJSONContentType {
+		resp := &otlpResponse{}
+		if rejected > 0 {
+			resp.PartialSuccess = &otlpPartialSuccess{
+				RejectedDataPoints: strconv.FormatInt(rejected, 10),
+				ErrorMessage:       otlpRejectedMessage,
+			}
+		}
+
+		return e.JSON(http.StatusOK, resp)
+	}
+
+	return e.Blob(http.StatusOK, otlpProtobufContentType, marshalOTLPResponse(rejected))
+}
+
+// marshalOTLPResponse кодирует ExportMetricsServiceResponse с количеством отклонённых точек данных
+// rejected в формат protobuf.
+func marshalOTLPResponse(rejected int64) []byte {
+	if rejected == 0 {
+		return []byte{}
+	}
+
+	var msg []byte
+	msg = protowire.AppendTag(msg, 1, protowire.VarintType)
+	msg = protowire.AppendVarint(msg, uint64(rejected))
+	msg = protowire.AppendTag(msg, 2, protowire.BytesType)
+	msg = protowire.AppendString(msg, otlpRejectedMessage)
+
+	var res []byte
+	res = protowire.AppendTag(res, 1, protowire.BytesType)
+
+	return protowire.AppendBytes(res, msg)
+}
+
+// otlpToMetrics преобразует метрики OTLP в набор метрик хранилища.
+// Возвращает также количество отклонённых точек данных.
+func otlpToMetrics(data *metricspb.MetricsData) ([]metric.Metrics, int64) {
+	var (
+		res      = make([]metric.Metrics, 0)
+		rejected int64
+	)
+
+	for _, rm := range data.GetResourceMetrics() {
+		resourceLabels := otlpLabels(nil, rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				mtrcs, n := otlpMetric(m, resourceLabels)
+				res = append(res, mtrcs...)
+				rejected += n
+			}
+		}
+	}
+
+	return res, rejected
+}
+
+// otlpMetric преобразует метрику OTLP в набор метрик хранилища с метками ресурса resourceLabels.
+// Возвращает также количество отклонённых точек данных.
+func otlpMetric(m *metricspb.Metric, resourceLabels metric.Labels) ([]metric.Metrics, int64) {
+	var (
+		res      = make([]metric.Metrics, 0)
+		rejected int64
+	)
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			if mtrc, ok := otlpNumber(m.GetName(), dp, false, resourceLabels); ok {
+				res = append(res, mtrc)
+			}
+		}
+
+	case *metricspb.Metric_Sum:
+		counter := data.Sum.GetIsMonotonic() &&
+			data.Sum.GetAggregationTemporality() == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+
+		for _, dp := range data.Sum.GetDataPoints() {
+			if mtrc, ok := otlpNumber(m.GetName(), dp, counter, resourceLabels); ok {
+				res = append(res, mtrc)
+			}
+		}
+
+	case *metricspb.Metric_Histogram:
+		delta := data.Histogram.GetAggregationTemporality() ==
+			metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+
+		for _, dp := range data.Histogram.GetDataPoints() {
+			if otlpNoValue(dp.GetFlags()) {
+				continue
+			}
+
+			h := &metric.Histogram{
+				Bounds: dp.GetExplicitBounds(), Counts: dp.GetBucketCounts(), Sum: dp.GetSum(), Count: dp.GetCount(),
+			}
+
+			// Накопительные гистограммы не могут быть объединены с сохранёнными значениями
+			if !delta || h.Validate() != nil {
+				rejected++
+
+				continue
+			}
+
+			res = append(res, metric.Metrics{
+				ID: m.GetName(), MType: metric.HistogramMetric, Histogram: h,
+				Labels: otlpLabels(resourceLabels, dp.GetAttributes()),
+			})
+		}
+
+	case *metricspb.Metric_ExponentialHistogram:
+		rejected += int64(len(data.ExponentialHistogram.GetDataPoints()))
+
+	case *metricspb.Metric_Summary:
+		rejected += int64(len(data.Summary.GetDataPoints()))
+	}
+
+	return res, rejected
+}
+
+// otlpNumber преобразует числовую точку данных OTLP в метрику типа counter, если counter, иначе - gauge.
+// Для точек данных без значения возвращается false.
+func otlpNumber(
+	name string, dp *metricspb.NumberDataPoint, counter bool, resourceLabels metric.Labels,
+) (metric.Metrics, bool) {
+	if otlpNoValue(dp.GetFlags()) || dp.GetValue() == nil {
+		return metric.Metrics{}, false
+	}
+
+	mtrc := metric.Metrics{ID: name, Labels: otlpLabels(resourceLabels, dp.GetAttributes())}
+
+	var value float64
+
+	switch v := dp.GetValue().(type) {
+	case *metricspb.NumberDataPoint_AsInt:
+		value = float64(v.AsInt)
+	case *metricspb.NumberDataPoint_AsDouble:
+		value = v.AsDouble
+	}
+
+	if !counter {
+		mtrc.MType = metric.GaugeMetric
+		mtrc.Value = &value
+
+		return mtrc, true
+	}
+
+	delta := dp.GetAsInt()
+	if _, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsDouble); ok {
+		delta = int64(math.Round(value))
+	}
+
+	mtrc.MType = metric.CounterMetric
+	mtrc.Delta = &delta
+
+	return mtrc, true
+}
+
+// otlpNoValue проверяет, отмечена ли точка данных как не содержащая значения.
+func otlpNoValue(flags uint32) bool {
+	return flags&uint32(metricspb.DataPointFlags_FLAG_NO_RECORDED_VALUE) != 0
+}
+
+// otlpLabels дополняет набор меток base атрибутами attrs. Атрибуты имеют приоритет.
+// Атрибуты-массивы и вложенные наборы атрибутов пропускаются.
+func otlpLabels(base metric.Labels, attrs []*commonpb.KeyValue) metric.Labels {
+	if len(attrs) == 0 {
+		return base
+	}
+
+	res := base.Copy()
+	if res == nil {
+		res = make(metric.Labels, len(attrs))
+	}
+
+	for _, attr := range attrs {
+		var value string
+
+		switch v := attr.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			value = v.StringValue
+		case *commonpb.AnyValue_BoolValue:
+			value = strconv.FormatBool(v.BoolValue)
+		case *commonpb.AnyValue_IntValue:
+			value = strconv.FormatInt(v.IntValue, 10)
+		case *commonpb.AnyValue_DoubleValue:
+			value = strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+		default:
+			continue
+		}
+
+		if attr.GetKey() != "" {
+			res[attr.GetKey()] = value
+		}
+	}
+
+	if len(res) == 0 {
+		return nil
+	}
+
+	return res
+}
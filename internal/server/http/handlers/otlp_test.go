@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// otlpAttr создаёт строковый атрибут OTLP.
+func otlpAttr(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func TestOTLPToMetrics(t *testing.T) {
+	data := &metricspb.MetricsData{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource: &resourcepb.Resource{
+				Attributes: []*commonpb.KeyValue{otlpAttr("service.name", "api"), otlpAttr("host", "a")},
+			},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{
+					{
+						Name: "http.requests",
+						Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+							IsMonotonic:            true,
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+							DataPoints: []*metricspb.NumberDataPoint{{
+								Attributes: []*commonpb.KeyValue{otlpAttr("host", "b")},
+								Value:      &metricspb.NumberDataPoint_AsInt{AsInt: 7},
+							}},
+						}},
+					},
+					{
+						Name: "http.active",
+						Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							DataPoints: []*metricspb.NumberDataPoint{
+								{Value: &metricspb.NumberDataPoint_AsInt{AsInt: 3}},
+								{Flags: uint32(metricspb.DataPointFlags_FLAG_NO_RECORDED_VALUE)},
+							},
+						}},
+					},
+					{
+						Name: "memory.usage",
+						Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+							DataPoints: []*metricspb.NumberDataPoint{
+								{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 0.75}},
+							},
+						}},
+					},
+					{
+						Name: "http.duration",
+						Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+							DataPoints: []*metricspb.HistogramDataPoint{{
+								ExplicitBounds: []float64{0.1, 1}, BucketCounts: []uint64{1, 2, 0}, Count: 3, Sum: proto.Float64(1.3),
+							}},
+						}},
+					},
+					{
+						Name: "http.duration.cumulative",
+						Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+							AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+							DataPoints:             []*metricspb.HistogramDataPoint{{BucketCounts: []uint64{1}, Count: 1}},
+						}},
+					},
+					{
+						Name: "rpc.latency",
+						Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+							DataPoints: []*metricspb.SummaryDataPoint{{Count: 1}},
+						}},
+					},
+				},
+			}},
+		}},
+	}
+
+	mtrcs, rejected := otlpToMetrics(data)
+	assert.EqualValues(t, 2, rejected)
+	require.Len(t, mtrcs, 4)
+
+	assert.Equal(t, metric.CounterMetric, mtrcs[0].MType)
+	assert.EqualValues(t, 7, *mtrcs[0].Delta)
+	assert.Equal(t, metric.Labels{"service.name": "api", "host": "b"}, mtrcs[0].Labels)
+
+	assert.Equal(t, metric.GaugeMetric, mtrcs[1].MType, "Cumulative sum must be stored as gauge")
+	assert.Equal(t, 3.0, *mtrcs[1].Value)
+
+	assert.Equal(t, 0.75, *mtrcs[2].Value)
+	assert.Equal(t, metric.Labels{"service.name": "api", "host": "a"}, mtrcs[2].Labels)
+
+	assert.Equal(t, metric.HistogramMetric, mtrcs[3].MType)
+	assert.EqualValues(t, 3, mtrcs[3].Histogram.Count)
+}
+
+func TestOTLPMetricsHandler(t *testing.T) {
+	data := &metricspb.MetricsData{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Metrics: []*metricspb.Metric{{
+					Name: "queue.size",
+					Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+						DataPoints: []*metricspb.NumberDataPoint{{Value: &metricspb.NumberDataPoint_AsInt{AsInt: 12}}},
+					}},
+				}},
+			}},
+		}},
+	}
+
+	protoBody, err := proto.Marshal(data)
+	require.NoError(t, err)
+
+	jsonBody := `{"resourceMetrics":[{"scopeMetrics":[{"metrics":[
+		{"name":"queue.size","gauge":{"dataPoints":[{"asInt":"12"}]}},
+		{"name":"rpc.latency","summary":{"dataPoints":[{"count":"1"}]}}
+	]}]}]}`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		response    string
+	}{
+		{
+			name:        "Protobuf body",
+			contentType: "application/x-protobuf",
+			body:        protoBody,
+			status:      http.StatusOK,
+		},
+		{
+			name:        "JSON body with unsupported data points",
+			contentType: "application/json; charset=utf-8",
+			body:        []byte(jsonBody),
+			status:      http.StatusOK,
+			response:    "1",
+		},
+		{
+			name:        "Wrong protobuf body",
+			contentType: "application/x-protobuf",
+			body:        []byte("metrics"),
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "Unsupported content type",
+			contentType: "text/plain",
+			body:        []byte("queue.size 12"),
+			status:      http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, http.MethodPost, "/v1/metrics", bytes.NewReader(test.body), nil)
+			require.NoError(t, err)
+
+			ctx.Request().Header.Set(echo.HeaderContentType, test.contentType)
+
+			repo, err := newTestRepo(true)
+			require.NoError(t, err)
+
+			s := StorageController{
+				storage: storage.NewMetricsStorage(repo, 10*time.Second),
+				l:       logrus.StandardLogger(),
+			}
+			require.NoError(t, s.otlpMetricsHandler(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, test.status, res.StatusCode)
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			if strings.HasPrefix(test.contentType, "application/json") {
+				var resp otlpResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+				if test.response != "" {
+					require.NotNil(t, resp.PartialSuccess)
+					assert.Equal(t, test.response, resp.PartialSuccess.RejectedDataPoints)
+				}
+			}
+
+			v, err := repo.GetValue(context.Background(), metric.GaugeMetric, "queue.size", nil)
+			require.NoError(t, err)
+			assert.Equal(t, 12.0, *v.Value)
+		})
+	}
+}
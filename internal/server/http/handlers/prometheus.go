@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+// prometheusContentType - тип содержимого ответа в текстовом формате Prometheus.
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// prometheusTypes - маппинг MetricType в тип метрики Prometheus.
+var prometheusTypes = map[metric.MetricType]string{
+	metric.GaugeMetric:     "gauge",
+	metric.CounterMetric:   "counter",
+	metric.HistogramMetric: "histogram",
+}
+
+// prometheusName приводит имя метрики к виду, допустимому в Prometheus: [a-zA-Z_:][a-zA-Z0-9_:]*.
+// Недопустимые символы заменяются на '_', к имени, начинающемуся с цифры, добавляется префикс '_'.
+func prometheusName(name string) string {
+	return sanitizePrometheusName(name, true)
+}
+
+// prometheusLabelName приводит имя метки к виду, допустимому в Prometheus: [a-zA-Z_][a-zA-Z0-9_]*.
+func prometheusLabelName(name string) string {
+	return sanitizePrometheusName(name, false)
+}
+
+// prometheusLabels формирует представление набора меток в формате Prometheus.
+func prometheusLabels(labels metric.Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	pairs := make([]string, 0, len(labels))
+
+	for _, name := range labels.Names() {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, prometheusLabelName(name), replacer.Replace(labels[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sanitizePrometheusName заменяет недопустимые в именах Prometheus символы на '_'.
+func sanitizePrometheusName(name string, allowColon bool) string {
+	builder := strings.Builder{}
+
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':' && allowColon:
+			builder.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				builder.WriteRune('_')
+			}
+
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	return builder.String()
+}
+
+// formatPrometheus формирует представление метрик в текстовом формате Prometheus.
+// Метрики с одинаковым именем и разными наборами меток объединяются в одно семейство.
+// Вторым значением возвращаются имена метрик, которые были пропущены,
+// так как после приведения их имя и метки совпали с другой метрикой
+// или имя совпало с семейством другого типа.
+func formatPrometheus(values []metric.Metrics) (string, []string) {
+	type series struct {
+		name   string
+		labels string
+		mtrc   metric.Metrics
+	}
+
+	list := make([]series, 0, len(values))
+
+	for _, v := range values {
+		list = append(list, series{name: prometheusName(v.ID), labels: prometheusLabels(v.Labels), mtrc: v})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].name != list[j].name {
+			return list[i].name < list[j].name
+		}
+
+		if list[i].labels != list[j].labels {
+			return list[i].labels < list[j].labels
+		}
+
+		return list[i].mtrc.ID < list[j].mtrc.ID
+	})
+
+	var (
+		builder  = strings.Builder{}
+		families = make(map[string]metric.MetricType, len(list))
+		written  = make(map[string]struct{}, len(list))
+		skipped  = make([]string, 0)
+	)
+
+	for _, s := range list {
+		pType, ok := prometheusTypes[s.mtrc.MType]
+		if !ok {
+			skipped = append(skipped, s.mtrc.ID)
+
+			continue
+		}
+
+		var value string
+
+		switch {
+		case s.mtrc.Delta != nil:
+			value = strconv.FormatInt(*s.mtrc.Delta, 10)
+		case s.mtrc.Value != nil:
+			value = strconv.FormatFloat(*s.mtrc.Value, 'f', -1, 64)
+		case s.mtrc.Histogram != nil:
+			// Серии гистограммы формируются в writePrometheusHistogram
+		default:
+			skipped = append(skipped, s.mtrc.ID)
+
+			continue
+		}
+
+		family, ok := families[s.name]
+		if ok && family != s.mtrc.MType {
+			skipped = append(skipped, s.mtrc.ID)
+
+			continue
+		}
+
+		if _, ok := written[s.name+s.labels]; ok {
+			skipped = append(skipped, s.mtrc.ID)
+
+			continue
+		}
+
+		if !ok {
+			families[s.name] = s.mtrc.MType
+
+			builder.WriteString(fmt.Sprintf("# TYPE %s %s\n", s.name, pType))
+		}
+
+		written[s.name+s.labels] = struct{}{}
+
+		if s.mtrc.Histogram != nil {
+			writePrometheusHistogram(&builder, s.name, s.mtrc.Labels, s.mtrc.Histogram)
+
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("%s%s %s\n", s.name, s.labels, value))
+	}
+
+	return builder.String(), skipped
+}
+
+// writePrometheusHistogram записывает серии _bucket, _sum и _count гистограммы в текстовом формате Prometheus.
+// Значения корзин в формате Prometheus накопительные.
+func writePrometheusHistogram(builder *strings.Builder, name string, labels metric.Labels, h *metric.Histogram) {
+	var cumulative uint64
+
+	for i, c := range h.Counts {
+		cumulative += c
+
+		le := "+Inf"
+		if i < len(h.Bounds) {
+			le = strconv.FormatFloat(h.Bounds[i], 'f', -1, 64)
+		}
+
+		bucketLabels := labels.Copy()
+		if bucketLabels == nil {
+			bucketLabels = make(metric.Labels, 1)
+		}
+
+		bucketLabels["le"] = le
+
+		builder.WriteString(fmt.Sprintf("%s_bucket%s %d\n", name, prometheusLabels(bucketLabels), cumulative))
+	}
+
+	lbls := prometheusLabels(labels)
+
+	builder.WriteString(fmt.Sprintf("%s_sum%s %s\n", name, lbls, strconv.FormatFloat(h.Sum, 'f', -1, 64)))
+	builder.WriteString(fmt.Sprintf("%s_count%s %d\n", name, lbls, h.Count))
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusName(t *testing.T) {
+	tests := []struct {
+		name     string
+		arg      string
+		expected string
+	}{
+		{
+			name:     "Valid name",
+			arg:      "CPUutilization0",
+			expected: "CPUutilization0",
+		},
+		{
+			name:     "Name with dots and dashes",
+			arg:      "http.requests-total",
+			expected: "http_requests_total",
+		},
+		{
+			name:     "Name starts with digit",
+			arg:      "5xx",
+			expected: "_5xx",
+		},
+		{
+			name:     "Name with colon",
+			arg:      "job:latency",
+			expected: "job:latency",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, prometheusName(test.arg))
+		})
+	}
+}
+
+func TestFormatPrometheus(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+	)
+
+	body, skipped := formatPrometheus(
+		[]metric.Metrics{
+			{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal},
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal},
+			{ID: "Poll.Count", MType: metric.CounterMetric, Delta: &counterVal},
+			{ID: "Poll-Count", MType: metric.GaugeMetric, Value: &gaugeVal},
+		},
+	)
+
+	expected := "# TYPE PollCount counter\nPollCount 100\n" +
+		"# TYPE Poll_Count gauge\nPoll_Count 12345.67\n" +
+		"# TYPE RandomValue gauge\nRandomValue 12345.67\n"
+
+	assert.Equal(t, expected, body)
+	assert.Equal(t, []string{"Poll.Count"}, skipped)
+}
+
+func TestFormatPrometheusLabels(t *testing.T) {
+	var (
+		hostA = 1.5
+		hostB = 2.5
+	)
+
+	body, skipped := formatPrometheus(
+		[]metric.Metrics{
+			{ID: "Alloc", MType: metric.GaugeMetric, Value: &hostB, Labels: metric.Labels{"host": "b"}},
+			{ID: "Alloc", MType: metric.GaugeMetric, Value: &hostA, Labels: metric.Labels{"host": "a\"x\""}},
+		},
+	)
+
+	expected := "# TYPE Alloc gauge\n" +
+		"Alloc{host=\"a\\\"x\\\"\"} 1.5\n" +
+		"Alloc{host=\"b\"} 2.5\n"
+
+	assert.Equal(t, expected, body)
+	assert.Empty(t, skipped)
+}
+
+func TestFormatPrometheusHistogram(t *testing.T) {
+	h := &metric.Histogram{Bounds: []float64{0.1, 1}, Counts: []uint64{1, 2, 1}, Sum: 3.5, Count: 4}
+
+	body, skipped := formatPrometheus(
+		[]metric.Metrics{
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: h, Labels: metric.Labels{"host": "a"}},
+		},
+	)
+
+	expected := "# TYPE Latency histogram\n" +
+		"Latency_bucket{host=\"a\",le=\"0.1\"} 1\n" +
+		"Latency_bucket{host=\"a\",le=\"1\"} 3\n" +
+		"Latency_bucket{host=\"a\",le=\"+Inf\"} 4\n" +
+		"Latency_sum{host=\"a\"} 3.5\n" +
+		"Latency_count{host=\"a\"} 4\n"
+
+	assert.Equal(t, expected, body)
+	assert.Empty(t, skipped)
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/query"
+
+	"github.com/labstack/echo"
+)
+
+// metricsPage описывает страницу выборки метрик в ответе на запрос списка метрик.
+type metricsPage struct {
+	Metrics    []metric.Metrics `json:"metrics"`               // Метрики страницы
+	NextCursor string           `json:"next_cursor,omitempty"` // Курсор следующей страницы, если она есть
+}
+
+// Параметры запроса значений метрики за интервал времени по умолчанию.
+const (
+	defaultRangeDuration = time.Hour   // Длительность интервала
+	defaultRangeStep     = time.Minute // Длительность шага
+)
+
+// rangeResponse описывает ответ на запрос значений метрики за интервал времени.
+type rangeResponse struct {
+	Points []metric.Point `json:"points"` // Агрегированные значения по шагам
+}
+
+// queryResponse описывает ответ на запрос вычисления выражения.
+type queryResponse struct {
+	Type   query.ValueType `json:"type"`   // Тип результата: scalar или vector
+	Result query.Value     `json:"result"` // Результат вычисления
+}
+
+// listMetricsHandler представляет собой обработчик запроса списка метрик в формате JSON.
+//
+// Параметры запроса:
+//   - type - допустимые типы метрик (параметр может повторяться или содержать типы через запятую);
+//   - prefix - префикс имени метрики;
+//   - regexp - регулярное выражение, которому должно соответствовать имя метрики (синтаксис RE2);
+//   - labels - метки, которые должен содержать набор меток метрики, в формате "name1=value1,name2=value2";
+//   - sort - поле сортировки: name (по умолчанию) или type;
+//   - order - порядок сортировки: asc (по умолчанию) или desc;
+//   - limit - размер страницы (по умолчанию metric.DefaultFilterLimit, не более metric.MaxFilterLimit);
+//   - cursor - курсор страницы из поля next_cursor предыдущего ответа.
+func (c *StorageController) listMetricsHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	filter, err := parseFilter(e)
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	values, next, err := c.storage.Find(e.Request().Context(), filter)
+	if errors.Is(err, metric.ErrWrongFilter) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	page := &metricsPage{Metrics: values}
+	if next != nil {
+		page.NextCursor = next.String()
+	}
+
+	return e.JSON(http.StatusOK, page)
+}
+
+// parseFilter возвращает условия выборки метрик, переданные в параметрах запроса.
+func parseFilter(e echo.Context) (metric.Filter, error) {
+	params := e.QueryParams()
+
+	filter := metric.Filter{
+		NamePrefix: params.Get("prefix"),
+		NameRegexp: params.Get("regexp"),
+		Sort:       metric.SortField(params.Get("sort")),
+	}
+
+	for _, value := range params["type"] {
+		for _, t := range strings.Split(value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				filter.Types = append(filter.Types, metric.MetricType(t))
+			}
+		}
+	}
+
+	labels, err := metric.ParseLabels(params.Get("labels"))
+	if err != nil {
+		return metric.Filter{}, fmt.Errorf("%w: %s", metric.ErrWrongFilter, err)
+	}
+
+	filter.Labels = labels
+
+	switch params.Get("order") {
+	case "", "asc":
+	case "desc":
+		filter.Desc = true
+	default:
+		return metric.Filter{}, fmt.Errorf("%w: unknown order %q", metric.ErrWrongFilter, params.Get("order"))
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 {
+			return metric.Filter{}, fmt.Errorf("%w: wrong limit %q", metric.ErrWrongFilter, limit)
+		}
+	}
+
+	if cursor := params.Get("cursor"); cursor != "" {
+		filter.After, err = metric.ParseCursor(cursor)
+		if err != nil {
+			return metric.Filter{}, err
+		}
+	}
+
+	return filter, nil
+}
+
+// queryRangeHandler представляет собой обработчик запроса значений метрики за интервал времени,
+// агрегированных по шагам. Результат возвращается в формате JSON.
+//
+// Параметры запроса:
+//   - type, name - тип (counter или gauge) и имя метрики;
+//   - labels - набор меток метрики в формате "name1=value1,name2=value2";
+//   - from, to - начало и окончание интервала в формате RFC 3339 или Unix-времени в секундах
+//     (по умолчанию - последний час);
+//   - step - длительность шага, например "30s" или "5m" (по умолчанию 1m);
+//   - agg - функция агрегации: avg (по умолчанию), min, max, sum, rate (только для counter), last.
+func (c *StorageController) queryRangeHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	q, err := parseRangeQuery(e, time.Now())
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	points, err := c.storage.GetRange(e.Request().Context(), q)
+	if errors.Is(err, metric.ErrWrongRangeQuery) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	return e.JSON(http.StatusOK, &rangeResponse{Points: points})
+}
+
+// parseRangeQuery возвращает запрос значений метрики за интервал времени, переданный в параметрах запроса.
+// Окончание интервала по умолчанию - now.
+func parseRangeQuery(e echo.Context, now time.Time) (metric.RangeQuery, error) {
+	params := e.QueryParams()
+
+	q := metric.RangeQuery{
+		MType:       metric.MetricType(params.Get("type")),
+		Name:        params.Get("name"),
+		To:          now,
+		Step:        defaultRangeStep,
+		Aggregation: metric.Aggregation(params.Get("agg")),
+	}
+
+	if q.Aggregation == "" {
+		q.Aggregation = metric.AggregationAvg
+	}
+
+	labels, err := metric.ParseLabels(params.Get("labels"))
+	if err != nil {
+		return metric.RangeQuery{}, fmt.Errorf("%w: %s", metric.ErrWrongRangeQuery, err)
+	}
+
+	q.Labels = labels
+
+	if to := params.Get("to"); to != "" {
+		if q.To, err = parseTime(to); err != nil {
+			return metric.RangeQuery{}, fmt.Errorf("%w: %s", metric.ErrWrongRangeQuery, err)
+		}
+	}
+
+	q.From = q.To.Add(-defaultRangeDuration)
+
+	if from := params.Get("from"); from != "" {
+		if q.From, err = parseTime(from); err != nil {
+			return metric.RangeQuery{}, fmt.Errorf("%w: %s", metric.ErrWrongRangeQuery, err)
+		}
+	}
+
+	if step := params.Get("step"); step != "" {
+		if q.Step, err = time.ParseDuration(step); err != nil {
+			return metric.RangeQuery{}, fmt.Errorf("%w: wrong step %q", metric.ErrWrongRangeQuery, step)
+		}
+	}
+
+	return q, nil
+}
+
+// parseTime разбирает момент времени в формате RFC 3339 или Unix-времени в секундах.
+func parseTime(s string) (time.Time, error) {
+	if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return ts, nil
+	}
+
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("wrong time %q", s)
+	}
+
+	whole, frac := math.Modf(secs)
+
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), nil
+}
+
+// queryHandler представляет собой обработчик запроса вычисления выражения над метриками
+// (см. пакет query). Результат возвращается в формате JSON.
+//
+// Параметры запроса:
+//   - query - выражение, например "HeapAlloc / HeapSys" или "sum by (host) (rate(requests[5m]))";
+//   - time - момент вычисления в формате RFC 3339 или Unix-времени в секундах (по умолчанию - текущий).
+func (c *StorageController) queryHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	ts := time.Now()
+
+	if t := e.QueryParam("time"); t != "" {
+		var err error
+
+		if ts, err = parseTime(t); err != nil {
+			c.l.Debugf("[%s] %s", uuid, err.Error())
+
+			return e.NoContent(http.StatusBadRequest)
+		}
+	}
+
+	res, err := query.NewEngine(c.storage).Query(e.Request().Context(), e.QueryParam("query"), ts)
+	if errors.Is(err, query.ErrSyntax) || errors.Is(err, query.ErrEvaluation) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	return e.JSON(http.StatusOK, &queryResponse{Type: res.Type(), Result: res})
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMetricsHandler(t *testing.T) {
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	gaugeVal := 0.5
+	err = repo.Update(
+		context.Background(),
+		&metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "a"}},
+	)
+	require.NoError(t, err)
+
+	c := StorageController{
+		storage: storage.NewMetricsStorage(repo, 10*time.Second),
+		l:       logrus.StandardLogger(),
+	}
+
+	// list выполняет запрос списка метрик с параметрами query
+	list := func(query url.Values) (int, metricsPage) {
+		rec := httptest.NewRecorder()
+		ctx, err := newEchoContext(rec, http.MethodGet, "/api/v1/metrics?"+query.Encode(), nil, nil)
+		require.NoError(t, err)
+		require.NoError(t, c.listMetricsHandler(ctx))
+
+		res := rec.Result()
+		defer res.Body.Close()
+
+		var page metricsPage
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&page))
+		}
+
+		return res.StatusCode, page
+	}
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		expected []string
+	}{
+		{
+			name:     "All metrics",
+			query:    url.Values{},
+			status:   http.StatusOK,
+			expected: []string{"PollCount", "RandomValue", `RandomValue{host="a"}`},
+		},
+		{
+			name:     "Filter by type and labels",
+			query:    url.Values{"type": {"gauge,histogram"}, "labels": {"host=a"}},
+			status:   http.StatusOK,
+			expected: []string{`RandomValue{host="a"}`},
+		},
+		{
+			name:     "Filter by prefix, sort by type descending",
+			query:    url.Values{"prefix": {"R"}, "sort": {"type"}, "order": {"desc"}},
+			status:   http.StatusOK,
+			expected: []string{`RandomValue{host="a"}`, "RandomValue"},
+		},
+		{
+			name:     "Filter by regexp",
+			query:    url.Values{"regexp": {"^Poll"}},
+			status:   http.StatusOK,
+			expected: []string{"PollCount"},
+		},
+		{
+			name:   "Wrong type",
+			query:  url.Values{"type": {"summary"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong regexp",
+			query:  url.Values{"regexp": {"("}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong sort",
+			query:  url.Values{"sort": {"value"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong limit",
+			query:  url.Values{"limit": {"0"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong cursor",
+			query:  url.Values{"cursor": {"!"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, page := list(test.query)
+			require.Equal(t, test.status, status)
+
+			if status != http.StatusOK {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Metrics))
+			for _, m := range page.Metrics {
+				ids = append(ids, m.ID+m.Labels.String())
+			}
+
+			assert.Equal(t, test.expected, ids)
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+
+	t.Run("Pagination", func(t *testing.T) {
+		status, page := list(url.Values{"limit": {"2"}})
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, page.Metrics, 2)
+		require.NotEmpty(t, page.NextCursor)
+
+		status, page = list(url.Values{"limit": {"2"}, "cursor": {page.NextCursor}})
+		require.Equal(t, http.StatusOK, status)
+		require.Len(t, page.Metrics, 1)
+		assert.Equal(t, metric.Labels{"host": "a"}, page.Metrics[0].Labels)
+		assert.Empty(t, page.NextCursor)
+	})
+}
+
+func TestQueryRangeHandler(t *testing.T) {
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	c := StorageController{
+		storage: storage.NewMetricsStorage(repo, 10*time.Second),
+		l:       logrus.StandardLogger(),
+	}
+
+	from := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	to := time.Now().Add(time.Minute).Format(time.RFC3339)
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		expected []float64
+	}{
+		{
+			name:     "Counter sum",
+			query:    url.Values{"type": {"counter"}, "name": {"PollCount"}, "agg": {"sum"}, "step": {"1h"}},
+			status:   http.StatusOK,
+			expected: []float64{100},
+		},
+		{
+			name: "Gauge with explicit range",
+			query: url.Values{
+				"type": {"gauge"}, "name": {"RandomValue"}, "from": {from}, "to": {to}, "step": {"5m"}, "agg": {"max"},
+			},
+			status:   http.StatusOK,
+			expected: []float64{12345.67},
+		},
+		{
+			name:     "Unknown labels",
+			query:    url.Values{"type": {"gauge"}, "name": {"RandomValue"}, "labels": {"host=a"}},
+			status:   http.StatusOK,
+			expected: []float64{},
+		},
+		{
+			name:   "Histogram",
+			query:  url.Values{"type": {"histogram"}, "name": {"RandomValue"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong step",
+			query:  url.Values{"type": {"gauge"}, "name": {"RandomValue"}, "step": {"minute"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong time",
+			query:  url.Values{"type": {"gauge"}, "name": {"RandomValue"}, "from": {"yesterday"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Rate of gauge",
+			query:  url.Values{"type": {"gauge"}, "name": {"RandomValue"}, "agg": {"rate"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, http.MethodGet, "/api/v1/query_range?"+test.query.Encode(), nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, c.queryRangeHandler(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, test.status, res.StatusCode)
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			var resp rangeResponse
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&resp))
+
+			values := make([]float64, 0, len(resp.Points))
+			for _, p := range resp.Points {
+				values = append(values, p.Value)
+			}
+
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestQueryHandler(t *testing.T) {
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	c := StorageController{
+		storage: storage.NewMetricsStorage(repo, 10*time.Second),
+		l:       logrus.StandardLogger(),
+	}
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		status   int
+		expected string
+	}{
+		{
+			name:     "Vector",
+			query:    url.Values{"query": {"RandomValue"}},
+			status:   http.StatusOK,
+			expected: `{"type":"vector","result":[{"name":"RandomValue","value":12345.67}]}`,
+		},
+		{
+			name:     "Arithmetic",
+			query:    url.Values{"query": {"PollCount * 2 > 100"}, "time": {"1700000000"}},
+			status:   http.StatusOK,
+			expected: `{"type":"vector","result":[{"value":200}]}`,
+		},
+		{
+			name:     "Empty vector",
+			query:    url.Values{"query": {`RandomValue{host="a"}`}},
+			status:   http.StatusOK,
+			expected: `{"type":"vector","result":[]}`,
+		},
+		{
+			name:     "Scalar",
+			query:    url.Values{"query": {"1 + 2"}},
+			status:   http.StatusOK,
+			expected: `{"type":"scalar","result":3}`,
+		},
+		{
+			name:   "Syntax error",
+			query:  url.Values{"query": {"RandomValue +"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Evaluation error",
+			query:  url.Values{"query": {"sum(1)"}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Wrong time",
+			query:  url.Values{"query": {"1"}, "time": {"now"}},
+			status: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, err := newEchoContext(rec, http.MethodGet, "/api/v1/query?"+test.query.Encode(), nil, nil)
+			require.NoError(t, err)
+			require.NoError(t, c.queryHandler(ctx))
+
+			res := rec.Result()
+			defer res.Body.Close()
+
+			require.Equal(t, test.status, res.StatusCode)
+
+			if test.status == http.StatusOK {
+				body, err := io.ReadAll(res.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, test.expected, string(body))
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
 	"strconv"
@@ -25,12 +26,15 @@ var (
 
 // StorageController представляет собой контроллер для хранилища.
 type StorageController struct {
-	storage storage.Storage
-	l       *log.Logger
+	storage    storage.Storage
+	adminToken string // токен доступа к операциям администрирования
+	l          *log.Logger
 }
 
 // NewStorageController создаёт новый контроллер хранилища.
-func NewStorageController(s storage.Storage, l *log.Logger) (*StorageController, error) {
+// Операции администрирования (удаление и сброс метрик) доступны только по токену adminToken;
+// если он пуст, операции администрирования запрещены.
+func NewStorageController(s storage.Storage, adminToken string, l *log.Logger) (*StorageController, error) {
 	if s == nil {
 		return nil, ErrStorageIsNil
 	}
@@ -40,7 +44,7 @@ func NewStorageController(s storage.Storage, l *log.Logger) (*StorageController,
 		lg = l
 	}
 
-	return &StorageController{storage: s, l: lg}, nil
+	return &StorageController{storage: s, adminToken: adminToken, l: lg}, nil
 }
 
 // MapStorageHandlers выполняет маппинг маршрутов и обработчиков в маршрутизатор echo.
@@ -56,16 +60,27 @@ func MapStorageHandlers(router *echo.Router, c *StorageController) error {
 	router.Add(http.MethodPost, "/update/:mtype/:mname/:value", c.updateHandler)
 	router.Add(http.MethodPost, "/update/", c.updateJSONHandler)
 	router.Add(http.MethodPost, "/updates/", c.updatesHandler)
+	router.Add(http.MethodPost, "/write", c.writeHandler)
+	router.Add(http.MethodPost, "/v1/metrics", c.otlpMetricsHandler)
 	router.Add(http.MethodGet, "/value/:mtype/:mname", c.getValueHandler)
 	router.Add(http.MethodPost, "/value/", c.getValueJSONHandler)
+	router.Add(http.MethodPost, "/values/", c.getByLabelsHandler)
 	router.Add(http.MethodGet, "/", c.getAllHandler)
+	router.Add(http.MethodGet, "/metrics", c.metricsHandler)
+	router.Add(http.MethodGet, "/api/v1/metrics", c.listMetricsHandler)
+	router.Add(http.MethodGet, "/api/v1/query_range", c.queryRangeHandler)
+	router.Add(http.MethodGet, "/api/v1/query", c.queryHandler)
 	router.Add(http.MethodGet, "/ping", c.pingHandler)
+	router.Add(http.MethodDelete, "/value/:mtype/:mname", c.adminOnly(c.deleteHandler))
+	router.Add(http.MethodDelete, "/values/:pattern", c.adminOnly(c.deleteByPatternHandler))
+	router.Add(http.MethodPost, "/reset/:mname", c.adminOnly(c.resetHandler))
 
 	return nil
 }
 
 // updateHandler представляет собой обработчик запроса на обновление единственной метрики.
-// Параметры метрики передаются через URL.
+// Параметры метрики передаются через URL, метки - через параметр запроса labels.
+// Для histogram значение из URL является единичным наблюдением.
 func (c *StorageController) updateHandler(e echo.Context) error {
 	uuid := e.Get("uuid")
 
@@ -116,6 +131,17 @@ func (c *StorageController) updateHandler(e echo.Context) error {
 		return e.NoContent(http.StatusInternalServerError)
 	}
 
+	mtrc.Labels, err = queryLabels(e)
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if mtrc.MType == metric.HistogramMetric {
+		c.alignHistogramBounds(e, &mtrc)
+	}
+
 	err = c.storage.Update(e.Request().Context(), &mtrc)
 	if errors.Is(err, metric.ErrWrongMetricName) {
 		c.l.Debugf("[%s] %s", uuid, err.Error())
@@ -123,7 +149,8 @@ func (c *StorageController) updateHandler(e echo.Context) error {
 		return e.NoContent(http.StatusNotFound)
 	}
 
-	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) ||
+		errors.Is(err, metric.ErrWrongMetricLabels) {
 		c.l.Debugf("[%s] %s", uuid, err.Error())
 
 		return e.NoContent(http.StatusBadRequest)
@@ -173,7 +200,8 @@ func (c *StorageController) updateJSONHandler(e echo.Context) error {
 		return e.NoContent(http.StatusNotFound)
 	}
 
-	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) ||
+		errors.Is(err, metric.ErrWrongMetricLabels) {
 		c.l.Debugf("[%s] %s", uuid, err.Error())
 
 		return e.NoContent(http.StatusBadRequest)
@@ -216,14 +244,18 @@ func (c *StorageController) updatesHandler(e echo.Context) error {
 		return e.NoContent(http.StatusInternalServerError)
 	}
 
-	err = c.storage.UpdateMany(e.Request().Context(), mtrcs)
+	// Повторно отправленный агентом набор с тем же идентификатором не применяется
+	batch := metric.Batch{ID: e.Request().Header.Get(metric.BatchIDHeader), Metrics: mtrcs}
+
+	applied, err := c.storage.UpdateBatch(e.Request().Context(), batch)
 	if errors.Is(err, metric.ErrWrongMetricName) {
 		c.l.Debugf("[%s] %s", uuid, err.Error())
 
 		return e.NoContent(http.StatusNotFound)
 	}
 
-	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) {
+	if errors.Is(err, metric.ErrWrongMetricType) || errors.Is(err, metric.ErrWrongMetricValue) ||
+		errors.Is(err, metric.ErrWrongMetricLabels) {
 		c.l.Debugf("[%s] %s", uuid, err.Error())
 
 		return e.NoContent(http.StatusBadRequest)
@@ -235,19 +267,36 @@ func (c *StorageController) updatesHandler(e echo.Context) error {
 		return e.NoContent(http.StatusInternalServerError)
 	}
 
+	if !applied {
+		c.l.Debugf("[%s] batch %s has already been applied", uuid, batch.ID)
+	}
+
 	return e.NoContent(http.StatusOK)
 }
 
 // getValueHandler представляет собой обработчик запроса на получение параметров единственной метрики.
-// Параметры запрашиваемой метрики передаются через URL.
+// Параметры запрашиваемой метрики передаются через URL, метки - через параметр запроса labels.
 func (c *StorageController) getValueHandler(e echo.Context) error {
 	uuid := e.Get("uuid")
 
-	v, err := c.storage.GetValue(e.Request().Context(), metric.MetricType(e.Param("mtype")), e.Param("mname"))
+	labels, err := queryLabels(e)
+	if err != nil {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	v, err := c.storage.GetValue(
+		e.Request().Context(), metric.MetricType(e.Param("mtype")), e.Param("mname"), labels,
+	)
 	if errors.Is(err, metric.ErrWrongMetricType) {
 		return e.NoContent(http.StatusNotFound)
 	}
 
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		return e.NoContent(http.StatusBadRequest)
+	}
+
 	if err != nil {
 		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
@@ -262,6 +311,10 @@ func (c *StorageController) getValueHandler(e echo.Context) error {
 		return e.String(http.StatusOK, strconv.FormatInt(*v.Delta, 10))
 	}
 
+	if v.Histogram != nil {
+		return e.String(http.StatusOK, v.Histogram.String())
+	}
+
 	return e.String(http.StatusOK, strconv.FormatFloat(*v.Value, 'f', -1, 64))
 }
 
@@ -286,11 +339,15 @@ func (c *StorageController) getValueJSONHandler(e echo.Context) error {
 		return e.NoContent(http.StatusInternalServerError)
 	}
 
-	v, err := c.storage.GetValue(e.Request().Context(), mtrc.MType, mtrc.ID)
+	v, err := c.storage.GetValue(e.Request().Context(), mtrc.MType, mtrc.ID, mtrc.Labels)
 	if errors.Is(err, metric.ErrWrongMetricType) {
 		return e.NoContent(http.StatusNotFound)
 	}
 
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		return e.NoContent(http.StatusBadRequest)
+	}
+
 	if err != nil {
 		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
@@ -304,8 +361,49 @@ func (c *StorageController) getValueJSONHandler(e echo.Context) error {
 	return e.JSON(http.StatusOK, v)
 }
 
+// getByLabelsHandler представляет собой обработчик запроса списка метрик,
+// набор меток которых содержит все метки из запроса.
+// Набор меток передаётся в формате JSON в теле HTTP-запроса, результат возвращается в формате JSON.
+func (c *StorageController) getByLabelsHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	body, err := io.ReadAll(e.Request().Body)
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	var labels metric.Labels
+
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &labels)
+		if err != nil {
+			c.l.Debugf("[%s] %s", uuid, err.Error())
+
+			return e.NoContent(http.StatusBadRequest)
+		}
+	}
+
+	values, err := c.storage.GetByLabels(e.Request().Context(), labels)
+	if errors.Is(err, metric.ErrWrongMetricLabels) {
+		c.l.Debugf("[%s] %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusBadRequest)
+	}
+
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	return e.JSON(http.StatusOK, values)
+}
+
 // getAllHandler представляет собой обработчик запроса списка всех метрик из хранилища.
 // Результат возвращается в формате HTML в виде таблицы: Metric name | Metric type | Value.
+// Метки метрики выводятся вместе с её именем.
 func (c *StorageController) getAllHandler(e echo.Context) error {
 	uuid := e.Get("uuid")
 
@@ -321,21 +419,23 @@ func (c *StorageController) getAllHandler(e echo.Context) error {
 	builder.WriteString("<table><tr><th>Metric name</th><th>Metric type</th><th>Value</th></tr>")
 
 	for _, v := range values {
-		if v.Delta != nil {
-			builder.WriteString(
-				fmt.Sprintf(
-					"<tr><td>%s</td><td>%s</td><td>%d</td></tr>",
-					v.ID, v.MType, *v.Delta,
-				),
-			)
-		} else {
-			builder.WriteString(
-				fmt.Sprintf(
-					"<tr><td>%s</td><td>%s</td><td>%s</td></tr>",
-					v.ID, v.MType, strconv.FormatFloat(*v.Value, 'f', -1, 64),
-				),
-			)
+		var value string
+
+		switch {
+		case v.Delta != nil:
+			value = strconv.FormatInt(*v.Delta, 10)
+		case v.Histogram != nil:
+			value = v.Histogram.String()
+		case v.Value != nil:
+			value = strconv.FormatFloat(*v.Value, 'f', -1, 64)
 		}
+
+		builder.WriteString(
+			fmt.Sprintf(
+				"<tr><td>%s</td><td>%s</td><td>%s</td></tr>",
+				html.EscapeString(v.ID+v.Labels.String()), v.MType, html.EscapeString(value),
+			),
+		)
 	}
 
 	builder.WriteString("</table>")
@@ -343,6 +443,55 @@ func (c *StorageController) getAllHandler(e echo.Context) error {
 	return e.HTML(http.StatusOK, builder.String())
 }
 
+// metricsHandler представляет собой обработчик запроса списка всех метрик из хранилища.
+// Результат возвращается в текстовом формате Prometheus.
+func (c *StorageController) metricsHandler(e echo.Context) error {
+	uuid := e.Get("uuid")
+
+	values, err := c.storage.GetAll(e.Request().Context())
+	if err != nil {
+		c.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
+
+		return e.NoContent(http.StatusInternalServerError)
+	}
+
+	body, skipped := formatPrometheus(values)
+	for _, id := range skipped {
+		c.l.Debugf("[%s] metric '%s' cannot be represented in Prometheus format", uuid, id)
+	}
+
+	return e.Blob(http.StatusOK, prometheusContentType, []byte(body))
+}
+
+// alignHistogramBounds приводит гистограмму из единичного наблюдения, созданную с границами корзин
+// по умолчанию, к границам корзин уже сохранённой гистограммы.
+// Ошибки получения сохранённой метрики игнорируются: они будут обработаны при обновлении.
+func (c *StorageController) alignHistogramBounds(e echo.Context, mtrc *metric.Metrics) {
+	stored, err := c.storage.GetValue(e.Request().Context(), mtrc.MType, mtrc.ID, mtrc.Labels)
+	if err != nil || stored.Histogram == nil || stored.Histogram.SameBounds(mtrc.Histogram) {
+		return
+	}
+
+	// Гистограмма содержит единственное наблюдение, поэтому его значение равно сумме
+	observation := mtrc.Histogram.Sum
+
+	mtrc.Histogram = metric.NewHistogram(stored.Histogram.Bounds)
+	mtrc.Histogram.Observe(observation)
+}
+
+// queryLabels возвращает набор меток, переданный в параметре запроса labels
+// в виде "name1=value1,name2=value2" (см. metric.ParseLabels).
+// Другие параметры запроса не допускаются: иначе они незаметно становились бы частью идентификатора метрики.
+func queryLabels(e echo.Context) (metric.Labels, error) {
+	for name := range e.QueryParams() {
+		if name != "labels" {
+			return nil, fmt.Errorf("%w: unexpected query parameter %q", metric.ErrWrongMetricLabels, name)
+		}
+	}
+
+	return metric.ParseLabels(e.QueryParam("labels"))
+}
+
 // pingHandler представляет собой обработчик запроса на проверку доступности хранилища.
 func (c *StorageController) pingHandler(e echo.Context) error {
 	if c.storage.Ping(e.Request().Context()) {
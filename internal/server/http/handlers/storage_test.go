@@ -36,7 +36,7 @@ func newTestRepo(clear bool) (*memstorage.MemStorage, error) {
 		gaugeVal         = 12345.67
 	)
 
-	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, retries, nil)
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, retries, memstorage.HistoryRetention{}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -414,6 +414,35 @@ func TestUpdatesHandler(t *testing.T) {
 	}
 }
 
+func TestUpdatesHandlerBatchID(t *testing.T) {
+	repo, err := newTestRepo(true)
+	require.NoError(t, err)
+
+	s := StorageController{
+		storage: storage.NewMetricsStorage(repo, 10*time.Second),
+		l:       logrus.StandardLogger(),
+	}
+
+	// Повторно отправленный набор подтверждается, но не применяется независимо от адреса отправителя
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2", ""} {
+		rec := httptest.NewRecorder()
+		ctx, err := newEchoContext(
+			rec, http.MethodPost, "/updates/", strings.NewReader(`[{"id":"PollCount", "type":"counter", "delta":1}]`), nil,
+		)
+		require.NoError(t, err)
+
+		ctx.Request().Header.Set(metric.BatchIDHeader, "batch-1")
+		ctx.Request().Header.Set("X-Real-IP", ip)
+
+		require.NoError(t, s.updatesHandler(ctx))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	v, err := repo.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, *v.Delta)
+}
+
 func TestGetValueHandler(t *testing.T) {
 	params := []string{"mtype", "mname"}
 	timeout := 10 * time.Second
@@ -644,6 +673,173 @@ func TestGetAllHandler(t *testing.T) {
 	}
 }
 
+func TestLabelsHandlers(t *testing.T) {
+	timeout := 10 * time.Second
+
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	s := StorageController{
+		storage: storage.NewMetricsStorage(repo, timeout),
+		l:       logrus.StandardLogger(),
+	}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/update/gauge/Alloc/1.5?labels=host%3Da", nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("mtype", "mname", "value")
+	ctx.SetParamValues("gauge", "Alloc", "1.5")
+
+	err = s.updateHandler(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Параметры запроса, кроме labels, отклоняются и не становятся метками
+	for _, target := range []string{"/update/gauge/Alloc/2.5?labels=host%3Da&_=123", "/update/gauge/Alloc/2.5?host=a"} {
+		rec = httptest.NewRecorder()
+		ctx = e.NewContext(httptest.NewRequest(http.MethodPost, target, nil), rec)
+		ctx.SetParamNames("mtype", "mname", "value")
+		ctx.SetParamValues("gauge", "Alloc", "2.5")
+
+		err = s.updateHandler(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx = e.NewContext(httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc?labels=host%3Da", nil), rec)
+	ctx.SetParamNames("mtype", "mname")
+	ctx.SetParamValues("gauge", "Alloc")
+
+	err = s.getValueHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "1.5", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	ctx = e.NewContext(httptest.NewRequest(http.MethodGet, "/value/gauge/Alloc?labels=host%3Da&_=123", nil), rec)
+	ctx.SetParamNames("mtype", "mname")
+	ctx.SetParamValues("gauge", "Alloc")
+
+	err = s.getValueHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(rec, http.MethodPost, "/values/", strings.NewReader(`{"host":"a"}`), nil)
+	require.NoError(t, err)
+
+	err = s.getByLabelsHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":"Alloc","type":"gauge","value":1.5,"labels":{"host":"a"}}]`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(rec, http.MethodPost, "/values/", strings.NewReader(`{"":"a"}`), nil)
+	require.NoError(t, err)
+
+	err = s.getByLabelsHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHistogramHandlers(t *testing.T) {
+	timeout := 10 * time.Second
+
+	repo, err := newTestRepo(true)
+	require.NoError(t, err)
+
+	s := StorageController{
+		storage: storage.NewMetricsStorage(repo, timeout),
+		l:       logrus.StandardLogger(),
+	}
+
+	body := `{"id":"Latency","type":"histogram","histogram":{"bounds":[0.1,1],"counts":[1,0,0],"sum":0.05,"count":1}}`
+
+	rec := httptest.NewRecorder()
+	ctx, err := newEchoContext(rec, http.MethodPost, "/update/", strings.NewReader(body), nil)
+	require.NoError(t, err)
+
+	err = s.updateJSONHandler(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Наблюдение из URL должно попасть в корзины сохранённой гистограммы
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(
+		rec, http.MethodPost, "/update/histogram/Latency/0.5", nil, []string{"mtype", "mname", "value"},
+	)
+	require.NoError(t, err)
+
+	err = s.updateHandler(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(rec, http.MethodGet, "/value/histogram/Latency", nil, []string{"mtype", "mname"})
+	require.NoError(t, err)
+
+	err = s.getValueHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "count=2 sum=0.55 buckets={0.1:1 1:1 +Inf:0}", rec.Body.String())
+
+	body = `{"id":"Latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}}`
+
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(rec, http.MethodPost, "/update/", strings.NewReader(body), nil)
+	require.NoError(t, err)
+
+	err = s.updateJSONHandler(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	ctx, err = newEchoContext(rec, http.MethodGet, "/", nil, nil)
+	require.NoError(t, err)
+
+	err = s.getAllHandler(ctx)
+	require.NoError(t, err)
+	assert.Contains(
+		t, rec.Body.String(),
+		"<tr><td>Latency</td><td>histogram</td><td>count=2 sum=0.55 buckets={0.1:1 1:1 +Inf:0}</td></tr>",
+	)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	timeout := 10 * time.Second
+
+	rec := httptest.NewRecorder()
+	ctx, err := newEchoContext(rec, http.MethodGet, "/metrics", nil, nil)
+	require.NoError(t, err)
+
+	repo, err := newTestRepo(false)
+	require.NoError(t, err)
+
+	s := StorageController{
+		storage: storage.NewMetricsStorage(repo, timeout),
+		l:       logrus.StandardLogger(),
+	}
+
+	err = s.metricsHandler(ctx)
+	require.NoError(t, err)
+
+	res := rec.Result()
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, prometheusContentType, res.Header.Get("Content-Type"))
+	assert.Equal(
+		t,
+		"# TYPE PollCount counter\nPollCount 100\n# TYPE RandomValue gauge\nRandomValue 12345.67\n",
+		string(body),
+	)
+}
+
 func TestPing(t *testing.T) {
 	timeout := 10 * time.Second
 
@@ -869,7 +1065,9 @@ func Example() {
 	e := echo.New()
 
 	// Инициализация хранилища.
-	repo, err := memstorage.NewMemStorage(context.Background(), "path/to/file", true, 10, []int{1, 2, 3}, lg)
+	repo, err := memstorage.NewMemStorage(
+		context.Background(), "path/to/file", true, 10, []int{1, 2, 3}, memstorage.HistoryRetention{}, lg,
+	)
 	if err != nil {
 		panic(err)
 	}
@@ -887,7 +1085,7 @@ func Example() {
 		panic(err)
 	}
 
-	if err := SetHandlers(e, stor, []byte("key"), privateKey, nil, lg); err != nil {
+	if err := SetHandlers(e, stor, nil, []byte("key"), privateKey, nil, "", lg); err != nil {
 		panic(err)
 	}
 
@@ -24,6 +24,7 @@ type Manager struct {
 	key         []byte
 	privateKey  *rsa.PrivateKey
 	trustedSNet *net.IPNet
+	plainPaths  map[string]struct{} // маршруты, запросы к которым не расшифровываются
 	l           *log.Logger
 }
 
@@ -38,10 +39,20 @@ func NewManager(key []byte, privateKey *rsa.PrivateKey, trustedSNet *net.IPNet,
 		key:         key,
 		privateKey:  privateKey,
 		trustedSNet: trustedSNet,
+		plainPaths:  make(map[string]struct{}),
 		l:           lg,
 	}
 }
 
+// SkipDecryption исключает запросы к маршрутам paths из дешифрования в RSAMiddleware.
+// Предназначен для маршрутов, принимающих данные от сторонних клиентов, которые не поддерживают шифрование.
+// Должен вызываться до начала обработки запросов.
+func (mw *Manager) SkipDecryption(paths ...string) {
+	for _, path := range paths {
+		mw.plainPaths[path] = struct{}{}
+	}
+}
+
 // LoggingMiddleware - middleware для логирования входящих запросов и их результатов.
 func (mw *Manager) LoggingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return echo.HandlerFunc(func(e echo.Context) error {
@@ -166,6 +177,10 @@ func (mw *Manager) HashMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 }
 
 // RSAMiddleware - middleware для дешифрования входящего запроса.
+// Схема шифрования определяется заголовком utils.EncryptionVersionHeader;
+// запросы без заголовка расшифровываются по схеме utils.EncryptionRSA.
+// Запросы с пустым телом (например, GET-запросы на чтение) и запросы к маршрутам,
+// исключённым SkipDecryption, передаются дальше без изменений.
 func (mw *Manager) RSAMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return echo.HandlerFunc(func(e echo.Context) error {
 		uuid := e.Get("uuid")
@@ -174,6 +189,10 @@ func (mw *Manager) RSAMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return next(e)
 		}
 
+		if _, ok := mw.plainPaths[e.Path()]; ok {
+			return next(e)
+		}
+
 		body, err := io.ReadAll(e.Request().Body)
 		if err != nil {
 			mw.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
@@ -181,7 +200,23 @@ func (mw *Manager) RSAMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return e.NoContent(http.StatusInternalServerError)
 		}
 
-		body, err = mw.privateKey.Decrypt(nil, body, &rsa.OAEPOptions{Hash: crypto.SHA256})
+		if len(body) == 0 {
+			e.Request().Body = io.NopCloser(bytes.NewBuffer(body))
+
+			return next(e)
+		}
+
+		switch version := e.Request().Header.Get(utils.EncryptionVersionHeader); version {
+		case "", utils.EncryptionRSA:
+			body, err = mw.privateKey.Decrypt(nil, body, &rsa.OAEPOptions{Hash: crypto.SHA256})
+		case utils.EncryptionHybrid:
+			body, err = utils.DecryptHybrid(mw.privateKey, body)
+		default:
+			mw.l.Debugf("[%s] unsupported encryption version: '%s'", uuid, version)
+
+			return e.NoContent(http.StatusBadRequest)
+		}
+
 		if err != nil {
 			mw.l.Errorf("[%s] something went wrong: %s", uuid, err.Error())
 
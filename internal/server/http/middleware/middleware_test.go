@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/utils"
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRSAMiddleware(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	small := []byte(`[{"id":"Alloc","type":"gauge","value":1.5}]`)
+	large := bytes.Repeat(small, 50)
+
+	legacy, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, &privateKey.PublicKey, small, nil)
+	require.NoError(t, err)
+
+	hybrid, err := utils.EncryptHybrid(&privateKey.PublicKey, large)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		version    string
+		body       []byte
+		wantStatus int
+		wantBody   []byte
+	}{
+		{
+			name:       "Legacy agent without header",
+			body:       legacy,
+			wantStatus: http.StatusOK,
+			wantBody:   small,
+		},
+		{
+			name:       "RSA encryption",
+			version:    utils.EncryptionRSA,
+			body:       legacy,
+			wantStatus: http.StatusOK,
+			wantBody:   small,
+		},
+		{
+			name:       "Hybrid encryption",
+			version:    utils.EncryptionHybrid,
+			body:       hybrid,
+			wantStatus: http.StatusOK,
+			wantBody:   large,
+		},
+		{
+			name:       "Empty body",
+			wantStatus: http.StatusOK,
+			wantBody:   []byte{},
+		},
+		{
+			name:       "Unsupported version",
+			version:    "3",
+			body:       hybrid,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	mw := NewManager(nil, privateKey, nil, nil)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(test.body))
+			if test.version != "" {
+				req.Header.Set(utils.EncryptionVersionHeader, test.version)
+			}
+
+			rec := httptest.NewRecorder()
+			ctx := echo.New().NewContext(req, rec)
+
+			var received []byte
+
+			handler := mw.RSAMiddleware(func(e echo.Context) error {
+				var err error
+
+				received, err = io.ReadAll(e.Request().Body)
+				if err != nil {
+					return err
+				}
+
+				return e.NoContent(http.StatusOK)
+			})
+
+			err := handler(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, test.wantStatus, rec.Code)
+			assert.Equal(t, test.wantBody, received)
+		})
+	}
+}
+
+func TestSkipDecryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	mw := NewManager(nil, privateKey, nil, nil)
+	mw.SkipDecryption("/write")
+
+	body := []byte("cpu usage=0.64")
+
+	e := echo.New()
+
+	var received []byte
+
+	e.POST("/write", func(e echo.Context) error {
+		received, err = io.ReadAll(e.Request().Body)
+		if err != nil {
+			return err
+		}
+
+		return e.NoContent(http.StatusNoContent)
+	}, mw.RSAMiddleware)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/write", bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, body, received)
+}
@@ -12,9 +12,12 @@ import (
 	"syscall"
 
 	pb "github.com/KryukovO/metricscollector/api/serverpb"
+	"github.com/KryukovO/metricscollector/internal/server/alerting"
 	"github.com/KryukovO/metricscollector/internal/server/config"
+	"github.com/KryukovO/metricscollector/internal/server/graphite"
 	sgrpc "github.com/KryukovO/metricscollector/internal/server/grpc"
 	"github.com/KryukovO/metricscollector/internal/server/http/handlers"
+	"github.com/KryukovO/metricscollector/internal/server/statsd"
 	"github.com/KryukovO/metricscollector/internal/storage"
 	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
 	"github.com/KryukovO/metricscollector/internal/storage/repository/pgstorage"
@@ -30,6 +33,9 @@ type Server struct {
 	cfg        *config.Config
 	httpServer *echo.Echo
 	grpcServer *grpc.Server
+	graphite   *graphite.Listener
+	alerting   *alerting.Manager
+	notifier   *alerting.WebhookNotifier
 	l          *log.Logger
 }
 
@@ -78,7 +84,9 @@ func (s *Server) Run(ctx context.Context) error {
 	} else {
 		repo, err = memstorage.NewMemStorage(
 			repoCtx, s.cfg.FileStoragePath, s.cfg.Restore,
-			s.cfg.StoreInterval.Duration, retries, s.l,
+			s.cfg.StoreInterval.Duration, retries,
+			memstorage.HistoryRetention{MaxAge: s.cfg.HistoryMaxAge.Duration, MaxSamples: s.cfg.HistoryMaxSamples},
+			s.l,
 		)
 	}
 
@@ -102,6 +110,30 @@ func (s *Server) Run(ctx context.Context) error {
 		}
 	}
 
+	// Инициализация подсистемы оповещений
+	if s.cfg.AlertRules != "" {
+		alertCfg, err := alerting.LoadConfig(s.cfg.AlertRules)
+		if err != nil {
+			return err
+		}
+
+		var notifier alerting.Notifier
+
+		if len(alertCfg.Receivers) > 0 {
+			s.notifier, err = alerting.NewWebhookNotifier(alertCfg.Receivers, s.l)
+			if err != nil {
+				return err
+			}
+
+			notifier = s.notifier
+		}
+
+		s.alerting, err = alerting.NewManager(stor, alertCfg.Rules, notifier, s.cfg.AlertInterval.Duration, s.l)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Инициализация HTTP-сервера
 	// NOTE: можно также переопределить e.HTTPErrorHandler, чтобы он не заполнял тело ответа
 	httpServer := echo.New()
@@ -109,16 +141,23 @@ func (s *Server) Run(ctx context.Context) error {
 	httpServer.HidePort = true
 	s.httpServer = httpServer
 
-	if err := handlers.SetHandlers(s.httpServer, stor, []byte(s.cfg.Key), s.cfg.PrivateKey, ipNet, s.l); err != nil {
+	if err := handlers.SetHandlers(
+		s.httpServer, stor, s.alerting, []byte(s.cfg.Key), s.cfg.PrivateKey, ipNet, s.cfg.AdminToken, s.l,
+	); err != nil {
 		return err
 	}
 
 	// Инициализация gRPC-сервера
-	itcManager := sgrpc.NewManager(ipNet, s.l)
+	itcManager := sgrpc.NewManager(ipNet, s.cfg.AdminToken, s.l)
 	grpcServer := grpc.NewServer(
 		grpc.ChainUnaryInterceptor(
 			itcManager.LoggingInterceptor,
 			itcManager.IPValidationInterceptor,
+			itcManager.AdminInterceptor,
+		),
+		grpc.ChainStreamInterceptor(
+			itcManager.LoggingStreamInterceptor,
+			itcManager.IPValidationStreamInterceptor,
 		),
 	)
 	s.grpcServer = grpcServer
@@ -134,18 +173,53 @@ func (s *Server) Run(ctx context.Context) error {
 	g.Go(s.runHTTPServer)
 
 	// Запуск gRPC-сервера
-	g.Go(func() error { return s.runGRPCServer(storageServer) })
+	g.Go(func() error { return s.runGRPCServer(storageServer, sgrpc.NewAlertingServer(s.alerting, s.l)) })
+
+	// Запуск приёмника метрик StatsD
+	statsdCtx, statsdCancel := context.WithCancel(groupCtx)
+	defer statsdCancel()
+
+	if s.cfg.StatsDAddress != "" {
+		listener, err := statsd.NewListener(stor, s.cfg.StatsDFlushInterval.Duration, s.l)
+		if err != nil {
+			return err
+		}
 
-	// Ожидание сигнала завершения
+		g.Go(func() error { return s.runStatsDListener(statsdCtx, listener) })
+	}
+
+	// Запуск приёмника метрик Graphite
+	if s.cfg.GraphiteAddress != "" {
+		s.graphite, err = graphite.NewListener(stor, s.l)
+		if err != nil {
+			return err
+		}
+
+		g.Go(s.runGraphiteListener)
+	}
+
+	// Запуск подсистемы оповещений
+	if s.alerting != nil {
+		g.Go(s.runAlerting)
+	}
+
+	// Запуск отправки уведомлений об оповещениях
+	if s.notifier != nil {
+		g.Go(s.notifier.Run)
+	}
+
+	// Ожидание сигнала завершения или ошибки запуска одного из компонентов сервера.
+	// В обоих случаях остальные компоненты останавливаются, иначе g.Wait не вернёт управление
 	g.Go(func() error {
 		select {
 		case <-groupCtx.Done():
-			return nil
 		case <-sigCtx.Done():
 		}
 
 		s.l.Info("Stopping server...")
 
+		statsdCancel()
+
 		shutdownCtx, cancel := context.WithTimeout(ctx, s.cfg.ShutdownTimeout.Duration)
 		defer cancel()
 
@@ -167,7 +241,7 @@ func (s *Server) runHTTPServer() error {
 	return nil
 }
 
-func (s *Server) runGRPCServer(storageServer *sgrpc.StorageServer) error {
+func (s *Server) runGRPCServer(storageServer *sgrpc.StorageServer, alertingServer *sgrpc.AlertingServer) error {
 	s.l.Infof("Run gRPC-server at %s...", s.cfg.GRPCAddress)
 
 	listen, err := net.Listen("tcp", s.cfg.GRPCAddress)
@@ -176,6 +250,7 @@ func (s *Server) runGRPCServer(storageServer *sgrpc.StorageServer) error {
 	}
 
 	pb.RegisterStorageServer(s.grpcServer, storageServer)
+	pb.RegisterAlertingServer(s.grpcServer, alertingServer)
 
 	if err := s.grpcServer.Serve(listen); err != nil {
 		return err
@@ -184,6 +259,44 @@ func (s *Server) runGRPCServer(storageServer *sgrpc.StorageServer) error {
 	return nil
 }
 
+func (s *Server) runStatsDListener(ctx context.Context, listener *statsd.Listener) error {
+	s.l.Infof("Run StatsD listener at %s...", s.cfg.StatsDAddress)
+
+	conn, err := net.ListenPacket("udp", s.cfg.StatsDAddress)
+	if err != nil {
+		return err
+	}
+
+	if err := listener.Serve(ctx, conn); err != nil {
+		return err
+	}
+
+	s.l.Info("StatsD listener stopped gracefully")
+
+	return nil
+}
+
+func (s *Server) runGraphiteListener() error {
+	s.l.Infof("Run Graphite listener at %s...", s.cfg.GraphiteAddress)
+
+	listen, err := net.Listen("tcp", s.cfg.GraphiteAddress)
+	if err != nil {
+		return err
+	}
+
+	if err := s.graphite.Serve(listen); err != nil && !errors.Is(err, graphite.ErrListenerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Server) runAlerting() error {
+	s.l.Infof("Run alerting with %s evaluation interval...", s.cfg.AlertInterval.Duration)
+
+	return s.alerting.Run()
+}
+
 func (s *Server) shutdown(ctx context.Context) {
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		s.l.Errorf("Can't gracefully shutdown HTTP-server: %s", err.Error())
@@ -194,4 +307,28 @@ func (s *Server) shutdown(ctx context.Context) {
 	s.grpcServer.GracefulStop()
 
 	s.l.Info("gRPC-server stopped gracefully")
+
+	if s.graphite != nil {
+		if err := s.graphite.Shutdown(ctx); err != nil {
+			s.l.Errorf("Can't gracefully shutdown Graphite listener: %s", err.Error())
+		} else {
+			s.l.Info("Graphite listener stopped gracefully")
+		}
+	}
+
+	if s.alerting != nil {
+		if err := s.alerting.Shutdown(ctx); err != nil {
+			s.l.Errorf("Can't gracefully shutdown alerting: %s", err.Error())
+		} else {
+			s.l.Info("Alerting stopped gracefully")
+		}
+	}
+
+	if s.notifier != nil {
+		if err := s.notifier.Shutdown(ctx); err != nil {
+			s.l.Errorf("Can't gracefully shutdown alert notifier: %s", err.Error())
+		} else {
+			s.l.Info("Alert notifier stopped gracefully")
+		}
+	}
 }
@@ -0,0 +1,104 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+)
+
+var (
+	// ErrWrongLine возвращается, если строка не соответствует формату StatsD.
+	ErrWrongLine = errors.New("wrong StatsD line")
+	// ErrUnsupportedType возвращается, если тип метрики StatsD не поддерживается.
+	ErrUnsupportedType = errors.New("unsupported StatsD metric type")
+)
+
+// Типы метрик StatsD.
+const (
+	counterType = "c"  // счётчик
+	gaugeType   = "g"  // текущее значение
+	timerType   = "ms" // длительность в миллисекундах
+	histType    = "h"  // распределение значений (DogStatsD), обрабатывается как длительность
+)
+
+// sample описывает значение метрики из строки StatsD.
+type sample struct {
+	name       string
+	mtype      string
+	value      float64
+	sampleRate float64       // доля отправленных клиентом значений; 1, если не указана
+	relative   bool          // признак изменения значения gauge относительно текущего (+N/-N)
+	labels     metric.Labels // метки из тегов DogStatsD (|#name:value,...)
+}
+
+// parseLine разбирает строку StatsD вида "name:value|type[|@rate][|#tag:value,...]".
+func parseLine(line string) (sample, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return sample{}, fmt.Errorf("%w: %q", ErrWrongLine, line)
+	}
+
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 {
+		return sample{}, fmt.Errorf("%w: %q", ErrWrongLine, line)
+	}
+
+	s := sample{name: name, mtype: parts[1], sampleRate: 1}
+
+	switch s.mtype {
+	case counterType, gaugeType, timerType, histType:
+	default:
+		return sample{}, fmt.Errorf("%w: %q", ErrUnsupportedType, s.mtype)
+	}
+
+	value, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return sample{}, fmt.Errorf("%w: wrong value %q", ErrWrongLine, parts[0])
+	}
+
+	s.value = value
+	s.relative = s.mtype == gaugeType && (parts[0][0] == '+' || parts[0][0] == '-')
+
+	for _, field := range parts[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			rate, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return sample{}, fmt.Errorf("%w: wrong sample rate %q", ErrWrongLine, field)
+			}
+
+			s.sampleRate = rate
+
+		case strings.HasPrefix(field, "#"):
+			s.labels = parseTags(field[1:])
+
+		default:
+			return sample{}, fmt.Errorf("%w: unknown field %q", ErrWrongLine, field)
+		}
+	}
+
+	return s, nil
+}
+
+// parseTags разбирает теги DogStatsD вида "name1:value1,name2" в набор меток.
+// Теги без значения преобразуются в метки с пустым значением.
+func parseTags(s string) metric.Labels {
+	labels := make(metric.Labels)
+
+	for _, tag := range strings.Split(s, ",") {
+		name, value, _ := strings.Cut(tag, ":")
+		if name = strings.TrimSpace(name); name != "" {
+			labels[name] = strings.TrimSpace(value)
+		}
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+
+	return labels
+}
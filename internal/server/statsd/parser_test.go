@@ -0,0 +1,91 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected sample
+		wantErr  error
+	}{
+		{
+			name:     "Counter",
+			line:     "api.requests:3|c",
+			expected: sample{name: "api.requests", mtype: counterType, value: 3, sampleRate: 1},
+		},
+		{
+			name:     "Counter with sample rate",
+			line:     "api.requests:1|c|@0.1",
+			expected: sample{name: "api.requests", mtype: counterType, value: 1, sampleRate: 0.1},
+		},
+		{
+			name:     "Gauge",
+			line:     "queue.size:42.5|g",
+			expected: sample{name: "queue.size", mtype: gaugeType, value: 42.5, sampleRate: 1},
+		},
+		{
+			name:     "Gauge increment",
+			line:     "queue.size:+5|g",
+			expected: sample{name: "queue.size", mtype: gaugeType, value: 5, sampleRate: 1, relative: true},
+		},
+		{
+			name:     "Gauge decrement",
+			line:     "queue.size:-5|g",
+			expected: sample{name: "queue.size", mtype: gaugeType, value: -5, sampleRate: 1, relative: true},
+		},
+		{
+			name: "Timer with tags",
+			line: "api.latency:250|ms|@0.5|#host:a,canary",
+			expected: sample{
+				name: "api.latency", mtype: timerType, value: 250, sampleRate: 0.5,
+				labels: metric.Labels{"host": "a", "canary": ""},
+			},
+		},
+		{
+			name:    "Missing type",
+			line:    "api.requests:1",
+			wantErr: ErrWrongLine,
+		},
+		{
+			name:    "Missing name",
+			line:    ":1|c",
+			wantErr: ErrWrongLine,
+		},
+		{
+			name:    "Wrong value",
+			line:    "api.requests:one|c",
+			wantErr: ErrWrongLine,
+		},
+		{
+			name:    "Wrong sample rate",
+			line:    "api.requests:1|c|@2",
+			wantErr: ErrWrongLine,
+		},
+		{
+			name:    "Set",
+			line:    "api.users:42|s",
+			wantErr: ErrUnsupportedType,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := parseLine(test.line)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, s)
+		})
+	}
+}
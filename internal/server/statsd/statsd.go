@@ -0,0 +1,319 @@
+// Package statsd содержит приёмник метрик в формате StatsD по протоколу UDP.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxPacketSize - максимальный размер принимаемой UDP-датаграммы.
+const maxPacketSize = 64 << 10
+
+var (
+	// ErrStorageIsNil возвращается NewListener, если передано неинициализированное хранилище.
+	ErrStorageIsNil = errors.New("storage is nil")
+	// ErrWrongFlushInterval возвращается NewListener, если интервал сброса метрик не положителен.
+	ErrWrongFlushInterval = errors.New("non-positive flush interval")
+)
+
+// Listener принимает метрики в формате StatsD, агрегирует их за интервал сброса
+// и записывает в хранилище.
+//
+// Счётчики суммируются с учётом частоты выборки, для gauge сохраняется последнее значение
+// (значения вида +N/-N изменяют текущее значение), длительности (ms) и распределения (h)
+// накапливаются в гистограмме с границами metric.DefaultHistogramBounds в секундах.
+type Listener struct {
+	storage       storage.Storage
+	flushInterval time.Duration
+	aggr          *aggregator
+	l             *log.Logger
+}
+
+// NewListener создаёт приёмник метрик StatsD, записывающий метрики в хранилище s
+// с интервалом flushInterval.
+func NewListener(s storage.Storage, flushInterval time.Duration, l *log.Logger) (*Listener, error) {
+	if s == nil {
+		return nil, ErrStorageIsNil
+	}
+
+	if flushInterval <= 0 {
+		return nil, ErrWrongFlushInterval
+	}
+
+	lg := log.StandardLogger()
+	if l != nil {
+		lg = l
+	}
+
+	return &Listener{
+		storage:       s,
+		flushInterval: flushInterval,
+		aggr:          newAggregator(),
+		l:             lg,
+	}, nil
+}
+
+// Serve принимает датаграммы из conn до отмены ctx. Накопленные метрики записываются в хранилище
+// каждые flushInterval и при завершении работы. Соединение conn закрывается при завершении работы.
+func (lsn *Listener) Serve(ctx context.Context, conn net.PacketConn) error {
+	readErr := make(chan error, 1)
+
+	go func() {
+		readErr <- lsn.read(conn)
+	}()
+
+	ticker := time.NewTicker(lsn.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			<-readErr
+
+			// Контекст ctx отменён, поэтому для записи метрик используется отдельный контекст
+			lsn.flush(context.Background())
+
+			return nil
+
+		case err := <-readErr:
+			lsn.flush(context.Background())
+
+			return err
+
+		case <-ticker.C:
+			lsn.flush(ctx)
+		}
+	}
+}
+
+// read читает датаграммы из conn до закрытия соединения.
+func (lsn *Listener) read(conn net.PacketConn) error {
+	buf := make([]byte, maxPacketSize)
+
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		lsn.handlePacket(string(buf[:n]))
+	}
+}
+
+// handlePacket разбирает строки датаграммы и добавляет значения в агрегатор.
+// Некорректные строки пропускаются.
+func (lsn *Listener) handlePacket(packet string) {
+	for _, line := range strings.Split(packet, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		s, err := parseLine(line)
+		if err != nil {
+			lsn.l.Debugf("StatsD: %s", err)
+
+			continue
+		}
+
+		lsn.aggr.add(s)
+	}
+}
+
+// flush записывает накопленные метрики в хранилище.
+// Если записать метрики одним набором не удалось, они записываются по одной,
+// чтобы некорректная метрика (например, гистограмма с другими границами корзин)
+// не приводила к потере остальных.
+func (lsn *Listener) flush(ctx context.Context) {
+	mtrcs := lsn.aggr.flush()
+	if len(mtrcs) == 0 {
+		return
+	}
+
+	err := lsn.storage.UpdateMany(ctx, mtrcs)
+	if err == nil {
+		return
+	}
+
+	if ctx.Err() != nil {
+		lsn.l.Errorf("StatsD: error when saving %d metrics: %s", len(mtrcs), err)
+
+		return
+	}
+
+	lsn.l.Warnf("StatsD: error when saving %d metrics: %s; saving metrics one by one", len(mtrcs), err)
+
+	for i := range mtrcs {
+		if err := lsn.storage.Update(ctx, &mtrcs[i]); err != nil {
+			lsn.l.Errorf("StatsD: metric %s of type %s dropped: %s", mtrcs[i].ID, mtrcs[i].MType, err)
+		}
+	}
+}
+
+// series описывает метрику StatsD.
+type series struct {
+	name   string
+	labels metric.Labels
+}
+
+// gaugeValue - текущее значение gauge.
+type gaugeValue struct {
+	series
+	value   float64
+	updated bool // признак изменения значения с момента последнего сброса
+}
+
+// counterValue - накопленное значение счётчика.
+type counterValue struct {
+	series
+	value   float64
+	updated bool // признак изменения значения с момента последнего сброса
+}
+
+// histogramValue - накопленные значения длительностей.
+type histogramValue struct {
+	series
+	hist *metric.Histogram
+}
+
+// aggregator накапливает значения метрик StatsD между сбросами.
+type aggregator struct {
+	mtx        sync.Mutex
+	counters   map[string]*counterValue
+	gauges     map[string]*gaugeValue
+	histograms map[string]*histogramValue
+}
+
+// newAggregator создаёт пустой агрегатор.
+func newAggregator() *aggregator {
+	return &aggregator{
+		counters:   make(map[string]*counterValue),
+		gauges:     make(map[string]*gaugeValue),
+		histograms: make(map[string]*histogramValue),
+	}
+}
+
+// add добавляет значение s.
+func (a *aggregator) add(s sample) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	k := s.name + s.labels.String()
+	ser := series{name: s.name, labels: s.labels}
+
+	switch s.mtype {
+	case counterType:
+		c, ok := a.counters[k]
+		if !ok {
+			c = &counterValue{series: ser}
+			a.counters[k] = c
+		}
+
+		c.value += s.value / s.sampleRate
+		c.updated = true
+
+	case gaugeType:
+		g, ok := a.gauges[k]
+		if !ok {
+			g = &gaugeValue{series: ser}
+			a.gauges[k] = g
+		}
+
+		if s.relative {
+			g.value += s.value
+		} else {
+			g.value = s.value
+		}
+
+		g.updated = true
+
+	default:
+		h, ok := a.histograms[k]
+		if !ok {
+			h = &histogramValue{series: ser, hist: metric.NewHistogram(metric.DefaultHistogramBounds)}
+			a.histograms[k] = h
+		}
+
+		// Каждое значение учитывается столько раз, сколько значений клиент пропустил при выборке
+		for i := 0; i < int(math.Max(1, math.Round(1/s.sampleRate))); i++ {
+			h.hist.Observe(s.value / float64(time.Second/time.Millisecond))
+		}
+	}
+}
+
+// flush возвращает метрики, накопленные с момента последнего сброса.
+// Дробная часть изменённых счётчиков переносится на следующий интервал.
+// Значения gauge сохраняются между сбросами, но возвращаются, только если были изменены.
+func (a *aggregator) flush() []metric.Metrics {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	res := make([]metric.Metrics, 0, len(a.counters)+len(a.gauges)+len(a.histograms))
+
+	for _, k := range sortedKeys(a.counters) {
+		c := a.counters[k]
+		if !c.updated {
+			delete(a.counters, k)
+
+			continue
+		}
+
+		delta := int64(c.value)
+		c.value -= float64(delta)
+		c.updated = false
+
+		res = append(res, metric.Metrics{ID: c.name, MType: metric.CounterMetric, Delta: &delta, Labels: c.labels})
+	}
+
+	for _, k := range sortedKeys(a.gauges) {
+		g := a.gauges[k]
+		if !g.updated {
+			continue
+		}
+
+		g.updated = false
+		value := g.value
+
+		res = append(res, metric.Metrics{ID: g.name, MType: metric.GaugeMetric, Value: &value, Labels: g.labels})
+	}
+
+	for _, k := range sortedKeys(a.histograms) {
+		h := a.histograms[k]
+
+		res = append(res, metric.Metrics{
+			ID: h.name, MType: metric.HistogramMetric, Histogram: h.hist, Labels: h.labels,
+		})
+
+		delete(a.histograms, k)
+	}
+
+	return res
+}
+
+// sortedKeys возвращает ключи m по возрастанию.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,137 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/KryukovO/metricscollector/internal/storage"
+	"github.com/KryukovO/metricscollector/internal/storage/repository/memstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addLines добавляет в агрегатор значения из строк StatsD.
+func addLines(t *testing.T, a *aggregator, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		s, err := parseLine(line)
+		require.NoError(t, err)
+
+		a.add(s)
+	}
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	a := newAggregator()
+
+	addLines(
+		t, a,
+		"requests:1|c", "requests:2|c", "requests:1|c|@0.4",
+		"queue:10|g", "queue:+5|g", "queue:-2|g",
+		"latency:100|ms", "latency:3000|ms|@0.5",
+	)
+
+	res := a.flush()
+	require.Len(t, res, 3)
+
+	assert.Equal(t, "requests", res[0].ID)
+	assert.EqualValues(t, 5, *res[0].Delta)
+
+	assert.Equal(t, "queue", res[1].ID)
+	assert.Equal(t, 13.0, *res[1].Value)
+
+	assert.Equal(t, metric.HistogramMetric, res[2].MType)
+	assert.EqualValues(t, 3, res[2].Histogram.Count)
+	assert.InDelta(t, 6.1, res[2].Histogram.Sum, 1e-9)
+
+	// Дробная часть счётчика переносится, значение gauge сохраняется между сбросами
+	addLines(t, a, "requests:1|c|@0.4", "queue:+1|g")
+
+	res = a.flush()
+	require.Len(t, res, 2)
+	assert.EqualValues(t, 3, *res[0].Delta)
+	assert.Equal(t, 14.0, *res[1].Value)
+
+	assert.Empty(t, a.flush(), "Unchanged metrics must not be flushed")
+}
+
+func TestListenerServe(t *testing.T) {
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	_, err = NewListener(nil, time.Second, nil)
+	assert.ErrorIs(t, err, ErrStorageIsNil)
+
+	listener, err := NewListener(storage.NewMetricsStorage(repo, time.Second), time.Hour, nil)
+	require.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- listener.Serve(ctx, conn)
+	}()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	_, err = client.Write([]byte("requests:2|c|#service:api\nwrong line\nqueue:7|g\n"))
+	require.NoError(t, err)
+
+	// Метрики записываются в хранилище при завершении работы
+	require.Eventually(t, func() bool {
+		listener.aggr.mtx.Lock()
+		defer listener.aggr.mtx.Unlock()
+
+		return len(listener.aggr.gauges) > 0
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	v, err := repo.GetValue(context.Background(), metric.CounterMetric, "requests", metric.Labels{"service": "api"})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, *v.Delta)
+
+	v, err = repo.GetValue(context.Background(), metric.GaugeMetric, "queue", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, *v.Value)
+}
+
+func TestListenerFlushFallback(t *testing.T) {
+	repo, err := memstorage.NewMemStorage(context.Background(), "", false, 0, []int{0}, memstorage.HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	// Гистограмма с границами, отличными от metric.DefaultHistogramBounds, не может быть обновлена
+	require.NoError(t, repo.Update(context.Background(), &metric.Metrics{
+		ID: "latency", MType: metric.HistogramMetric, Histogram: metric.NewHistogram([]float64{1}),
+	}))
+
+	listener, err := NewListener(storage.NewMetricsStorage(repo, time.Second), time.Hour, nil)
+	require.NoError(t, err)
+
+	addLines(t, listener.aggr, "requests:2|c", "queue:7|g", "latency:320|ms")
+	listener.flush(context.Background())
+
+	v, err := repo.GetValue(context.Background(), metric.CounterMetric, "requests", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, *v.Delta, "Valid metrics must be saved despite the invalid histogram")
+
+	v, err = repo.GetValue(context.Background(), metric.GaugeMetric, "queue", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7.0, *v.Value)
+
+	v, err = repo.GetValue(context.Background(), metric.HistogramMetric, "latency", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1}, v.Histogram.Bounds)
+	assert.EqualValues(t, 0, v.Histogram.Count)
+}
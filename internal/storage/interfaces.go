@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/KryukovO/metricscollector/internal/metric"
 )
@@ -10,12 +11,39 @@ import (
 type Storage interface {
 	// GetAll возвращает все метрики, находящиеся в хранилище.
 	GetAll(ctx context.Context) ([]metric.Metrics, error)
-	// GetValue возвращает определенную метрику, соответствующую параметрам mType и mName.
-	GetValue(ctx context.Context, mType metric.MetricType, mName string) (*metric.Metrics, error)
+	// GetValue возвращает определенную метрику, соответствующую параметрам mType, mName и labels.
+	GetValue(
+		ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+	) (*metric.Metrics, error)
+	// GetByLabels возвращает все метрики, набор меток которых содержит все метки из labels.
+	GetByLabels(ctx context.Context, labels metric.Labels) ([]metric.Metrics, error)
+	// Find возвращает страницу метрик, соответствующих условиям filter, в порядке, заданном filter.
+	// Если за страницей следуют другие метрики, возвращается курсор для получения следующей страницы.
+	Find(ctx context.Context, filter metric.Filter) ([]metric.Metrics, *metric.Cursor, error)
+	// GetHistory возвращает значения метрики, принятые в интервале времени [from, to].
+	GetHistory(
+		ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels, from, to time.Time,
+	) ([]metric.Sample, error)
+	// GetRange возвращает значения метрики за интервал времени, агрегированные по шагам согласно q.
+	// Шаги, на которых значения не принимались, не возвращаются.
+	GetRange(ctx context.Context, q metric.RangeQuery) ([]metric.Point, error)
 	// Update выполняет обновление единственной метрики.
 	Update(ctx context.Context, mtrc *metric.Metrics) error
 	// UpdateMany выполняет обновление метрик из набора.
 	UpdateMany(ctx context.Context, mtrc []metric.Metrics) error
+	// UpdateBatch выполняет обновление метрик из набора batch.
+	// Если набор с тем же идентификатором уже был применён, обновление не выполняется
+	// и возвращается false.
+	UpdateBatch(ctx context.Context, batch metric.Batch) (bool, error)
+	// Delete удаляет метрику, соответствующую параметрам mType, mName и labels, вместе с её историей.
+	// Если метрика не найдена, возвращается false.
+	Delete(ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels) (bool, error)
+	// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону pattern
+	// (см. metric.MatchName), вместе с их историей. Возвращает количество удалённых метрик.
+	DeleteByPattern(ctx context.Context, pattern string) (int64, error)
+	// Reset обнуляет значение метрики типа counter, соответствующей параметрам mName и labels.
+	// Если метрика не найдена, возвращается false.
+	Reset(ctx context.Context, mName string, labels metric.Labels) (bool, error)
 	// Ping выполняет проверку доступности хранилища.
 	Ping(ctx context.Context) bool
 	// Close выполняет закрытие хранилища.
@@ -26,12 +54,38 @@ type Storage interface {
 type Repo interface {
 	// GetAll возвращает все метрики, находящиеся в репозитории.
 	GetAll(ctx context.Context) ([]metric.Metrics, error)
-	// GetValue возвращает определенную метрику, соответствующую параметрам mType и mName.
-	GetValue(ctx context.Context, mType metric.MetricType, mName string) (*metric.Metrics, error)
+	// GetValue возвращает определенную метрику, соответствующую параметрам mType, mName и labels.
+	GetValue(
+		ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+	) (*metric.Metrics, error)
+	// GetByLabels возвращает все метрики, набор меток которых содержит все метки из labels.
+	GetByLabels(ctx context.Context, labels metric.Labels) ([]metric.Metrics, error)
+	// Find возвращает страницу метрик, соответствующих условиям filter, в порядке, заданном filter.
+	// Если за страницей следуют другие метрики, возвращается курсор для получения следующей страницы.
+	Find(ctx context.Context, filter metric.Filter) ([]metric.Metrics, *metric.Cursor, error)
+	// GetHistory возвращает значения метрики, принятые в интервале времени [from, to].
+	GetHistory(
+		ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels, from, to time.Time,
+	) ([]metric.Sample, error)
+	// GetRange возвращает значения метрики за интервал времени, агрегированные по шагам согласно q.
+	// Шаги, на которых значения не принимались, не возвращаются.
+	GetRange(ctx context.Context, q metric.RangeQuery) ([]metric.Point, error)
 	// Update выполняет обновление единственной метрики.
 	Update(ctx context.Context, mtrc *metric.Metrics) error
 	// UpdateMany выполняет обновление метрик из набора.
 	UpdateMany(ctx context.Context, mtrc []metric.Metrics) error
+	// UpdateBatch выполняет обновление метрик из набора batch и запоминает идентификатор набора.
+	// Если набор с тем же идентификатором уже был применён, обновление не выполняется и возвращается false.
+	UpdateBatch(ctx context.Context, batch metric.Batch) (bool, error)
+	// Delete удаляет метрику, соответствующую параметрам mType, mName и labels, вместе с её историей.
+	// Если метрика не найдена, возвращается false.
+	Delete(ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels) (bool, error)
+	// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону pattern
+	// (см. metric.MatchName), вместе с их историей. Возвращает количество удалённых метрик.
+	DeleteByPattern(ctx context.Context, pattern string) (int64, error)
+	// Reset обнуляет значение метрики типа counter, соответствующей параметрам mName и labels.
+	// Если метрика не найдена, возвращается false.
+	Reset(ctx context.Context, mName string, labels metric.Labels) (bool, error)
 	// Ping выполняет проверку доступности репозитория.
 	Ping(ctx context.Context) error
 	// Close выполняет закрытие репозитория.
@@ -8,6 +8,9 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -17,10 +20,30 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// batchRetention - время хранения идентификаторов применённых наборов метрик.
+	batchRetention = 24 * time.Hour
+	// pruneInterval - интервал удаления устаревших идентификаторов применённых наборов метрик
+	// и устаревших значений истории.
+	pruneInterval = time.Minute
+)
+
+// HistoryRetention описывает ограничения хранения истории принятых значений метрик.
+// Нулевое значение параметра означает отсутствие ограничения.
+type HistoryRetention struct {
+	MaxAge     time.Duration // Время хранения значения
+	MaxSamples int           // Количество хранимых значений одной метрики
+}
+
 // MemStorage - хранилище метрик с репозиторием в памяти сервера.
 // Репозиторий поддерживает функциональность сброса содержимого в файл на сервере.
 type MemStorage struct {
-	storage []metric.Metrics // in-memory хранилище метрик
+	storage   []metric.Metrics           // in-memory хранилище метрик
+	history   map[string][]metric.Sample // история принятых значений по метрикам (см. seriesKey)
+	retention HistoryRetention           // ограничения хранения истории
+
+	batches map[string]time.Time // время применения наборов метрик по идентификаторам
+	pruned  time.Time            // время последнего удаления устаревших наборов и истории
 
 	fileStoragePath string // путь до файла, в который сохраняются метрики
 	syncSave        bool   // признак синхронной записи в файл
@@ -31,9 +54,10 @@ type MemStorage struct {
 }
 
 // NewMemStorage создаёт новое in-memory хранилище.
+// История принятых значений метрик хранится с ограничениями retention.
 func NewMemStorage(
 	ctx context.Context, file string, restore bool,
-	storeInterval time.Duration, retries []int, l *log.Logger,
+	storeInterval time.Duration, retries []int, retention HistoryRetention, l *log.Logger,
 ) (*MemStorage, error) {
 	lg := log.StandardLogger()
 	if l != nil {
@@ -42,6 +66,9 @@ func NewMemStorage(
 
 	s := &MemStorage{
 		storage:         make([]metric.Metrics, 0),
+		history:         make(map[string][]metric.Sample),
+		retention:       retention,
+		batches:         make(map[string]time.Time),
 		fileStoragePath: file,
 		retries:         retries,
 		syncSave:        storeInterval == 0,
@@ -81,15 +108,38 @@ func NewMemStorage(
 	return s, nil
 }
 
-// update выполняет обновление метрики в репозитории.
-func (s *MemStorage) update(mtrc *metric.Metrics) {
+// fileContent описывает формат файла, в который сохраняются метрики.
+type fileContent struct {
+	Metrics []metric.Metrics `json:"metrics"`
+	History []metric.Sample  `json:"history"`
+	// Batches содержит время применения наборов метрик по идентификаторам
+	Batches map[string]time.Time `json:"applied_batches,omitempty"`
+}
+
+// seriesKey возвращает ключ истории метрики с типом mType, именем mName и набором меток labels.
+func seriesKey(mType metric.MetricType, mName string, labels metric.Labels) string {
+	return string(mType) + "/" + mName + labels.String()
+}
+
+// update выполняет обновление метрики в репозитории и добавляет значение в историю.
+func (s *MemStorage) update(mtrc *metric.Metrics, ts time.Time) {
+	s.appendHistory(newSample(mtrc, ts))
+
 	for i := range s.storage {
-		if mtrc.MType == s.storage[i].MType && mtrc.ID == s.storage[i].ID {
+		if mtrc.MType == s.storage[i].MType && mtrc.ID == s.storage[i].ID && mtrc.Labels.Equal(s.storage[i].Labels) {
 			if mtrc.Delta != nil {
 				*s.storage[i].Delta += *mtrc.Delta
 				mtrc.Delta = s.storage[i].Delta
 			}
 
+			if mtrc.Histogram != nil {
+				// Совместимость границ корзин проверяется заранее в checkHistograms
+				merged := s.storage[i].Histogram.Copy()
+				_ = merged.Merge(mtrc.Histogram)
+				s.storage[i].Histogram = merged
+				mtrc.Histogram = merged
+			}
+
 			s.storage[i].Value = mtrc.Value
 
 			return
@@ -99,6 +149,84 @@ func (s *MemStorage) update(mtrc *metric.Metrics) {
 	s.storage = append(s.storage, *mtrc)
 }
 
+// checkHistograms проверяет, что гистограммы из набора mtrcs могут быть объединены
+// с гистограммами в репозитории и между собой.
+func (s *MemStorage) checkHistograms(mtrcs []metric.Metrics) error {
+	bounds := make(map[string]*metric.Histogram)
+
+	for i := range mtrcs {
+		if mtrcs[i].Histogram == nil {
+			continue
+		}
+
+		key := mtrcs[i].ID + mtrcs[i].Labels.String()
+
+		h, ok := bounds[key]
+		if !ok {
+			for j := range s.storage {
+				if s.storage[j].MType == mtrcs[i].MType && s.storage[j].ID == mtrcs[i].ID &&
+					s.storage[j].Labels.Equal(mtrcs[i].Labels) {
+					h, ok = s.storage[j].Histogram, s.storage[j].Histogram != nil
+
+					break
+				}
+			}
+		}
+
+		if ok && !h.SameBounds(mtrcs[i].Histogram) {
+			return metric.ErrHistogramBoundsMismatch
+		}
+
+		bounds[key] = mtrcs[i].Histogram
+	}
+
+	return nil
+}
+
+// appendHistory добавляет значение в историю метрики. Если количество значений метрики превышает
+// retention.MaxSamples, самые ранние значения удаляются.
+func (s *MemStorage) appendHistory(sample metric.Sample) {
+	if s.history == nil {
+		s.history = make(map[string][]metric.Sample)
+	}
+
+	key := seriesKey(sample.MType, sample.ID, sample.Labels)
+	samples := append(s.history[key], sample)
+
+	// При следующем расширении слайса в новый массив копируются только хранимые значения
+	if limit := s.retention.MaxSamples; limit > 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+
+	s.history[key] = samples
+}
+
+// newSample создаёт запись истории, не разделяющую память с mtrc.
+func newSample(mtrc *metric.Metrics, ts time.Time) metric.Sample {
+	sample := metric.Sample{
+		Metrics: metric.Metrics{
+			ID:     mtrc.ID,
+			MType:  mtrc.MType,
+			Labels: mtrc.Labels.Copy(),
+		},
+		Timestamp: ts,
+	}
+
+	if mtrc.Delta != nil {
+		delta := *mtrc.Delta
+		sample.Delta = &delta
+	}
+
+	if mtrc.Value != nil {
+		value := *mtrc.Value
+		sample.Value = &value
+	}
+
+	sample.Histogram = mtrc.Histogram.Copy()
+
+	return sample
+}
+
 // save выполняет сохранение метрик из памяти сервера в файл.
 func (s *MemStorage) save(ctx context.Context) error {
 	const filePerm fs.FileMode = 0o666
@@ -130,9 +258,14 @@ func (s *MemStorage) save(ctx context.Context) error {
 
 		defer file.Close()
 
+		history := make([]metric.Sample, 0)
+		for _, samples := range s.history {
+			history = append(history, samples...)
+		}
+
 		encoder := json.NewEncoder(file)
 
-		return encoder.Encode(&s.storage)
+		return encoder.Encode(&fileContent{Metrics: s.storage, History: history, Batches: s.batches})
 	}
 
 	return nil
@@ -172,9 +305,38 @@ func (s *MemStorage) load(ctx context.Context) error {
 		return err
 	}
 
+	var raw json.RawMessage
+
 	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err = decoder.Decode(&raw); err != nil {
+		return err
+	}
+
+	// Файлы, сохранённые до появления истории, содержат только массив метрик
+	if bytes.HasPrefix(raw, []byte("[")) {
+		return json.Unmarshal(raw, &s.storage)
+	}
+
+	var content fileContent
+	if err = json.Unmarshal(raw, &content); err != nil {
+		return err
+	}
+
+	if content.Metrics != nil {
+		s.storage = content.Metrics
+	}
+
+	for i := range content.History {
+		s.appendHistory(content.History[i])
+	}
+
+	if content.Batches != nil {
+		s.batches = content.Batches
+	}
 
-	return decoder.Decode(&s.storage)
+	s.prune(time.Now())
+
+	return nil
 }
 
 // GetAll возвращает все метрики, находящиеся в репозитории.
@@ -188,13 +350,15 @@ func (s *MemStorage) GetAll(_ context.Context) ([]metric.Metrics, error) {
 	return data, nil
 }
 
-// GetValue возвращает определенную метрику, соответствующую параметрам mType и mName.
-func (s *MemStorage) GetValue(_ context.Context, mType metric.MetricType, mName string) (*metric.Metrics, error) {
+// GetValue возвращает определенную метрику, соответствующую параметрам mType, mName и labels.
+func (s *MemStorage) GetValue(
+	_ context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (*metric.Metrics, error) {
 	s.mtx.RLock()
 	defer s.mtx.RUnlock()
 
 	for _, mtrc := range s.storage {
-		if mtrc.MType == mType && mtrc.ID == mName {
+		if mtrc.MType == mType && mtrc.ID == mName && mtrc.Labels.Equal(labels) {
 			return &mtrc, nil
 		}
 	}
@@ -202,6 +366,181 @@ func (s *MemStorage) GetValue(_ context.Context, mType metric.MetricType, mName
 	return &metric.Metrics{}, nil
 }
 
+// GetByLabels возвращает все метрики, набор меток которых содержит все метки из labels.
+func (s *MemStorage) GetByLabels(_ context.Context, labels metric.Labels) ([]metric.Metrics, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	res := make([]metric.Metrics, 0)
+
+	for _, mtrc := range s.storage {
+		if mtrc.Labels.Match(labels) {
+			res = append(res, mtrc)
+		}
+	}
+
+	return res, nil
+}
+
+// Find возвращает страницу метрик, соответствующих условиям filter, в порядке, заданном filter.
+// Если за страницей следуют другие метрики, возвращается курсор для получения следующей страницы.
+// Ключом набора меток в курсоре является metric.Labels.String.
+func (s *MemStorage) Find(_ context.Context, filter metric.Filter) ([]metric.Metrics, *metric.Cursor, error) {
+	var nameRe *regexp.Regexp
+
+	if filter.NameRegexp != "" {
+		re, err := regexp.Compile(filter.NameRegexp)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		nameRe = re
+	}
+
+	// Порядок сортировки: 1 - по возрастанию, -1 - по убыванию
+	order := 1
+	if filter.Desc {
+		order = -1
+	}
+
+	s.mtx.RLock()
+
+	res := make([]metric.Metrics, 0)
+
+	for _, mtrc := range s.storage {
+		if !matchFilter(&mtrc, &filter, nameRe) {
+			continue
+		}
+
+		if filter.After != nil && order*cursorOf(&mtrc).Compare(*filter.After, filter.Sort) <= 0 {
+			continue
+		}
+
+		res = append(res, mtrc)
+	}
+
+	s.mtx.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return order*cursorOf(&res[i]).Compare(cursorOf(&res[j]), filter.Sort) < 0
+	})
+
+	if filter.Limit <= 0 || len(res) <= filter.Limit {
+		return res, nil, nil
+	}
+
+	res = res[:filter.Limit]
+	next := cursorOf(&res[len(res)-1])
+
+	return res, &next, nil
+}
+
+// matchFilter проверяет соответствие метрики условиям выборки filter.
+// nameRe - скомпилированное регулярное выражение filter.NameRegexp или nil.
+func matchFilter(mtrc *metric.Metrics, filter *metric.Filter, nameRe *regexp.Regexp) bool {
+	if len(filter.Types) > 0 {
+		found := false
+
+		for _, t := range filter.Types {
+			if mtrc.MType == t {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if !strings.HasPrefix(mtrc.ID, filter.NamePrefix) {
+		return false
+	}
+
+	if nameRe != nil && !nameRe.MatchString(mtrc.ID) {
+		return false
+	}
+
+	return mtrc.Labels.Match(filter.Labels)
+}
+
+// cursorOf возвращает позицию метрики в упорядоченной выборке.
+func cursorOf(mtrc *metric.Metrics) metric.Cursor {
+	return metric.Cursor{Name: mtrc.ID, Type: mtrc.MType, Labels: mtrc.Labels.String()}
+}
+
+// GetHistory возвращает значения метрики, принятые в интервале времени [from, to].
+func (s *MemStorage) GetHistory(
+	_ context.Context, mType metric.MetricType, mName string, labels metric.Labels, from, to time.Time,
+) ([]metric.Sample, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	res := make([]metric.Sample, 0)
+
+	for _, sample := range s.history[seriesKey(mType, mName, labels)] {
+		if sample.Timestamp.Before(from) || sample.Timestamp.After(to) {
+			continue
+		}
+
+		res = append(res, sample)
+	}
+
+	return res, nil
+}
+
+// GetRange возвращает значения метрики за интервал времени, агрегированные по шагам согласно q.
+// Шаги, на которых значения не принимались, не возвращаются.
+func (s *MemStorage) GetRange(ctx context.Context, q metric.RangeQuery) ([]metric.Point, error) {
+	samples, err := s.GetHistory(ctx, q.MType, q.Name, q.Labels, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+
+	var (
+		res    = make([]metric.Point, 0)
+		values = make([]float64, 0)
+		step   int64
+	)
+
+	// flush добавляет в результат значение шага с накопленными значениями
+	flush := func() {
+		if len(values) == 0 {
+			return
+		}
+
+		res = append(res, metric.Point{
+			Timestamp: q.From.Add(time.Duration(step) * q.Step),
+			Value:     q.Aggregation.Apply(values, q.Step),
+		})
+		values = values[:0]
+	}
+
+	for _, sample := range samples {
+		if n := int64(sample.Timestamp.Sub(q.From) / q.Step); n != step {
+			flush()
+
+			step = n
+		}
+
+		switch {
+		case sample.Delta != nil:
+			values = append(values, float64(*sample.Delta))
+		case sample.Value != nil:
+			values = append(values, *sample.Value)
+		}
+	}
+
+	flush()
+
+	return res, nil
+}
+
 // Update выполняет обновление единственной метрики.
 func (s *MemStorage) Update(ctx context.Context, mtrc *metric.Metrics) error {
 	defer func() {
@@ -216,7 +555,14 @@ func (s *MemStorage) Update(ctx context.Context, mtrc *metric.Metrics) error {
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
-	s.update(mtrc)
+	if err := s.checkHistograms([]metric.Metrics{*mtrc}); err != nil {
+		return err
+	}
+
+	ts := time.Now()
+
+	s.update(mtrc, ts)
+	s.prune(ts)
 
 	return nil
 }
@@ -226,13 +572,197 @@ func (s *MemStorage) UpdateMany(_ context.Context, mtrcs []metric.Metrics) error
 	s.mtx.Lock()
 	defer s.mtx.Unlock()
 
+	if err := s.checkHistograms(mtrcs); err != nil {
+		return err
+	}
+
+	ts := time.Now()
+
 	for i := 0; i < len(mtrcs); i++ {
-		s.update(&mtrcs[i])
+		s.update(&mtrcs[i], ts)
 	}
 
+	s.prune(ts)
+
 	return nil
 }
 
+// UpdateBatch выполняет обновление метрик из набора batch и запоминает идентификатор набора.
+// Если набор с тем же идентификатором уже был применён в течение batchRetention,
+// обновление не выполняется и возвращается false.
+func (s *MemStorage) UpdateBatch(_ context.Context, batch metric.Batch) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	ts := time.Now()
+
+	if appliedAt, found := s.batches[batch.ID]; found && ts.Sub(appliedAt) < batchRetention {
+		return false, nil
+	}
+
+	if err := s.checkHistograms(batch.Metrics); err != nil {
+		return false, err
+	}
+
+	for i := 0; i < len(batch.Metrics); i++ {
+		s.update(&batch.Metrics[i], ts)
+	}
+
+	if s.batches == nil {
+		s.batches = make(map[string]time.Time)
+	}
+
+	s.batches[batch.ID] = ts
+
+	s.prune(ts)
+
+	return true, nil
+}
+
+// prune удаляет идентификаторы наборов, применённых раньше, чем за batchRetention до ts,
+// и значения истории, принятые раньше, чем за retention.MaxAge до ts.
+// Удаление выполняется не чаще, чем раз в pruneInterval.
+func (s *MemStorage) prune(ts time.Time) {
+	if ts.Sub(s.pruned) < pruneInterval {
+		return
+	}
+
+	s.pruned = ts
+
+	if s.retention.MaxAge > 0 {
+		s.pruneHistory(ts.Add(-s.retention.MaxAge))
+	}
+
+	for id, appliedAt := range s.batches {
+		if ts.Sub(appliedAt) >= batchRetention {
+			delete(s.batches, id)
+		}
+	}
+}
+
+// pruneHistory удаляет значения истории, принятые раньше момента времени before.
+func (s *MemStorage) pruneHistory(before time.Time) {
+	for key, samples := range s.history {
+		kept := samples[:0]
+
+		for i := range samples {
+			if !samples[i].Timestamp.Before(before) {
+				kept = append(kept, samples[i])
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(s.history, key)
+
+			continue
+		}
+
+		// Освободившаяся часть массива очищается, чтобы не удерживать удалённые значения
+		for i := len(kept); i < len(samples); i++ {
+			samples[i] = metric.Sample{}
+		}
+
+		s.history[key] = kept
+	}
+}
+
+// Delete удаляет метрику, соответствующую параметрам mType, mName и labels, вместе с её историей.
+// Если метрика не найдена, возвращается false.
+func (s *MemStorage) Delete(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (bool, error) {
+	defer s.syncSaveIfNeeded(ctx)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	deleted := s.remove(func(mtrc *metric.Metrics) bool {
+		return mtrc.MType == mType && mtrc.ID == mName && mtrc.Labels.Equal(labels)
+	})
+
+	return deleted > 0, nil
+}
+
+// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону pattern
+// (см. metric.MatchName), вместе с их историей. Возвращает количество удалённых метрик.
+func (s *MemStorage) DeleteByPattern(ctx context.Context, pattern string) (int64, error) {
+	defer s.syncSaveIfNeeded(ctx)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	deleted := s.remove(func(mtrc *metric.Metrics) bool {
+		return metric.MatchName(pattern, mtrc.ID)
+	})
+
+	return int64(deleted), nil
+}
+
+// Reset обнуляет значение метрики типа counter, соответствующей параметрам mName и labels,
+// и удаляет её историю, чтобы приращения до сброса не учитывались в запросах за интервал.
+// Если метрика не найдена, возвращается false.
+func (s *MemStorage) Reset(ctx context.Context, mName string, labels metric.Labels) (bool, error) {
+	defer s.syncSaveIfNeeded(ctx)
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for i := range s.storage {
+		mtrc := &s.storage[i]
+		if mtrc.MType != metric.CounterMetric || mtrc.ID != mName || !mtrc.Labels.Equal(labels) {
+			continue
+		}
+
+		// Значение заменяется, а не изменяется на месте: указатель мог быть возвращён при обновлении
+		var zero int64
+		mtrc.Delta = &zero
+
+		delete(s.history, seriesKey(metric.CounterMetric, mName, labels))
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// remove удаляет из репозитория и истории метрики, для которых match возвращает true.
+// Возвращает количество удалённых метрик.
+func (s *MemStorage) remove(match func(mtrc *metric.Metrics) bool) int {
+	storage := s.storage[:0]
+
+	for i := range s.storage {
+		if !match(&s.storage[i]) {
+			storage = append(storage, s.storage[i])
+		}
+	}
+
+	deleted := len(s.storage) - len(storage)
+	s.storage = storage
+
+	if deleted == 0 {
+		return 0
+	}
+
+	for key, samples := range s.history {
+		if len(samples) > 0 && match(&samples[0].Metrics) {
+			delete(s.history, key)
+		}
+	}
+
+	return deleted
+}
+
+// syncSaveIfNeeded выполняет сохранение метрик в файл, если включена синхронная запись.
+func (s *MemStorage) syncSaveIfNeeded(ctx context.Context) {
+	if !s.syncSave {
+		return
+	}
+
+	if err := s.save(ctx); err != nil {
+		s.l.Errorf("error when saving metrics to the file: %s", err)
+	}
+}
+
 // Ping выполняет проверку доступности репозитория.
 func (s *MemStorage) Ping(_ context.Context) error {
 	return nil
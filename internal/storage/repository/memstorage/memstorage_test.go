@@ -2,7 +2,10 @@ package memstorage
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/KryukovO/metricscollector/internal/metric"
 	"github.com/stretchr/testify/assert"
@@ -37,13 +40,15 @@ func TestGetAll(t *testing.T) {
 
 func TestGetValue(t *testing.T) {
 	var (
-		counterVal int64 = 100
-		gaugeVal         = 12345.67
+		counterVal      int64 = 100
+		gaugeVal              = 12345.67
+		labeledGaugeVal       = 67.12345
 	)
 
 	type args struct {
-		mType metric.MetricType
-		mname string
+		mType  metric.MetricType
+		mname  string
+		labels metric.Labels
 	}
 
 	type want struct {
@@ -119,6 +124,35 @@ func TestGetValue(t *testing.T) {
 				ok:    false,
 			},
 		},
+		{
+			name: "Existing labeled value",
+			args: args{
+				mType:  "gauge",
+				mname:  "RandomValue",
+				labels: metric.Labels{"host": "a"},
+			},
+			want: want{
+				value: &metric.Metrics{
+					ID:     "RandomValue",
+					MType:  metric.GaugeMetric,
+					Value:  &labeledGaugeVal,
+					Labels: metric.Labels{"host": "a"},
+				},
+				ok: true,
+			},
+		},
+		{
+			name: "Labels do not match",
+			args: args{
+				mType:  "gauge",
+				mname:  "RandomValue",
+				labels: metric.Labels{"host": "b"},
+			},
+			want: want{
+				value: &metric.Metrics{},
+				ok:    false,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -135,10 +169,16 @@ func TestGetValue(t *testing.T) {
 						MType: metric.GaugeMetric,
 						Value: &gaugeVal,
 					},
+					{
+						ID:     "RandomValue",
+						MType:  metric.GaugeMetric,
+						Value:  &labeledGaugeVal,
+						Labels: metric.Labels{"host": "a"},
+					},
 				},
 			}
 
-			v, err := s.GetValue(context.Background(), test.args.mType, test.args.mname)
+			v, err := s.GetValue(context.Background(), test.args.mType, test.args.mname, test.args.labels)
 			assert.NoError(t, err)
 			assert.Equal(t, test.want.value, v)
 		})
@@ -402,6 +442,589 @@ func TestUpdateMany(t *testing.T) {
 	}
 }
 
+func TestUpdateLabels(t *testing.T) {
+	var (
+		hostA int64 = 100
+		hostB int64 = 500
+	)
+
+	s := &MemStorage{}
+
+	err := s.UpdateMany(
+		context.Background(),
+		[]metric.Metrics{
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &hostA, Labels: metric.Labels{"host": "a"}},
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &hostB, Labels: metric.Labels{"host": "b"}},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, s.storage, 2, "Metrics with different labels must be stored separately")
+
+	v, err := s.GetByLabels(context.Background(), metric.Labels{"host": "b"})
+	require.NoError(t, err)
+	require.Len(t, v, 1)
+	assert.EqualValues(t, 500, *v[0].Delta)
+
+	v, err = s.GetByLabels(context.Background(), metric.Labels{"host": "c"})
+	require.NoError(t, err)
+	assert.Empty(t, v)
+}
+
+func TestUpdateHistogram(t *testing.T) {
+	s := &MemStorage{}
+
+	first := metric.NewHistogram([]float64{1, 2})
+	first.Observe(0.5)
+
+	second := metric.NewHistogram([]float64{1, 2})
+	second.Observe(1.5)
+	second.Observe(3)
+
+	err := s.UpdateMany(
+		context.Background(),
+		[]metric.Metrics{
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: first},
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: second},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, s.storage, 1)
+	assert.Equal(
+		t,
+		&metric.Histogram{Bounds: []float64{1, 2}, Counts: []uint64{1, 1, 1}, Sum: 5, Count: 3},
+		s.storage[0].Histogram,
+	)
+	assert.EqualValues(t, 1, first.Count, "Stored histogram must not share memory with the update")
+
+	other := metric.NewHistogram([]float64{1})
+	other.Observe(0.5)
+
+	err = s.Update(context.Background(), &metric.Metrics{ID: "Latency", MType: metric.HistogramMetric, Histogram: other})
+	assert.ErrorIs(t, err, metric.ErrHistogramBoundsMismatch)
+	assert.EqualValues(t, 3, s.storage[0].Histogram.Count)
+	assert.Equal(t, 2, historyLen(s))
+
+	err = s.UpdateMany(
+		context.Background(),
+		[]metric.Metrics{
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: second, Labels: metric.Labels{"host": "a"}},
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: other, Labels: metric.Labels{"host": "a"}},
+		},
+	)
+	assert.ErrorIs(t, err, metric.ErrHistogramBoundsMismatch)
+	assert.Len(t, s.storage, 1, "Batch with incompatible histograms must not be applied")
+}
+
+func TestUpdateBatch(t *testing.T) {
+	var counterVal int64 = 100
+
+	file := filepath.Join(t.TempDir(), "metrics.json")
+
+	s, err := NewMemStorage(context.Background(), file, false, 0, []int{0}, HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	batch := metric.Batch{
+		ID:      "batch-1",
+		Metrics: []metric.Metrics{{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}},
+	}
+
+	applied, err := s.UpdateBatch(context.Background(), batch)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	applied, err = s.UpdateBatch(context.Background(), batch)
+	require.NoError(t, err)
+	assert.False(t, applied, "Retried batch must not be applied twice")
+
+	applied, err = s.UpdateBatch(context.Background(), metric.Batch{ID: "batch-3", Metrics: batch.Metrics})
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	v, err := s.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 200, *v.Delta)
+
+	// Набор, который не удалось применить, может быть отправлен повторно
+	h := metric.NewHistogram([]float64{1})
+	failed := metric.Batch{
+		ID: "batch-2",
+		Metrics: []metric.Metrics{
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: h},
+			{ID: "Latency", MType: metric.HistogramMetric, Histogram: metric.NewHistogram([]float64{2})},
+		},
+	}
+
+	_, err = s.UpdateBatch(context.Background(), failed)
+	assert.ErrorIs(t, err, metric.ErrHistogramBoundsMismatch)
+
+	failed.Metrics = failed.Metrics[:1]
+
+	applied, err = s.UpdateBatch(context.Background(), failed)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	// Идентификаторы применённых наборов сохраняются в файл вместе с метриками
+	require.NoError(t, s.Close())
+
+	restored, err := NewMemStorage(context.Background(), file, true, 0, []int{0}, HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	applied, err = restored.UpdateBatch(context.Background(), batch)
+	require.NoError(t, err)
+	assert.False(t, applied)
+
+	// Устаревшие идентификаторы удаляются
+	restored.batches["batch-1"] = time.Now().Add(-batchRetention)
+
+	applied, err = restored.UpdateBatch(context.Background(), batch)
+	require.NoError(t, err)
+	assert.True(t, applied)
+
+	restored.batches["batch-3"] = time.Now().Add(-batchRetention)
+	restored.pruned = time.Time{}
+	restored.prune(time.Now())
+	assert.NotContains(t, restored.batches, "batch-3")
+}
+
+func TestHistoryRetention(t *testing.T) {
+	var (
+		ts       = time.Now()
+		gaugeVal = 12345.67
+		gauge    = &metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal}
+		labels   = metric.Labels{"host": "a"}
+		other    = &metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: labels}
+	)
+
+	s := &MemStorage{retention: HistoryRetention{MaxAge: time.Hour, MaxSamples: 3}}
+
+	for i := 5; i > 0; i-- {
+		s.update(gauge, ts.Add(-time.Duration(i)*time.Minute))
+	}
+
+	s.update(other, ts.Add(-2*time.Hour))
+	s.update(other, ts.Add(-time.Minute))
+
+	samples, err := s.GetHistory(context.Background(), metric.GaugeMetric, "RandomValue", nil, ts.Add(-time.Hour), ts)
+	require.NoError(t, err)
+	require.Len(t, samples, 3, "Only the latest MaxSamples values of a metric must be kept")
+	assert.Equal(t, ts.Add(-3*time.Minute), samples[0].Timestamp)
+	assert.Equal(t, 5, historyLen(s))
+
+	s.prune(ts)
+	assert.Equal(t, 4, historyLen(s), "Values older than MaxAge must be removed")
+
+	s.update(other, ts.Add(-3*time.Hour))
+	s.prune(ts.Add(time.Second))
+	assert.Equal(t, 5, historyLen(s), "History must be pruned at most once per pruneInterval")
+
+	s.prune(ts.Add(2 * time.Hour))
+	assert.Zero(t, historyLen(s))
+	assert.Empty(t, s.history, "Metrics without values must be removed from history")
+}
+
+func TestDelete(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+	)
+
+	file := filepath.Join(t.TempDir(), "metrics.json")
+
+	s, err := NewMemStorage(context.Background(), file, false, 0, []int{0}, HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	err = s.UpdateMany(
+		context.Background(),
+		[]metric.Metrics{
+			{ID: "cpu_user", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "a"}},
+			{ID: "cpu_user", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "b"}},
+			{ID: "cpu_system", MType: metric.GaugeMetric, Value: &gaugeVal},
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal},
+		},
+	)
+	require.NoError(t, err)
+
+	deleted, err := s.Delete(context.Background(), metric.GaugeMetric, "cpu_user", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	assert.Len(t, s.storage, 3)
+	assert.Equal(t, 3, historyLen(s), "History of the deleted metric must be removed")
+
+	deleted, err = s.Delete(context.Background(), metric.CounterMetric, "cpu_user", metric.Labels{"host": "b"})
+	require.NoError(t, err)
+	assert.False(t, deleted, "Metric type must match")
+
+	count, err := s.DeleteByPattern(context.Background(), "cpu_*")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	require.Len(t, s.storage, 1)
+	assert.Equal(t, "PollCount", s.storage[0].ID)
+	assert.Equal(t, 1, historyLen(s))
+
+	// Удаление сохраняется в файл при синхронной записи
+	restored, err := NewMemStorage(context.Background(), file, true, 0, []int{0}, HistoryRetention{}, nil)
+	require.NoError(t, err)
+
+	v, err := restored.GetAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, v, 1)
+}
+
+func TestReset(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+	)
+
+	s := &MemStorage{}
+
+	mtrc := metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}
+	require.NoError(t, s.Update(context.Background(), &mtrc))
+	require.NoError(
+		t,
+		s.Update(context.Background(), &metric.Metrics{ID: "PollCount", MType: metric.GaugeMetric, Value: &gaugeVal}),
+	)
+
+	reset, err := s.Reset(context.Background(), "PollCount", nil)
+	require.NoError(t, err)
+	assert.True(t, reset)
+	assert.EqualValues(t, 100, *mtrc.Delta, "Reset must not change values returned by earlier updates")
+
+	v, err := s.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, *v.Delta)
+
+	v, err = s.GetValue(context.Background(), metric.GaugeMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.Equal(t, gaugeVal, *v.Value, "Only counters must be reset")
+
+	reset, err = s.Reset(context.Background(), "PollCount", metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	assert.False(t, reset)
+}
+
+func TestResetRange(t *testing.T) {
+	var (
+		from  = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+		delta = []int64{5, 7, 2}
+	)
+
+	s := &MemStorage{}
+
+	s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &delta[0]}, from)
+	s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &delta[1]}, from.Add(10*time.Second))
+
+	reset, err := s.Reset(context.Background(), "PollCount", nil)
+	require.NoError(t, err)
+	require.True(t, reset)
+
+	s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &delta[2]}, from.Add(20*time.Second))
+
+	points, err := s.GetRange(context.Background(), metric.RangeQuery{
+		MType: metric.CounterMetric, Name: "PollCount", From: from, To: from.Add(time.Minute),
+		Step: time.Minute, Aggregation: metric.AggregationSum,
+	})
+	require.NoError(t, err)
+	require.Len(t, points, 1)
+	assert.InDelta(t, 2, points[0].Value, 1e-9, "Increments made before Reset must not be summed")
+
+	v, err := s.GetValue(context.Background(), metric.CounterMetric, "PollCount", nil)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, *v.Delta, "Range sum must match the current counter value")
+}
+
+func TestFind(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+	)
+
+	s := &MemStorage{}
+
+	err := s.UpdateMany(
+		context.Background(),
+		[]metric.Metrics{
+			{ID: "cpu_user", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "b"}},
+			{ID: "cpu_user", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "a"}},
+			{ID: "cpu_system", MType: metric.GaugeMetric, Value: &gaugeVal, Labels: metric.Labels{"host": "a"}},
+			{ID: "cpu_count", MType: metric.CounterMetric, Delta: &counterVal},
+			{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal},
+		},
+	)
+	require.NoError(t, err)
+
+	// ids возвращает имена и наборы меток метрик
+	ids := func(mtrcs []metric.Metrics) []string {
+		res := make([]string, 0, len(mtrcs))
+		for _, m := range mtrcs {
+			res = append(res, m.ID+m.Labels.String())
+		}
+
+		return res
+	}
+
+	tests := []struct {
+		name     string
+		filter   metric.Filter
+		expected []string
+	}{
+		{
+			name:   "Sort by name",
+			filter: metric.Filter{Sort: metric.SortByName},
+			expected: []string{
+				"PollCount", "cpu_count", `cpu_system{host="a"}`, `cpu_user{host="a"}`, `cpu_user{host="b"}`,
+			},
+		},
+		{
+			name:     "Sort by type descending",
+			filter:   metric.Filter{Sort: metric.SortByType, Desc: true, NamePrefix: "cpu_"},
+			expected: []string{`cpu_user{host="b"}`, `cpu_user{host="a"}`, `cpu_system{host="a"}`, "cpu_count"},
+		},
+		{
+			name: "Filter by type, regexp and labels",
+			filter: metric.Filter{
+				Types: []metric.MetricType{metric.GaugeMetric}, NameRegexp: "user$", Labels: metric.Labels{"host": "a"},
+			},
+			expected: []string{`cpu_user{host="a"}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			res, next, err := s.Find(context.Background(), test.filter)
+			require.NoError(t, err)
+			assert.Nil(t, next)
+			assert.Equal(t, test.expected, ids(res))
+		})
+	}
+
+	// Постраничная выборка возвращает все метрики ровно один раз
+	var (
+		filter = metric.Filter{Sort: metric.SortByName, Desc: true, Limit: 2}
+		pages  = make([]string, 0)
+	)
+
+	for i := 0; i < 3; i++ {
+		res, next, err := s.Find(context.Background(), filter)
+		require.NoError(t, err)
+
+		pages = append(pages, ids(res)...)
+
+		if i < 2 {
+			require.NotNil(t, next)
+			assert.Len(t, res, 2)
+		} else {
+			assert.Nil(t, next)
+		}
+
+		filter.After = next
+	}
+
+	assert.Equal(
+		t,
+		[]string{`cpu_user{host="b"}`, `cpu_user{host="a"}`, `cpu_system{host="a"}`, "cpu_count", "PollCount"},
+		pages,
+	)
+}
+
+func TestGetHistory(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+		newGauge         = 67.12345
+		ts               = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+	)
+
+	s := &MemStorage{}
+
+	s.update(&metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal}, ts)
+	s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}, ts)
+	s.update(&metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &newGauge}, ts.Add(time.Minute))
+	s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}, ts.Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		mType    metric.MetricType
+		mName    string
+		from, to time.Time
+		expected []float64
+	}{
+		{
+			name:     "Whole gauge history",
+			mType:    metric.GaugeMetric,
+			mName:    "RandomValue",
+			from:     ts,
+			to:       ts.Add(time.Hour),
+			expected: []float64{12345.67, 67.12345},
+		},
+		{
+			name:     "Gauge history in range",
+			mType:    metric.GaugeMetric,
+			mName:    "RandomValue",
+			from:     ts.Add(time.Second),
+			to:       ts.Add(time.Hour),
+			expected: []float64{67.12345},
+		},
+		{
+			name:     "Counter history keeps deltas",
+			mType:    metric.CounterMetric,
+			mName:    "PollCount",
+			from:     ts,
+			to:       ts.Add(time.Hour),
+			expected: []float64{100, 100},
+		},
+		{
+			name:     "Metric with name exists, but type incorrect",
+			mType:    metric.GaugeMetric,
+			mName:    "PollCount",
+			from:     ts,
+			to:       ts.Add(time.Hour),
+			expected: []float64{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			samples, err := s.GetHistory(context.Background(), test.mType, test.mName, nil, test.from, test.to)
+			require.NoError(t, err)
+
+			values := make([]float64, 0, len(samples))
+
+			for _, sample := range samples {
+				if sample.Delta != nil {
+					values = append(values, float64(*sample.Delta))
+				} else {
+					values = append(values, *sample.Value)
+				}
+			}
+
+			assert.Equal(t, test.expected, values)
+		})
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	var (
+		from  = time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+		delta = []int64{1, 2, 3, 4}
+		gauge = []float64{10, 20, 30}
+	)
+
+	s := &MemStorage{}
+
+	// Значения принимаются в порядке, отличном от хронологического
+	for i, offset := range []time.Duration{70 * time.Second, 10 * time.Second, 20 * time.Second, 3 * time.Minute} {
+		s.update(&metric.Metrics{ID: "PollCount", MType: metric.CounterMetric, Delta: &delta[i]}, from.Add(offset))
+	}
+
+	for i, offset := range []time.Duration{0, 30 * time.Second, time.Hour} {
+		s.update(&metric.Metrics{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gauge[i]}, from.Add(offset))
+	}
+
+	tests := []struct {
+		name     string
+		query    metric.RangeQuery
+		expected []metric.Point
+	}{
+		{
+			name: "Counter rate",
+			query: metric.RangeQuery{
+				MType: metric.CounterMetric, Name: "PollCount", From: from, To: from.Add(5 * time.Minute),
+				Step: time.Minute, Aggregation: metric.AggregationRate,
+			},
+			expected: []metric.Point{
+				{Timestamp: from, Value: 5.0 / 60},
+				{Timestamp: from.Add(time.Minute), Value: 1.0 / 60},
+				{Timestamp: from.Add(3 * time.Minute), Value: 4.0 / 60},
+			},
+		},
+		{
+			name: "Last counter increment",
+			query: metric.RangeQuery{
+				MType: metric.CounterMetric, Name: "PollCount", From: from, To: from.Add(2 * time.Minute),
+				Step: time.Minute, Aggregation: metric.AggregationLast,
+			},
+			expected: []metric.Point{
+				{Timestamp: from, Value: 3},
+				{Timestamp: from.Add(time.Minute), Value: 1},
+			},
+		},
+		{
+			name: "Gauge average",
+			query: metric.RangeQuery{
+				MType: metric.GaugeMetric, Name: "RandomValue", From: from, To: from.Add(10 * time.Minute),
+				Step: 10 * time.Minute, Aggregation: metric.AggregationAvg,
+			},
+			expected: []metric.Point{{Timestamp: from, Value: 15}},
+		},
+		{
+			name: "Unknown metric",
+			query: metric.RangeQuery{
+				MType: metric.GaugeMetric, Name: "PollCount", From: from, To: from.Add(time.Hour),
+				Step: time.Minute, Aggregation: metric.AggregationMax,
+			},
+			expected: []metric.Point{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			points, err := s.GetRange(context.Background(), test.query)
+			require.NoError(t, err)
+			require.Len(t, points, len(test.expected))
+
+			for i := range points {
+				assert.Equal(t, test.expected[i].Timestamp, points[i].Timestamp)
+				assert.InDelta(t, test.expected[i].Value, points[i].Value, 1e-9)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	var (
+		counterVal int64 = 100
+		gaugeVal         = 12345.67
+	)
+
+	t.Run("Save and load with history", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "metrics.json")
+
+		s, err := NewMemStorage(context.Background(), file, false, 0, []int{0}, HistoryRetention{}, nil)
+		require.NoError(t, err)
+
+		err = s.UpdateMany(
+			context.Background(),
+			[]metric.Metrics{
+				{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal},
+				{ID: "RandomValue", MType: metric.GaugeMetric, Value: &gaugeVal},
+			},
+		)
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		restored, err := NewMemStorage(context.Background(), file, true, 0, []int{0}, HistoryRetention{}, nil)
+		require.NoError(t, err)
+
+		assert.Len(t, restored.storage, 2)
+		assert.Equal(t, 2, historyLen(restored))
+	})
+
+	t.Run("Load legacy file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "metrics.json")
+
+		err := os.WriteFile(file, []byte(`[{"id":"PollCount","type":"counter","delta":100}]`), 0o600)
+		require.NoError(t, err)
+
+		restored, err := NewMemStorage(context.Background(), file, true, 0, []int{0}, HistoryRetention{}, nil)
+		require.NoError(t, err)
+
+		require.Len(t, restored.storage, 1)
+		assert.Equal(t, "PollCount", restored.storage[0].ID)
+		assert.Zero(t, historyLen(restored))
+	})
+}
+
 func BenchmarkGet(b *testing.B) {
 	ctx := context.Background()
 	counterVal := int64(100)
@@ -423,7 +1046,7 @@ func BenchmarkGet(b *testing.B) {
 	}
 
 	b.Run("getValue", func(b *testing.B) {
-		_, err := s.GetValue(ctx, metric.CounterMetric, "PollCount")
+		_, err := s.GetValue(ctx, metric.CounterMetric, "PollCount", nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -484,3 +1107,13 @@ func BenchmarkUpdate(b *testing.B) {
 		}
 	})
 }
+
+// historyLen возвращает количество значений в истории хранилища s.
+func historyLen(s *MemStorage) int {
+	n := 0
+	for _, samples := range s.history {
+		n += len(samples)
+	}
+
+	return n
+}
@@ -4,8 +4,11 @@ package pgstorage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/KryukovO/metricscollector/internal/metric"
@@ -16,6 +19,9 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// batchRetention - время хранения идентификаторов применённых наборов метрик.
+const batchRetention = 24 * time.Hour
+
 // PgStorage - хранилище метрик в репозитории PostgreSQL.
 type PgStorage struct {
 	db      *sql.DB
@@ -64,16 +70,196 @@ func (s *PgStorage) runMigrations(dsn, migrations string) error {
 	return nil
 }
 
+// marshalLabels возвращает JSON-представление набора меток для записи в репозиторий.
+func marshalLabels(labels metric.Labels) (string, error) {
+	if labels == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// unmarshalLabels разбирает JSON-представление набора меток, полученное из репозитория.
+// Для пустого набора возвращается nil.
+func unmarshalLabels(data []byte) (metric.Labels, error) {
+	labels := make(metric.Labels)
+
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return nil, err
+	}
+
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	return labels, nil
+}
+
+// marshalHistogram возвращает JSON-представление гистограммы для записи в репозиторий.
+// Для nil возвращается nil, что соответствует NULL.
+func marshalHistogram(h *metric.Histogram) (interface{}, error) {
+	if h == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+// setValue заполняет значение метрики по полям delta, value и histogram, полученным из репозитория.
+func setValue(mtrc *metric.Metrics, delta sql.NullInt64, value sql.NullFloat64, histogram []byte) error {
+	switch {
+	case histogram != nil:
+		mtrc.Histogram = &metric.Histogram{}
+
+		return json.Unmarshal(histogram, mtrc.Histogram)
+	case delta.Valid:
+		mtrc.Delta = &delta.Int64
+	default:
+		mtrc.Value = &value.Float64
+	}
+
+	return nil
+}
+
+// updateHistogram выполняет в рамках транзакции tx слияние гистограммы mtrc с сохранённой в репозитории
+// и добавляет значение в историю. Возвращает итоговое значение гистограммы.
+func updateHistogram(ctx context.Context, tx *sql.Tx, mtrc *metric.Metrics, lbls string) (*metric.Histogram, error) {
+	insertQuery := `
+		INSERT INTO metrics(mname, mtype, histogram, labels) VALUES($1, $2, $3::jsonb, $4::jsonb)
+		ON CONFLICT (mname, mtype, labels) DO NOTHING`
+	selectQuery := `
+		SELECT 
+			histogram 
+		FROM metrics
+		WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb
+		FOR UPDATE`
+	updateQuery := `
+		UPDATE metrics SET histogram = $3::jsonb
+		WHERE mname = $1 AND mtype = $2 AND labels = $4::jsonb`
+	historyQuery := `
+		INSERT INTO metrics_history(mname, mtype, histogram, labels) VALUES($1, $2, $3::jsonb, $4::jsonb)`
+
+	// Пустая гистограмма гарантирует наличие строки, которую можно заблокировать для слияния
+	empty, err := marshalHistogram(metric.NewHistogram(mtrc.Histogram.Bounds))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, insertQuery, mtrc.ID, mtrc.MType, empty, lbls)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+
+	err = tx.QueryRowContext(ctx, selectQuery, mtrc.ID, mtrc.MType, lbls).Scan(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &metric.Histogram{}
+	if err = json.Unmarshal(data, merged); err != nil {
+		return nil, err
+	}
+
+	if err = merged.Merge(mtrc.Histogram); err != nil {
+		return nil, err
+	}
+
+	mergedData, err := marshalHistogram(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, updateQuery, mtrc.ID, mtrc.MType, mergedData, lbls)
+	if err != nil {
+		return nil, err
+	}
+
+	histogram, err := marshalHistogram(mtrc.Histogram)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, historyQuery, mtrc.ID, mtrc.MType, histogram, lbls)
+	if err != nil {
+		return nil, err
+	}
+
+	return merged, nil
+}
+
+// scanMetric выполняет чтение метрики из текущей строки результата запроса.
+// Запрос должен возвращать поля mname, mtype, delta, value, histogram, labels,
+// за которыми следуют поля, считываемые в extra.
+func scanMetric(rows *sql.Rows, extra ...interface{}) (metric.Metrics, error) {
+	var (
+		delta     sql.NullInt64
+		value     sql.NullFloat64
+		histogram []byte
+		labels    []byte
+		mtrc      metric.Metrics
+	)
+
+	dest := append([]interface{}{&mtrc.ID, &mtrc.MType, &delta, &value, &histogram, &labels}, extra...)
+
+	if err := rows.Scan(dest...); err != nil {
+		return metric.Metrics{}, err
+	}
+
+	if err := setValue(&mtrc, delta, value, histogram); err != nil {
+		return metric.Metrics{}, err
+	}
+
+	var err error
+
+	mtrc.Labels, err = unmarshalLabels(labels)
+	if err != nil {
+		return metric.Metrics{}, err
+	}
+
+	return mtrc, nil
+}
+
+// scanMetrics выполняет чтение набора метрик из результата запроса.
+// Запрос должен возвращать поля mname, mtype, delta, value, histogram, labels.
+func scanMetrics(rows *sql.Rows) ([]metric.Metrics, error) {
+	res := make([]metric.Metrics, 0)
+
+	for rows.Next() {
+		mtrc, err := scanMetric(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, mtrc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
 // GetAll возвращает все метрики, находящиеся в репозитории.
 func (s *PgStorage) GetAll(ctx context.Context) ([]metric.Metrics, error) {
 	slct := func() ([]metric.Metrics, error) {
 		query := `
 			SELECT 
-				mname, mtype, delta, value 
+				mname, mtype, delta, value, histogram, labels 
 			FROM metrics`
 
-		res := make([]metric.Metrics, 0)
-
 		rows, err := s.db.QueryContext(ctx, query)
 		if err != nil {
 			return nil, err
@@ -81,32 +267,7 @@ func (s *PgStorage) GetAll(ctx context.Context) ([]metric.Metrics, error) {
 
 		defer rows.Close()
 
-		for rows.Next() {
-			var (
-				delta sql.NullInt64
-				value sql.NullFloat64
-				mtrc  metric.Metrics
-			)
-
-			err = rows.Scan(&mtrc.ID, &mtrc.MType, &delta, &value)
-			if err != nil {
-				return nil, err
-			}
-
-			if delta.Valid {
-				mtrc.Delta = &delta.Int64
-			} else {
-				mtrc.Value = &value.Float64
-			}
-
-			res = append(res, mtrc)
-		}
-
-		if err = rows.Err(); err != nil {
-			return nil, err
-		}
-
-		return res, nil
+		return scanMetrics(rows)
 	}
 
 	var (
@@ -135,39 +296,45 @@ func (s *PgStorage) GetAll(ctx context.Context) ([]metric.Metrics, error) {
 	return res, nil
 }
 
-// GetValue возвращает определенную метрику, соответствующую параметрам mType и mName.
-func (s *PgStorage) GetValue(ctx context.Context, mType metric.MetricType, mName string) (*metric.Metrics, error) {
+// GetValue возвращает определенную метрику, соответствующую параметрам mType, mName и labels.
+func (s *PgStorage) GetValue(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (*metric.Metrics, error) {
+	lbls, err := marshalLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
 	slct := func() (*metric.Metrics, error) {
 		query := `
 			SELECT 
-				mname, mtype, delta, value 
+				mname, mtype, delta, value, histogram 
 			FROM metrics
-			WHERE mname = $1 AND mtype = $2`
+			WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb`
 
 		var (
-			delta sql.NullInt64
-			value sql.NullFloat64
-			mtrc  metric.Metrics
+			delta     sql.NullInt64
+			value     sql.NullFloat64
+			histogram []byte
+			mtrc      metric.Metrics
 		)
 
-		err := s.db.QueryRowContext(ctx, query, mName, mType).Scan(&mtrc.ID, &mtrc.MType, &delta, &value)
+		err := s.db.QueryRowContext(ctx, query, mName, mType, lbls).
+			Scan(&mtrc.ID, &mtrc.MType, &delta, &value, &histogram)
 		if err != nil {
 			return nil, err
 		}
 
-		if delta.Valid {
-			mtrc.Delta = &delta.Int64
-		} else {
-			mtrc.Value = &value.Float64
+		if err = setValue(&mtrc, delta, value, histogram); err != nil {
+			return nil, err
 		}
 
+		mtrc.Labels = labels
+
 		return &mtrc, nil
 	}
 
-	var (
-		mtrc *metric.Metrics
-		err  error
-	)
+	var mtrc *metric.Metrics
 
 	for _, t := range s.retries {
 		err = utils.Wait(ctx, time.Duration(t)*time.Second)
@@ -194,43 +361,39 @@ func (s *PgStorage) GetValue(ctx context.Context, mType metric.MetricType, mName
 	return mtrc, nil
 }
 
-// Update выполняет обновление единственной метрики.
-func (s *PgStorage) Update(ctx context.Context, mtrc *metric.Metrics) error {
-	insert := func() (sql.NullInt64, error) {
-		query := `
-			INSERT INTO metrics(mname, mtype, delta, value) VALUES($1, $2, $3, $4)
-			ON CONFLICT (mname, mtype) DO UPDATE SET delta = metrics.delta + $3, value = $4
-			RETURNING delta`
+// GetByLabels возвращает все метрики, набор меток которых содержит все метки из labels.
+func (s *PgStorage) GetByLabels(ctx context.Context, labels metric.Labels) ([]metric.Metrics, error) {
+	lbls, err := marshalLabels(labels)
+	if err != nil {
+		return nil, err
+	}
 
-		var delta sql.NullInt64
+	slct := func() ([]metric.Metrics, error) {
+		query := `
+			SELECT 
+				mname, mtype, delta, value, histogram, labels 
+			FROM metrics
+			WHERE labels @> $1::jsonb`
 
-		tx, err := s.db.BeginTx(ctx, nil)
+		rows, err := s.db.QueryContext(ctx, query, lbls)
 		if err != nil {
-			return sql.NullInt64{}, err
+			return nil, err
 		}
 
-		defer tx.Rollback()
-
-		err = tx.QueryRowContext(ctx, query, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value).Scan(&delta)
-		if err != nil {
-			return sql.NullInt64{}, err
-		}
+		defer rows.Close()
 
-		return delta, tx.Commit()
+		return scanMetrics(rows)
 	}
 
-	var (
-		delta sql.NullInt64
-		err   error
-	)
+	var res []metric.Metrics
 
 	for _, t := range s.retries {
 		err = utils.Wait(ctx, time.Duration(t)*time.Second)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		delta, err = insert()
+		res, err = slct()
 
 		var pgErr *pgconn.PgError
 		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
@@ -239,56 +402,93 @@ func (s *PgStorage) Update(ctx context.Context, mtrc *metric.Metrics) error {
 	}
 
 	if err != nil {
-		return err
-	}
-
-	if delta.Valid {
-		*mtrc.Delta = delta.Int64
+		return nil, err
 	}
 
-	return nil
+	return res, nil
 }
 
-// UpdateMany выполняет обновление метрик из набора.
-func (s *PgStorage) UpdateMany(ctx context.Context, mtrcs []metric.Metrics) error {
-	insert := func() error {
-		query := `
-			INSERT INTO metrics(mname, mtype, delta, value) VALUES($1, $2, $3, $4)
-			ON CONFLICT (mname, mtype) DO UPDATE SET delta = metrics.delta + $3, value = $4`
+// Find возвращает страницу метрик, соответствующих условиям filter, в порядке, заданном filter.
+// Если за страницей следуют другие метрики, возвращается курсор для получения следующей страницы.
+// Ключом набора меток в курсоре является текстовое представление jsonb; строки сравниваются побайтово.
+// Имена проверяются на соответствие filter.NameRegexp при чтении строк, чтобы использовался синтаксис RE2,
+// а не синтаксис регулярных выражений PostgreSQL.
+func (s *PgStorage) Find(ctx context.Context, filter metric.Filter) ([]metric.Metrics, *metric.Cursor, error) {
+	query, args, err := findQuery(filter)
+	if err != nil {
+		return nil, nil, err
+	}
 
-		tx, err := s.db.BeginTx(ctx, nil)
+	var nameRe *regexp.Regexp
+
+	if filter.NameRegexp != "" {
+		nameRe, err = regexp.Compile(filter.NameRegexp)
 		if err != nil {
-			return err
+			return nil, nil, fmt.Errorf("%w: %s", metric.ErrWrongFilter, err)
 		}
+	}
 
-		defer tx.Rollback()
-
-		stmt, err := tx.PrepareContext(ctx, query)
+	slct := func() ([]metric.Metrics, *metric.Cursor, error) {
+		rows, err := s.db.QueryContext(ctx, query, args...)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
-		defer stmt.Close()
+		defer rows.Close()
+
+		var (
+			res       = make([]metric.Metrics, 0)
+			labelKeys = make([]string, 0)
+		)
+
+		for rows.Next() {
+			var labelsKey string
 
-		for _, mtrc := range mtrcs {
-			_, err = stmt.ExecContext(ctx, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value)
+			mtrc, err := scanMetric(rows, &labelsKey)
 			if err != nil {
-				return err
+				return nil, nil, err
+			}
+
+			if nameRe != nil && !nameRe.MatchString(mtrc.ID) {
+				continue
+			}
+
+			res = append(res, mtrc)
+			labelKeys = append(labelKeys, labelsKey)
+
+			if filter.Limit > 0 && len(res) > filter.Limit {
+				break
 			}
 		}
 
-		return tx.Commit()
+		if err = rows.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		// Запрос возвращает на одну строку больше страницы, чтобы определить наличие следующей
+		if filter.Limit <= 0 || len(res) <= filter.Limit {
+			return res, nil, nil
+		}
+
+		res = res[:filter.Limit]
+		last := res[len(res)-1]
+		next := &metric.Cursor{Name: last.ID, Type: last.MType, Labels: labelKeys[len(res)-1]}
+
+		return res, next, nil
 	}
 
-	var err error
+	var (
+		res  []metric.Metrics
+		next *metric.Cursor
+	)
 
 	for _, t := range s.retries {
 		err = utils.Wait(ctx, time.Duration(t)*time.Second)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 
-		err = insert()
+		res, next, err = slct()
 
 		var pgErr *pgconn.PgError
 		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
@@ -296,7 +496,628 @@ func (s *PgStorage) UpdateMany(ctx context.Context, mtrcs []metric.Metrics) erro
 		}
 	}
 
-	return err
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, next, nil
+}
+
+// findQuery формирует запрос выборки метрик по условиям filter и его аргументы.
+// Запрос возвращает поля mname, mtype, delta, value, histogram, labels и ключ набора меток
+// и содержит на одну строку больше, чем filter.Limit.
+// Условие filter.NameRegexp в запрос не включается и проверяется вызывающей стороной,
+// поэтому при его наличии число строк запроса не ограничивается.
+func findQuery(filter metric.Filter) (string, []interface{}, error) {
+	var (
+		conds = make([]string, 0)
+		args  = make([]interface{}, 0)
+	)
+
+	// arg добавляет аргумент запроса и возвращает его плейсхолдер
+	arg := func(v interface{}) string {
+		args = append(args, v)
+
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Types) > 0 {
+		placeholders := make([]string, 0, len(filter.Types))
+		for _, t := range filter.Types {
+			placeholders = append(placeholders, arg(t))
+		}
+
+		conds = append(conds, fmt.Sprintf("mtype IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if filter.NamePrefix != "" {
+		conds = append(conds, fmt.Sprintf(`mname LIKE %s ESCAPE '\'`, arg(likeEscape(filter.NamePrefix)+"%")))
+	}
+
+	if len(filter.Labels) > 0 {
+		lbls, err := marshalLabels(filter.Labels)
+		if err != nil {
+			return "", nil, err
+		}
+
+		conds = append(conds, fmt.Sprintf("labels @> %s::jsonb", arg(lbls)))
+	}
+
+	key := []string{`mname COLLATE "C"`, "mtype", `labels::text COLLATE "C"`}
+	if filter.Sort == metric.SortByType {
+		key[0], key[1] = key[1], key[0]
+	}
+
+	order, cmp := "ASC", ">"
+	if filter.Desc {
+		order, cmp = "DESC", "<"
+	}
+
+	if filter.After != nil {
+		after := []string{arg(filter.After.Name), arg(filter.After.Type), arg(filter.After.Labels)}
+		if filter.Sort == metric.SortByType {
+			after[0], after[1] = after[1], after[0]
+		}
+
+		conds = append(
+			conds,
+			fmt.Sprintf("(%s) %s (%s)", strings.Join(key, ", "), cmp, strings.Join(after, ", ")),
+		)
+	}
+
+	builder := strings.Builder{}
+	builder.WriteString(`
+		SELECT 
+			mname, mtype, delta, value, histogram, labels, labels::text 
+		FROM metrics`)
+
+	if len(conds) > 0 {
+		builder.WriteString("\n\t\tWHERE " + strings.Join(conds, " AND "))
+	}
+
+	orderBy := make([]string, 0, len(key))
+	for _, k := range key {
+		orderBy = append(orderBy, k+" "+order)
+	}
+
+	builder.WriteString("\n\t\tORDER BY " + strings.Join(orderBy, ", "))
+
+	if filter.Limit > 0 && filter.NameRegexp == "" {
+		builder.WriteString("\n\t\tLIMIT " + arg(filter.Limit+1))
+	}
+
+	return builder.String(), args, nil
+}
+
+// GetHistory возвращает значения метрики, принятые в интервале времени [from, to].
+func (s *PgStorage) GetHistory(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels, from, to time.Time,
+) ([]metric.Sample, error) {
+	lbls, err := marshalLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+
+	slct := func() ([]metric.Sample, error) {
+		query := `
+			SELECT 
+				mname, mtype, delta, value, histogram, ts 
+			FROM metrics_history
+			WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb AND ts BETWEEN $4 AND $5
+			ORDER BY ts`
+
+		res := make([]metric.Sample, 0)
+
+		rows, err := s.db.QueryContext(ctx, query, mName, mType, lbls, from, to)
+		if err != nil {
+			return nil, err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				delta     sql.NullInt64
+				value     sql.NullFloat64
+				histogram []byte
+				sample    metric.Sample
+			)
+
+			err = rows.Scan(&sample.ID, &sample.MType, &delta, &value, &histogram, &sample.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+
+			if err = setValue(&sample.Metrics, delta, value, histogram); err != nil {
+				return nil, err
+			}
+
+			sample.Labels = labels
+
+			res = append(res, sample)
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	var res []metric.Sample
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = slct()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// GetRange возвращает значения метрики за интервал времени, агрегированные по шагам согласно q.
+// Шаги, на которых значения не принимались, не возвращаются.
+func (s *PgStorage) GetRange(ctx context.Context, q metric.RangeQuery) ([]metric.Point, error) {
+	lbls, err := marshalLabels(q.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := rangeQuery(q)
+	if err != nil {
+		return nil, err
+	}
+
+	slct := func() ([]metric.Point, error) {
+		rows, err := s.db.QueryContext(ctx, query, q.Step.Seconds(), q.From, q.To, q.Name, q.MType, lbls)
+		if err != nil {
+			return nil, err
+		}
+
+		defer rows.Close()
+
+		res := make([]metric.Point, 0)
+
+		for rows.Next() {
+			var point metric.Point
+
+			if err = rows.Scan(&point.Timestamp, &point.Value); err != nil {
+				return nil, err
+			}
+
+			res = append(res, point)
+		}
+
+		if err = rows.Err(); err != nil {
+			return nil, err
+		}
+
+		return res, nil
+	}
+
+	var res []metric.Point
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err = slct()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// rangeQuery формирует запрос значений метрики за интервал времени, агрегированных по шагам
+// с помощью date_bin. Аргументы запроса: длительность шага в секундах, начало и окончание интервала,
+// имя, тип и набор меток метрики.
+func rangeQuery(q metric.RangeQuery) (string, error) {
+	value := "value"
+	if q.MType == metric.CounterMetric {
+		value = "delta::double precision"
+	}
+
+	var agg string
+
+	switch q.Aggregation {
+	case metric.AggregationAvg:
+		agg = fmt.Sprintf("avg(%s)", value)
+	case metric.AggregationMin:
+		agg = fmt.Sprintf("min(%s)", value)
+	case metric.AggregationMax:
+		agg = fmt.Sprintf("max(%s)", value)
+	case metric.AggregationSum:
+		agg = fmt.Sprintf("sum(%s)", value)
+	case metric.AggregationRate:
+		agg = fmt.Sprintf("sum(%s) / $1", value)
+	case metric.AggregationLast:
+		agg = fmt.Sprintf("(array_agg(%s ORDER BY ts DESC, id DESC))[1]", value)
+	default:
+		return "", fmt.Errorf("%w: unknown aggregation %q", metric.ErrWrongRangeQuery, q.Aggregation)
+	}
+
+	return fmt.Sprintf(`
+		SELECT 
+			date_bin(make_interval(secs => $1), ts, $2) AS bucket, %s 
+		FROM metrics_history
+		WHERE mname = $4 AND mtype = $5 AND labels = $6::jsonb AND ts BETWEEN $2 AND $3
+		GROUP BY bucket
+		ORDER BY bucket`, agg), nil
+}
+
+// Update выполняет обновление единственной метрики.
+func (s *PgStorage) Update(ctx context.Context, mtrc *metric.Metrics) error {
+	lbls, err := marshalLabels(mtrc.Labels)
+	if err != nil {
+		return err
+	}
+
+	insert := func() (sql.NullInt64, *metric.Histogram, error) {
+		query := `
+			INSERT INTO metrics(mname, mtype, delta, value, labels) VALUES($1, $2, $3, $4, $5::jsonb)
+			ON CONFLICT (mname, mtype, labels) DO UPDATE SET delta = metrics.delta + $3, value = $4
+			RETURNING delta`
+		historyQuery := `
+			INSERT INTO metrics_history(mname, mtype, delta, value, labels) VALUES($1, $2, $3, $4, $5::jsonb)`
+
+		var delta sql.NullInt64
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return sql.NullInt64{}, nil, err
+		}
+
+		defer tx.Rollback()
+
+		if mtrc.Histogram != nil {
+			histogram, err := updateHistogram(ctx, tx, mtrc, lbls)
+			if err != nil {
+				return sql.NullInt64{}, nil, err
+			}
+
+			return delta, histogram, tx.Commit()
+		}
+
+		err = tx.QueryRowContext(ctx, query, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value, lbls).Scan(&delta)
+		if err != nil {
+			return sql.NullInt64{}, nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, historyQuery, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value, lbls)
+		if err != nil {
+			return sql.NullInt64{}, nil, err
+		}
+
+		return delta, nil, tx.Commit()
+	}
+
+	var (
+		delta     sql.NullInt64
+		histogram *metric.Histogram
+	)
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return err
+		}
+
+		delta, histogram, err = insert()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if delta.Valid {
+		*mtrc.Delta = delta.Int64
+	}
+
+	if histogram != nil {
+		mtrc.Histogram = histogram
+	}
+
+	return nil
+}
+
+// updateMetrics выполняет обновление метрик из набора в рамках транзакции tx.
+func updateMetrics(ctx context.Context, tx *sql.Tx, mtrcs []metric.Metrics) error {
+	query := `
+		INSERT INTO metrics(mname, mtype, delta, value, labels) VALUES($1, $2, $3, $4, $5::jsonb)
+		ON CONFLICT (mname, mtype, labels) DO UPDATE SET delta = metrics.delta + $3, value = $4`
+	historyQuery := `
+		INSERT INTO metrics_history(mname, mtype, delta, value, labels) VALUES($1, $2, $3, $4, $5::jsonb)`
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	historyStmt, err := tx.PrepareContext(ctx, historyQuery)
+	if err != nil {
+		return err
+	}
+
+	defer historyStmt.Close()
+
+	for i := range mtrcs {
+		mtrc := &mtrcs[i]
+
+		lbls, err := marshalLabels(mtrc.Labels)
+		if err != nil {
+			return err
+		}
+
+		if mtrc.Histogram != nil {
+			if _, err = updateHistogram(ctx, tx, mtrc, lbls); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		_, err = stmt.ExecContext(ctx, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value, lbls)
+		if err != nil {
+			return err
+		}
+
+		_, err = historyStmt.ExecContext(ctx, mtrc.ID, mtrc.MType, mtrc.Delta, mtrc.Value, lbls)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UpdateMany выполняет обновление метрик из набора.
+func (s *PgStorage) UpdateMany(ctx context.Context, mtrcs []metric.Metrics) error {
+	insert := func() error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+
+		defer tx.Rollback()
+
+		if err = updateMetrics(ctx, tx, mtrcs); err != nil {
+			return err
+		}
+
+		return tx.Commit()
+	}
+
+	var err error
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return err
+		}
+
+		err = insert()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	return err
+}
+
+// UpdateBatch выполняет обновление метрик из набора batch и запоминает идентификатор набора.
+// Если набор с тем же идентификатором уже был применён в течение batchRetention,
+// обновление не выполняется и возвращается false.
+func (s *PgStorage) UpdateBatch(ctx context.Context, batch metric.Batch) (bool, error) {
+	insert := func() (bool, error) {
+		query := `
+			INSERT INTO applied_batches(batch_id) VALUES($1)
+			ON CONFLICT (batch_id) DO UPDATE SET applied_at = now()
+			WHERE applied_batches.applied_at < $2`
+		cleanupQuery := `DELETE FROM applied_batches WHERE applied_at < $1`
+
+		expired := time.Now().Add(-batchRetention)
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, query, batch.ID, expired)
+		if err != nil {
+			return false, err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return false, err
+		}
+
+		if rows == 0 {
+			return false, nil
+		}
+
+		if err = updateMetrics(ctx, tx, batch.Metrics); err != nil {
+			return false, err
+		}
+
+		if _, err = tx.ExecContext(ctx, cleanupQuery, expired); err != nil {
+			return false, err
+		}
+
+		return true, tx.Commit()
+	}
+
+	var (
+		applied bool
+		err     error
+	)
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return false, err
+		}
+
+		applied, err = insert()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	return applied, err
+}
+
+// Delete удаляет метрику, соответствующую параметрам mType, mName и labels, вместе с её историей.
+// Если метрика не найдена, возвращается false.
+func (s *PgStorage) Delete(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (bool, error) {
+	lbls, err := marshalLabels(labels)
+	if err != nil {
+		return false, err
+	}
+
+	query := `DELETE FROM metrics WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb`
+	historyQuery := `DELETE FROM metrics_history WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb`
+
+	deleted, err := s.delete(ctx, query, historyQuery, mName, mType, lbls)
+
+	return deleted > 0, err
+}
+
+// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону pattern
+// (см. metric.MatchName), вместе с их историей. Возвращает количество удалённых метрик.
+func (s *PgStorage) DeleteByPattern(ctx context.Context, pattern string) (int64, error) {
+	query := `DELETE FROM metrics WHERE mname LIKE $1 ESCAPE '\'`
+	historyQuery := `DELETE FROM metrics_history WHERE mname LIKE $1 ESCAPE '\'`
+
+	return s.delete(ctx, query, historyQuery, likePattern(pattern))
+}
+
+// delete выполняет в одной транзакции запрос удаления (или обнуления) метрик query и запрос удаления
+// их истории historyQuery с аргументами args. Возвращает количество удалённых (обнулённых) метрик.
+func (s *PgStorage) delete(ctx context.Context, query, historyQuery string, args ...interface{}) (int64, error) {
+	del := func() (int64, error) {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, err
+		}
+
+		deleted, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		if _, err = tx.ExecContext(ctx, historyQuery, args...); err != nil {
+			return 0, err
+		}
+
+		return deleted, tx.Commit()
+	}
+
+	var (
+		deleted int64
+		err     error
+	)
+
+	for _, t := range s.retries {
+		err = utils.Wait(ctx, time.Duration(t)*time.Second)
+		if err != nil {
+			return 0, err
+		}
+
+		deleted, err = del()
+
+		var pgErr *pgconn.PgError
+		if err == nil || !errors.As(err, &pgErr) || !pgerrcode.IsConnectionException(pgErr.Code) {
+			break
+		}
+	}
+
+	return deleted, err
+}
+
+// likePattern преобразует шаблон имени метрики (см. metric.MatchName) в шаблон оператора LIKE
+// с символом экранирования '\'.
+func likePattern(pattern string) string {
+	builder := strings.Builder{}
+
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteByte('%')
+		case '?':
+			builder.WriteByte('_')
+		default:
+			builder.WriteString(likeEscape(string(r)))
+		}
+	}
+
+	return builder.String()
+}
+
+// likeEscape экранирует символом '\' специальные символы оператора LIKE в строке s.
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Reset обнуляет значение метрики типа counter, соответствующей параметрам mName и labels,
+// и удаляет её историю, чтобы приращения до сброса не учитывались в запросах за интервал.
+// Если метрика не найдена, возвращается false.
+func (s *PgStorage) Reset(ctx context.Context, mName string, labels metric.Labels) (bool, error) {
+	lbls, err := marshalLabels(labels)
+	if err != nil {
+		return false, err
+	}
+
+	query := `UPDATE metrics SET delta = 0 WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb`
+	historyQuery := `DELETE FROM metrics_history WHERE mname = $1 AND mtype = $2 AND labels = $3::jsonb`
+
+	reset, err := s.delete(ctx, query, historyQuery, mName, metric.CounterMetric, lbls)
+
+	return reset > 0, err
 }
 
 // Ping выполняет проверку доступности репозитория.
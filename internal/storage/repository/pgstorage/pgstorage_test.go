@@ -0,0 +1,79 @@
+package pgstorage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KryukovO/metricscollector/internal/metric"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLikePattern(t *testing.T) {
+	assert.Equal(t, `cpu\_%`, likePattern("cpu_*"))
+	assert.Equal(t, `_100\%`, likePattern("?100%"))
+	assert.Equal(t, `a\\b%`, likePattern(`a\b*`))
+}
+
+func TestFindQuery(t *testing.T) {
+	query, args, err := findQuery(metric.Filter{
+		Types:      []metric.MetricType{metric.GaugeMetric, metric.CounterMetric},
+		NamePrefix: "cpu_",
+		Labels:     metric.Labels{"host": "a"},
+		Sort:       metric.SortByType,
+		Desc:       true,
+		Limit:      10,
+		After:      &metric.Cursor{Name: "cpu_user", Type: metric.GaugeMetric, Labels: `{"host": "a"}`},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, query, "mtype IN ($1, $2)")
+	assert.Contains(t, query, `mname LIKE $3 ESCAPE '\'`)
+	assert.Contains(t, query, "labels @> $4::jsonb")
+	assert.Contains(t, query, `(mtype, mname COLLATE "C", labels::text COLLATE "C") < ($6, $5, $7)`)
+	assert.Contains(t, query, `ORDER BY mtype DESC, mname COLLATE "C" DESC, labels::text COLLATE "C" DESC`)
+	assert.Contains(t, query, "LIMIT $8")
+	assert.Equal(
+		t,
+		[]interface{}{
+			metric.GaugeMetric, metric.CounterMetric, `cpu\_%`, `{"host":"a"}`,
+			"cpu_user", metric.GaugeMetric, `{"host": "a"}`, 11,
+		},
+		args,
+	)
+
+	query, args, err = findQuery(metric.Filter{})
+	require.NoError(t, err)
+	assert.NotContains(t, query, "WHERE")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Empty(t, args)
+
+	// Регулярное выражение проверяется в Go, поэтому не передаётся в запрос и не ограничивает число строк
+	query, args, err = findQuery(metric.Filter{NameRegexp: `^cpu_\d+$`, Limit: 10})
+	require.NoError(t, err)
+	assert.NotContains(t, query, "WHERE")
+	assert.NotContains(t, query, "LIMIT")
+	assert.Empty(t, args)
+}
+
+func TestRangeQuery(t *testing.T) {
+	q := metric.RangeQuery{
+		MType: metric.CounterMetric, Name: "PollCount", From: time.Now().Add(-time.Hour), To: time.Now(),
+		Step: time.Minute, Aggregation: metric.AggregationRate,
+	}
+
+	query, err := rangeQuery(q)
+	require.NoError(t, err)
+	assert.Contains(t, query, "date_bin(make_interval(secs => $1), ts, $2) AS bucket, sum(delta::double precision) / $1")
+
+	q.MType, q.Aggregation = metric.GaugeMetric, metric.AggregationLast
+
+	query, err = rangeQuery(q)
+	require.NoError(t, err)
+	assert.Contains(t, query, "(array_agg(value ORDER BY ts DESC, id DESC))[1]")
+
+	q.Aggregation = "median"
+
+	_, err = rangeQuery(q)
+	assert.ErrorIs(t, err, metric.ErrWrongRangeQuery)
+}
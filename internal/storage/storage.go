@@ -3,11 +3,15 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/KryukovO/metricscollector/internal/metric"
 )
 
+// ErrInvalidTimeRange возвращается, если начало интервала времени позже его окончания.
+var ErrInvalidTimeRange = errors.New("invalid time range")
+
 // MetricsStorage структура, обеспечивающая взаимодействие с хранилищем.
 type MetricsStorage struct {
 	repo    Repo
@@ -30,16 +34,94 @@ func (s *MetricsStorage) GetAll(ctx context.Context) ([]metric.Metrics, error) {
 	return s.repo.GetAll(ctx)
 }
 
-// GetValue возвращает определенную метрику, соответствующую параметрам mType и mName.
-func (s *MetricsStorage) GetValue(ctx context.Context, mType metric.MetricType, mName string) (*metric.Metrics, error) {
-	if mType != metric.CounterMetric && mType != metric.GaugeMetric {
+// GetValue возвращает определенную метрику, соответствующую параметрам mType, mName и labels.
+func (s *MetricsStorage) GetValue(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (*metric.Metrics, error) {
+	if !mType.IsValid() {
 		return nil, metric.ErrWrongMetricType
 	}
 
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.GetValue(ctx, mType, mName, labels)
+}
+
+// GetByLabels возвращает все метрики, набор меток которых содержит все метки из labels.
+func (s *MetricsStorage) GetByLabels(ctx context.Context, labels metric.Labels) ([]metric.Metrics, error) {
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.GetByLabels(ctx, labels)
+}
+
+// Find возвращает страницу метрик, соответствующих условиям filter, в порядке, заданном filter.
+// Если за страницей следуют другие метрики, возвращается курсор для получения следующей страницы.
+// Размер страницы ограничивается metric.MaxFilterLimit.
+func (s *MetricsStorage) Find(ctx context.Context, filter metric.Filter) ([]metric.Metrics, *metric.Cursor, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = metric.SortByName
+	}
+
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = metric.DefaultFilterLimit
+	case filter.Limit > metric.MaxFilterLimit:
+		filter.Limit = metric.MaxFilterLimit
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.Find(ctx, filter)
+}
+
+// GetHistory возвращает значения метрики, принятые в интервале времени [from, to].
+func (s *MetricsStorage) GetHistory(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels, from, to time.Time,
+) ([]metric.Sample, error) {
+	if !mType.IsValid() {
+		return nil, metric.ErrWrongMetricType
+	}
+
+	if err := labels.Validate(); err != nil {
+		return nil, err
+	}
+
+	if to.Before(from) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.GetHistory(ctx, mType, mName, labels, from, to)
+}
+
+// GetRange возвращает значения метрики за интервал времени, агрегированные по шагам согласно q.
+// Шаги, на которых значения не принимались, не возвращаются.
+func (s *MetricsStorage) GetRange(ctx context.Context, q metric.RangeQuery) ([]metric.Point, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
 	defer cancel()
 
-	return s.repo.GetValue(ctx, mType, mName)
+	return s.repo.GetRange(ctx, q)
 }
 
 // Update выполняет обновление единственной метрики.
@@ -68,6 +150,71 @@ func (s *MetricsStorage) UpdateMany(ctx context.Context, mtrcs []metric.Metrics)
 	return s.repo.UpdateMany(ctx, mtrcs)
 }
 
+// UpdateBatch выполняет обновление метрик из набора batch.
+// Если набор с тем же идентификатором уже был применён, обновление не выполняется
+// и возвращается false. Набор без идентификатора применяется всегда.
+func (s *MetricsStorage) UpdateBatch(ctx context.Context, batch metric.Batch) (bool, error) {
+	for _, mtrc := range batch.Metrics {
+		if err := mtrc.Validate(); err != nil {
+			return false, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if batch.ID == "" {
+		return true, s.repo.UpdateMany(ctx, batch.Metrics)
+	}
+
+	return s.repo.UpdateBatch(ctx, batch)
+}
+
+// Delete удаляет метрику, соответствующую параметрам mType, mName и labels, вместе с её историей.
+// Если метрика не найдена, возвращается false.
+func (s *MetricsStorage) Delete(
+	ctx context.Context, mType metric.MetricType, mName string, labels metric.Labels,
+) (bool, error) {
+	if !mType.IsValid() {
+		return false, metric.ErrWrongMetricType
+	}
+
+	if err := labels.Validate(); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.Delete(ctx, mType, mName, labels)
+}
+
+// DeleteByPattern удаляет все метрики, имена которых соответствуют шаблону pattern
+// (см. metric.MatchName), вместе с их историей. Возвращает количество удалённых метрик.
+func (s *MetricsStorage) DeleteByPattern(ctx context.Context, pattern string) (int64, error) {
+	if pattern == "" {
+		return 0, metric.ErrWrongNamePattern
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.DeleteByPattern(ctx, pattern)
+}
+
+// Reset обнуляет значение метрики типа counter, соответствующей параметрам mName и labels.
+// Если метрика не найдена, возвращается false.
+func (s *MetricsStorage) Reset(ctx context.Context, mName string, labels metric.Labels) (bool, error) {
+	if err := labels.Validate(); err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.repo.Reset(ctx, mName, labels)
+}
+
 // Ping выполняет проверку доступности хранилища.
 func (s *MetricsStorage) Ping(ctx context.Context) bool {
 	ctx, cancel := context.WithTimeout(ctx, s.timeout)
@@ -19,7 +19,7 @@ func newTestRepo(ctx context.Context, clear bool) (*memstorage.MemStorage, []met
 		stor       []metric.Metrics
 	)
 
-	repo, err := memstorage.NewMemStorage(ctx, "", false, 0, retries, nil)
+	repo, err := memstorage.NewMemStorage(ctx, "", false, 0, retries, memstorage.HistoryRetention{}, nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -157,7 +157,7 @@ func TestGetValue(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			v, err := s.GetValue(context.Background(), test.args.mType, test.args.mName)
+			v, err := s.GetValue(context.Background(), test.args.mType, test.args.mName, nil)
 			assert.Equal(t, test.want.expected, v)
 			if test.want.wantErr {
 				assert.Error(t, err)
@@ -168,6 +168,125 @@ func TestGetValue(t *testing.T) {
 	}
 }
 
+func TestGetByLabels(t *testing.T) {
+	var (
+		timeout          = 10 * time.Second
+		counterVal int64 = 100
+	)
+
+	repo, _, err := newTestRepo(context.Background(), false)
+	require.NoError(t, err)
+
+	err = repo.Update(
+		context.Background(),
+		&metric.Metrics{
+			ID:     "PollCount",
+			MType:  metric.CounterMetric,
+			Delta:  &counterVal,
+			Labels: metric.Labels{"host": "a"},
+		},
+	)
+	require.NoError(t, err)
+
+	s := NewMetricsStorage(repo, timeout)
+
+	v, err := s.GetByLabels(context.Background(), metric.Labels{"host": "a"})
+	require.NoError(t, err)
+	require.Len(t, v, 1)
+	assert.Equal(t, metric.Labels{"host": "a"}, v[0].Labels)
+
+	v, err = s.GetByLabels(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Len(t, v, 3)
+
+	_, err = s.GetByLabels(context.Background(), metric.Labels{"": "a"})
+	assert.ErrorIs(t, err, metric.ErrWrongMetricLabels)
+}
+
+func TestGetHistory(t *testing.T) {
+	var (
+		timeout = 10 * time.Second
+		now     = time.Now()
+	)
+
+	type args struct {
+		mType    metric.MetricType
+		mName    string
+		from, to time.Time
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		wantLen int
+		wantErr bool
+	}{
+		{
+			name: "Existing gauge history",
+			args: args{
+				mType: metric.GaugeMetric,
+				mName: "RandomValue",
+				from:  now.Add(-time.Hour),
+				to:    now.Add(time.Hour),
+			},
+			wantLen: 1,
+			wantErr: false,
+		},
+		{
+			name: "Empty time range",
+			args: args{
+				mType: metric.CounterMetric,
+				mName: "PollCount",
+				from:  now.Add(-2 * time.Hour),
+				to:    now.Add(-time.Hour),
+			},
+			wantLen: 0,
+			wantErr: false,
+		},
+		{
+			name: "Invalid time range",
+			args: args{
+				mType: metric.CounterMetric,
+				mName: "PollCount",
+				from:  now.Add(time.Hour),
+				to:    now.Add(-time.Hour),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Invalid metric type",
+			args: args{
+				mType: "metric",
+				mName: "PollCount",
+				from:  now.Add(-time.Hour),
+				to:    now.Add(time.Hour),
+			},
+			wantErr: true,
+		},
+	}
+
+	repo, _, err := newTestRepo(context.Background(), false)
+	require.NoError(t, err)
+
+	s := NewMetricsStorage(repo, timeout)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := s.GetHistory(
+				context.Background(), test.args.mType, test.args.mName, nil, test.args.from, test.args.to,
+			)
+			if test.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, v, test.wantLen)
+		})
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	var (
 		timeout          = 10 * time.Second
@@ -416,7 +535,7 @@ func BenchmarkGet(b *testing.B) {
 	s := MetricsStorage{repo: repo}
 
 	b.Run("getValue", func(b *testing.B) {
-		_, err := s.GetValue(ctx, mtrc[0].MType, mtrc[0].ID)
+		_, err := s.GetValue(ctx, mtrc[0].MType, mtrc[0].ID, nil)
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -476,3 +595,61 @@ func BenchmarkUpdate(b *testing.B) {
 		}
 	})
 }
+
+func TestUpdateBatch(t *testing.T) {
+	var counterVal int64 = 100
+
+	repo, _, err := newTestRepo(context.Background(), true)
+	require.NoError(t, err)
+
+	s := NewMetricsStorage(repo, 10*time.Second)
+
+	mtrcs := []metric.Metrics{{ID: "PollCount", MType: metric.CounterMetric, Delta: &counterVal}}
+
+	tests := []struct {
+		name    string
+		batch   metric.Batch
+		applied bool
+		wantErr bool
+	}{
+		{
+			name:    "New batch",
+			batch:   metric.Batch{ID: "batch-1", Metrics: mtrcs},
+			applied: true,
+		},
+		{
+			name:    "Retried batch",
+			batch:   metric.Batch{ID: "batch-1", Metrics: mtrcs},
+			applied: false,
+		},
+		{
+			name:    "Batch without ID #1",
+			batch:   metric.Batch{Metrics: mtrcs},
+			applied: true,
+		},
+		{
+			name:    "Batch without ID #2",
+			batch:   metric.Batch{Metrics: mtrcs},
+			applied: true,
+		},
+		{
+			name:    "Incorrect counter value",
+			batch:   metric.Batch{ID: "batch-2", Metrics: []metric.Metrics{{ID: "PollCount", MType: metric.CounterMetric}}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			applied, err := s.UpdateBatch(context.Background(), test.batch)
+			if test.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.applied, applied)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// EncryptionVersionHeader - заголовок HTTP-запроса с версией схемы шифрования тела запроса.
+	EncryptionVersionHeader = "X-Encryption-Version"
+	// EncryptionRSA - тело запроса целиком зашифровано RSA-OAEP.
+	// Используется, если заголовок EncryptionVersionHeader не передан.
+	EncryptionRSA = "1"
+	// EncryptionHybrid - тело запроса зашифровано AES-GCM, ключ AES зашифрован RSA-OAEP.
+	EncryptionHybrid = "2"
+)
+
+// aesKeySize - размер ключа AES (AES-256).
+const aesKeySize = 32
+
+// keyLenSize - размер поля, содержащего длину зашифрованного ключа AES.
+const keyLenSize = 2
+
+// ErrMalformedCiphertext возвращается, если зашифрованные данные не соответствуют формату.
+var ErrMalformedCiphertext = errors.New("malformed ciphertext")
+
+// EncryptHybrid выполняет шифрование data по схеме EncryptionHybrid:
+// данные шифруются случайным ключом AES-GCM, а сам ключ - открытым ключом RSA-OAEP (SHA256).
+//
+// Формат результата: длина зашифрованного ключа (2 байта, big-endian) | зашифрованный ключ | nonce | шифротекст.
+func EncryptHybrid(publicKey *rsa.PublicKey, data []byte) ([]byte, error) {
+	key := make([]byte, aesKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+
+	encKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, keyLenSize, keyLenSize+len(encKey)+len(nonce)+len(data)+gcm.Overhead())
+	binary.BigEndian.PutUint16(res, uint16(len(encKey)))
+	res = append(res, encKey...)
+	res = append(res, nonce...)
+
+	return gcm.Seal(res, nonce, data, nil), nil
+}
+
+// DecryptHybrid выполняет расшифровку данных, зашифрованных EncryptHybrid.
+func DecryptHybrid(privateKey *rsa.PrivateKey, data []byte) ([]byte, error) {
+	if len(data) < keyLenSize {
+		return nil, ErrMalformedCiphertext
+	}
+
+	keyLen := int(binary.BigEndian.Uint16(data))
+	data = data[keyLenSize:]
+
+	if len(data) < keyLen {
+		return nil, ErrMalformedCiphertext
+	}
+
+	key, err := privateKey.Decrypt(nil, data[:keyLen], &rsa.OAEPOptions{Hash: crypto.SHA256})
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[keyLen:]
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrMalformedCiphertext
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// newGCM создаёт шифр AES-GCM с ключом key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHybridEncryption(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// Размер данных превышает ограничение RSA-OAEP для ключа 2048 бит
+	data := bytes.Repeat([]byte(`{"id":"Alloc","type":"gauge","value":12345.67}`), 100)
+
+	encrypted, err := EncryptHybrid(&privateKey.PublicKey, data)
+	require.NoError(t, err)
+
+	decrypted, err := DecryptHybrid(privateKey, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	encrypted[len(encrypted)-1] ^= 0xff
+	_, err = DecryptHybrid(privateKey, encrypted)
+	assert.Error(t, err)
+
+	_, err = DecryptHybrid(privateKey, []byte{0xff})
+	assert.ErrorIs(t, err, ErrMalformedCiphertext)
+
+	_, err = DecryptHybrid(privateKey, []byte{0x01, 0x00, 0x01})
+	assert.ErrorIs(t, err, ErrMalformedCiphertext)
+}
@@ -5,7 +5,9 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
+	"crypto/subtle"
 	"flag"
+	"strings"
 	"time"
 )
 
@@ -40,6 +42,22 @@ func HashSHA256(src, key []byte) ([]byte, error) {
 	return h.Sum(nil), nil
 }
 
+// BearerPrefix - префикс значения заголовка Authorization, содержащего токен доступа.
+const BearerPrefix = "Bearer "
+
+// ValidBearerToken проверяет, содержит ли значение заголовка Authorization authorization
+// токен доступа token. Сравнение выполняется за время, не зависящее от содержимого токенов.
+// Пустой token не соответствует никакому значению заголовка.
+func ValidBearerToken(authorization, token string) bool {
+	if token == "" || !strings.HasPrefix(authorization, BearerPrefix) {
+		return false
+	}
+
+	received := strings.TrimPrefix(authorization, BearerPrefix)
+
+	return subtle.ConstantTimeCompare([]byte(received), []byte(token)) == 1
+}
+
 // IsFlagPassed проверяет, был ли указан флаг запуска.
 func IsFlagPassed(name string) bool {
 	found := false
@@ -0,0 +1,6 @@
+--
+BEGIN TRANSACTION;
+--
+ALTER TABLE metrics_history RENAME TO "__metrics_history";
+--
+COMMIT TRANSACTION;
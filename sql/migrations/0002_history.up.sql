@@ -0,0 +1,29 @@
+--
+BEGIN TRANSACTION;
+--
+DO $$
+BEGIN
+    IF EXISTS(
+        SELECT 
+            * 
+        FROM information_schema.tables 
+        WHERE table_name = '__metrics_history' AND table_schema = 'public'
+    )
+    THEN
+        ALTER TABLE "__metrics_history" RENAME TO metrics_history;
+    ELSE
+        CREATE TABLE IF NOT EXISTS metrics_history(
+            id BIGINT GENERATED ALWAYS AS IDENTITY,
+            mname TEXT NOT NULL,
+            mtype TEXT NOT NULL,
+            delta BIGINT,
+            value DOUBLE PRECISION,
+            ts TIMESTAMPTZ NOT NULL DEFAULT now(),
+            PRIMARY KEY(id)
+        );
+    END IF;
+    --
+    CREATE INDEX IF NOT EXISTS metrics_history_mname_mtype_ts_idx ON metrics_history(mname, mtype, ts);
+END $$;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,15 @@
+--
+BEGIN TRANSACTION;
+--
+-- Метрики с метками не могут быть сохранены без нарушения уникальности (mname, mtype)
+DELETE FROM metrics WHERE labels <> '{}'::jsonb;
+DELETE FROM metrics_history WHERE labels <> '{}'::jsonb;
+--
+DROP INDEX IF EXISTS metrics_labels_idx;
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_mname_mtype_labels_key;
+ALTER TABLE metrics ADD CONSTRAINT metrics_mname_mtype_key UNIQUE(mname, mtype);
+--
+ALTER TABLE metrics DROP COLUMN IF EXISTS labels;
+ALTER TABLE metrics_history DROP COLUMN IF EXISTS labels;
+--
+COMMIT TRANSACTION;
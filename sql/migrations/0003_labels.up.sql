@@ -0,0 +1,13 @@
+--
+BEGIN TRANSACTION;
+--
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+ALTER TABLE metrics_history ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+--
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_mname_mtype_key;
+ALTER TABLE metrics DROP CONSTRAINT IF EXISTS metrics_mname_mtype_labels_key;
+ALTER TABLE metrics ADD CONSTRAINT metrics_mname_mtype_labels_key UNIQUE(mname, mtype, labels);
+--
+CREATE INDEX IF NOT EXISTS metrics_labels_idx ON metrics USING gin(labels);
+--
+COMMIT TRANSACTION;
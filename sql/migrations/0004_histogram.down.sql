@@ -0,0 +1,11 @@
+--
+BEGIN TRANSACTION;
+--
+-- Значения гистограмм не могут быть представлены в полях delta и value
+DELETE FROM metrics WHERE mtype = 'histogram';
+DELETE FROM metrics_history WHERE mtype = 'histogram';
+--
+ALTER TABLE metrics DROP COLUMN IF EXISTS histogram;
+ALTER TABLE metrics_history DROP COLUMN IF EXISTS histogram;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,7 @@
+--
+BEGIN TRANSACTION;
+--
+ALTER TABLE metrics ADD COLUMN IF NOT EXISTS histogram JSONB;
+ALTER TABLE metrics_history ADD COLUMN IF NOT EXISTS histogram JSONB;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,6 @@
+--
+BEGIN TRANSACTION;
+--
+DROP TABLE IF EXISTS applied_batches;
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,12 @@
+--
+BEGIN TRANSACTION;
+--
+CREATE TABLE IF NOT EXISTS applied_batches(
+    agent TEXT NOT NULL,
+    batch_id TEXT NOT NULL,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY(agent, batch_id)
+);
+CREATE INDEX IF NOT EXISTS applied_batches_applied_at_idx ON applied_batches(applied_at);
+--
+COMMIT TRANSACTION;
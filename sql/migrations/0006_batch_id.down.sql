@@ -0,0 +1,9 @@
+--
+BEGIN TRANSACTION;
+--
+ALTER TABLE applied_batches DROP CONSTRAINT IF EXISTS applied_batches_pkey;
+ALTER TABLE applied_batches ADD COLUMN IF NOT EXISTS agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE applied_batches ALTER COLUMN agent DROP DEFAULT;
+ALTER TABLE applied_batches ADD PRIMARY KEY(agent, batch_id);
+--
+COMMIT TRANSACTION;
@@ -0,0 +1,14 @@
+--
+BEGIN TRANSACTION;
+--
+-- Идентификатор набора уникален (UUID), поэтому наборы учитываются без привязки к агенту
+DELETE FROM applied_batches a USING applied_batches b
+WHERE a.batch_id = b.batch_id AND a.applied_at < b.applied_at;
+DELETE FROM applied_batches a USING applied_batches b
+WHERE a.batch_id = b.batch_id AND a.applied_at = b.applied_at AND a.agent < b.agent;
+--
+ALTER TABLE applied_batches DROP CONSTRAINT IF EXISTS applied_batches_pkey;
+ALTER TABLE applied_batches DROP COLUMN IF EXISTS agent;
+ALTER TABLE applied_batches ADD PRIMARY KEY(batch_id);
+--
+COMMIT TRANSACTION;